@@ -0,0 +1,185 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	config "github.com/ArteShow/Calculator/pkg/Config"
+	database "github.com/ArteShow/Calculator/pkg/Database"
+	user "github.com/ArteShow/Calculator/proto"
+)
+
+// requireAdmin answers the request itself and returns false unless it comes from
+// one of the adminUserIds of the calculator config
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	userID, err := GetUserIdFromToken(w, r, w.Header().Get("Authorization"))
+	if err != nil {
+		http.Error(w, "Failed to get userId from token", http.StatusUnauthorized)
+		return false
+	}
+	calculatorConfig, err := config.LoadCalculatorConfig()
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Failed to load config", http.StatusInternalServerError)
+		return false
+	}
+	if !calculatorConfig.IsAdmin(userID) {
+		http.Error(w, "Only admins may do this", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// AgentStatus is an agent as shown by /api/v1/admin/agents
+type AgentStatus struct {
+	Id             string `json:"id"`
+	Status         string `json:"status"`
+	Capacity       int32  `json:"capacity"`
+	RunningTasks   int32  `json:"runningTasks"`
+	CompletedTasks int64  `json:"completedTasks"`
+	RegisteredAt   string `json:"registeredAt"`
+	LastSeenAt     string `json:"lastSeenAt"`
+}
+
+// Agents lists the agents registered with the orchestrator and whether they are alive
+func Agents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	conn, err := dialOrchestrator()
+	if err != nil {
+		http.Error(w, "Failed to connect to gRPC server", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	client := user.NewUserServiceClient(conn)
+	res, err := client.GetAgents(withToken(context.Background(), r), &user.AgentsRequest{})
+	if err != nil {
+		writeGRPCError(w, err, "Failed to get agents")
+		return
+	}
+
+	agents := []AgentStatus{}
+	for _, agent := range res.Agents {
+		agents = append(agents, AgentStatus{
+			Id:             agent.Id,
+			Status:         agent.Status,
+			Capacity:       agent.Capacity,
+			RunningTasks:   agent.RunningTasks,
+			CompletedTasks: agent.CompletedTasks,
+			RegisteredAt:   agent.RegisteredAt,
+			LastSeenAt:     agent.LastSeenAt,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"agents": agents})
+}
+
+func deadLetterOf(deadLetter *user.DeadLetter) database.DeadLetter {
+	return database.DeadLetter{
+		Id:           int(deadLetter.Id),
+		TaskId:       deadLetter.TaskId,
+		ExpressionId: int(deadLetter.ExpressionId),
+		UserId:       int(deadLetter.UserId),
+		Expression:   deadLetter.Expression,
+		Path:         deadLetter.Path,
+		Operation:    deadLetter.Operation,
+		Left:         deadLetter.Left,
+		Right:        deadLetter.Right,
+		Attempts:     int(deadLetter.Attempts),
+		Error:        deadLetter.Error,
+		CreatedAt:    deadLetter.CreatedAt,
+		RequeuedAt:   deadLetter.RequeuedAt,
+	}
+}
+
+// DeadLetters lists the operations that failed for good, with ?pending=true
+// only those that were not requeued yet
+func DeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	pendingOnly := false
+	if pending := r.URL.Query().Get("pending"); pending != "" {
+		var err error
+		if pendingOnly, err = strconv.ParseBool(pending); err != nil {
+			http.Error(w, "pending must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	conn, err := dialOrchestrator()
+	if err != nil {
+		http.Error(w, "Failed to connect to gRPC server", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	client := user.NewUserServiceClient(conn)
+	res, err := client.GetDeadLetters(withToken(context.Background(), r), &user.DeadLettersRequest{PendingOnly: pendingOnly})
+	if err != nil {
+		writeGRPCError(w, err, "Failed to get dead letters")
+		return
+	}
+
+	deadLetters := []database.DeadLetter{}
+	for _, deadLetter := range res.DeadLetters {
+		deadLetters = append(deadLetters, deadLetterOf(deadLetter))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"deadLetters": deadLetters})
+}
+
+// RequeueDeadLetter starts the failed calculation of a dead letter again,
+// POST /api/v1/admin/dead-letters/{id}/requeue
+func RequeueDeadLetter(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/admin/dead-letters/")
+	idText, found := strings.CutSuffix(path, "/requeue")
+	if !found {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	deadLetterID, err := strconv.Atoi(idText)
+	if err != nil {
+		http.Error(w, "Invalid dead letter ID", http.StatusBadRequest)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	conn, err := dialOrchestrator()
+	if err != nil {
+		http.Error(w, "Failed to connect to gRPC server", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	client := user.NewUserServiceClient(conn)
+	res, err := client.RequeueDeadLetter(withToken(context.Background(), r), &user.RequeueDeadLetterRequest{Id: int32(deadLetterID)})
+	if err != nil {
+		writeGRPCError(w, err, "Failed to requeue dead letter")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deadLetterOf(res))
+}
@@ -0,0 +1,79 @@
+package application
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	MyJWT "github.com/ArteShow/Calculator/pkg/JWT"
+)
+
+func TestAgents_NotAllowed(t *testing.T) {
+	t.Setenv("ADMIN_USER_IDS", "1")
+	token, err := MyJWT.CreateJWT(2, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		method string
+		token  string
+		code   int
+	}{
+		{"no token", http.MethodGet, "", http.StatusUnauthorized},
+		{"not an admin", http.MethodGet, token, http.StatusForbidden},
+		{"wrong method", http.MethodPost, token, http.StatusMethodNotAllowed},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, "/api/v1/admin/agents", nil)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		w := httptest.NewRecorder()
+		Agents(w, req)
+		if w.Code != c.code {
+			t.Fatalf("%s: expected %d, got %d", c.name, c.code, w.Code)
+		}
+	}
+}
+
+func TestDeadLetters_BadRequests(t *testing.T) {
+	t.Setenv("ADMIN_USER_IDS", "1")
+	admin, err := MyJWT.CreateJWT(1, "admin", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	other, err := MyJWT.CreateJWT(2, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		method  string
+		path    string
+		token   string
+		handler http.HandlerFunc
+		code    int
+	}{
+		{"list without token", http.MethodGet, "/api/v1/admin/dead-letters", "", DeadLetters, http.StatusUnauthorized},
+		{"list as user", http.MethodGet, "/api/v1/admin/dead-letters", other, DeadLetters, http.StatusForbidden},
+		{"invalid filter", http.MethodGet, "/api/v1/admin/dead-letters?pending=maybe", admin, DeadLetters, http.StatusBadRequest},
+		{"requeue as user", http.MethodPost, "/api/v1/admin/dead-letters/1/requeue", other, RequeueDeadLetter, http.StatusForbidden},
+		{"requeue with GET", http.MethodGet, "/api/v1/admin/dead-letters/1/requeue", admin, RequeueDeadLetter, http.StatusMethodNotAllowed},
+		{"invalid id", http.MethodPost, "/api/v1/admin/dead-letters/one/requeue", admin, RequeueDeadLetter, http.StatusBadRequest},
+		{"unknown action", http.MethodPost, "/api/v1/admin/dead-letters/1", admin, RequeueDeadLetter, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		w := httptest.NewRecorder()
+		c.handler(w, req)
+		if w.Code != c.code {
+			t.Fatalf("%s: expected %d, got %d", c.name, c.code, w.Code)
+		}
+	}
+}
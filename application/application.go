@@ -12,11 +12,14 @@ import (
 
 	jwt "github.com/golang-jwt/jwt/v5"
 
+	calculate "github.com/ArteShow/Calculator/pkg/Calculation"
+	certs "github.com/ArteShow/Calculator/pkg/Certs"
 	config "github.com/ArteShow/Calculator/pkg/Config"
 	database "github.com/ArteShow/Calculator/pkg/Database"
 	MyJWT "github.com/ArteShow/Calculator/pkg/JWT"
 	user "github.com/ArteShow/Calculator/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 type User struct {
@@ -26,11 +29,80 @@ type User struct {
 }
 
 type Calculations struct {
-	Calculations []Calculation `json:"expression"`
+	Calculations []Expression `json:"expression"`
 }
 
-type Calculation struct {
+// Expression is a stored calculation with its status as shown to the user
+type Expression struct {
+	Id         int    `json:"id"`
 	Expression string `json:"expression"`
+	Status     string `json:"status"`
+	Result     string `json:"result,omitempty"`
+	Error      string `json:"error,omitempty"`
+	CreatedAt  string `json:"createdAt,omitempty"`
+	UpdatedAt  string `json:"updatedAt,omitempty"`
+	Message    string `json:"message,omitempty"`
+	Format     string `json:"format,omitempty"`
+	Formatted  string `json:"formatted,omitempty"`
+}
+
+// ExpressionEvent is one Server-Sent Event of a watched calculation
+type ExpressionEvent struct {
+	Id             int    `json:"id"`
+	Status         string `json:"status"`
+	Result         string `json:"result,omitempty"`
+	Error          string `json:"error,omitempty"`
+	Path           string `json:"path,omitempty"`
+	Value          string `json:"value,omitempty"`
+	CompletedTasks int    `json:"completedTasks,omitempty"`
+	TotalTasks     int    `json:"totalTasks,omitempty"`
+}
+
+type PlanNode struct {
+	Id         int     `json:"id"`
+	Path       string  `json:"path"`
+	Op         string  `json:"op"`
+	Value      float64 `json:"value,omitempty"`
+	DependsOn  []int   `json:"dependsOn"`
+	Uses       int     `json:"uses"`
+	State      string  `json:"state,omitempty"`
+	AgentId    string  `json:"agentId,omitempty"`
+	ReadyAt    string  `json:"readyAt,omitempty"`
+	StartedAt  string  `json:"startedAt,omitempty"`
+	FinishedAt string  `json:"finishedAt,omitempty"`
+}
+
+type ExpressionPlanResponse struct {
+	Id             int        `json:"id"`
+	Status         string     `json:"status"`
+	Expression     string     `json:"expression"`
+	Live           bool       `json:"live"`
+	CompletedTasks int        `json:"completedTasks"`
+	TotalTasks     int        `json:"totalTasks"`
+	Nodes          []PlanNode `json:"nodes"`
+}
+
+type Calculation struct {
+	Expression  string          `json:"expression"`
+	Notation    string          `json:"notation,omitempty"`
+	AST         *calculate.Node `json:"ast,omitempty"`
+	CallbackUrl string          `json:"callbackUrl,omitempty"`
+}
+
+type Batch struct {
+	Expressions []Calculation `json:"expressions"`
+}
+
+type BatchItem struct {
+	Index  int    `json:"index"`
+	Id     int    `json:"id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ParsedExpression struct {
+	Expression string          `json:"expression"`
+	AST        *calculate.Node `json:"ast"`
 }
 
 type Login struct {
@@ -150,6 +222,83 @@ func GetUserIdFromToken(w http.ResponseWriter, r *http.Request, tokenstring stri
 	return int(userIDFloat), nil
 }
 
+// dialOrchestrator connects to the gRPC server, over TLS if the config asks for it
+func dialOrchestrator() (*grpc.ClientConn, error) {
+	calculatorConfig, err := config.LoadCalculatorConfig()
+	if err != nil {
+		log.Printf("❌ Failed to load calculator config: %v", err)
+		return nil, err
+	}
+	credentials, err := certs.DialOption(calculatorConfig)
+	if err != nil {
+		log.Printf("❌ Failed to set up TLS for gRPC: %v", err)
+		return nil, err
+	}
+	return grpc.Dial("localhost:50051", credentials)
+}
+
+// withToken sends the caller's bearer token along with a gRPC call, the gRPC server
+// checks it again and only answers for the user of the token
+func withToken(ctx context.Context, r *http.Request) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", r.Header.Get("Authorization"))
+}
+
+// Locale for numbers in the request and response: the Accept-Language header wins,
+// then the user's saved preference, then the default
+func GetUserLocale(r *http.Request, userID int) calculate.Locale {
+	if locale, ok := calculate.LocaleFromHeader(r.Header.Get("Accept-Language")); ok {
+		return locale
+	}
+	preferences, err := database.GetPreferences(config.GetDatabasePath(), userID)
+	if err != nil {
+		log.Printf("Failed to get preferences: %v", err)
+		return calculate.DefaultLocale
+	}
+	if locale, ok := calculate.GetLocale(preferences.Locale); ok {
+		return locale
+	}
+	return calculate.DefaultLocale
+}
+
+// Result format: the result and digits query parameters win over the user's saved preference
+func GetUserResultFormat(r *http.Request, userID int) (calculate.ResultFormat, error) {
+	format := calculate.ResultFormat{Digits: -1}
+	preferences, err := database.GetPreferences(config.GetDatabasePath(), userID)
+	if err != nil {
+		log.Printf("Failed to get preferences: %v", err)
+	} else {
+		format = calculate.ResultFormat{Mode: preferences.ResultFormat, Digits: preferences.Digits}
+	}
+	if err := format.Validate(); err != nil {
+		// Saved before digits were limited, fall back to the default of the mode
+		log.Printf("Ignoring saved digits of user %d: %v", userID, err)
+		format.Digits = -1
+	}
+
+	query := r.URL.Query()
+	if query.Has("result") {
+		format = calculate.ResultFormat{Mode: query.Get("result"), Digits: -1}
+	}
+	if !calculate.IsResultMode(format.Mode) {
+		return format, fmt.Errorf("unknown result format %s", format.Mode)
+	}
+	if query.Has("digits") {
+		digits, err := strconv.Atoi(query.Get("digits"))
+		if err != nil || digits < 0 {
+			return format, fmt.Errorf("invalid digits %s", query.Get("digits"))
+		}
+		format.Digits = digits
+	}
+	return format, format.Validate()
+}
+
+// The result arrives as float32 over gRPC, print it with float32 precision
+// so 0.3 does not turn into 0.30000001192092896
+func float32ToFloat64(value float32) float64 {
+	result, _ := strconv.ParseFloat(strconv.FormatFloat(float64(value), 'g', -1, 32), 64)
+	return result
+}
+
 func Calculate(w http.ResponseWriter, r *http.Request) {
 	var calculation Calculation
 	err := json.NewDecoder(r.Body).Decode(&calculation)
@@ -157,6 +306,20 @@ func Calculate(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
+	if !calculate.IsNotation(calculation.Notation) {
+		http.Error(w, "Unknown notation, use infix, rpn or prefix", http.StatusBadRequest)
+		return
+	}
+	// A pre-parsed tree is sent on in its canonical form, which is also what is stored.
+	// That form is written in the default locale, whatever the user's is
+	if calculation.AST != nil {
+		if err := calculation.AST.Validate(); err != nil {
+			writeExpressionError(w, fmt.Sprintf("Invalid ast: %v", err), 0)
+			return
+		}
+		calculation.Expression = calculation.AST.String()
+		calculation.Notation = calculate.NotationInfix
+	}
 
 	// Get userId from token
 	userID, err := GetUserIdFromToken(w, r, w.Header().Get("Authorization"))
@@ -165,9 +328,19 @@ func Calculate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	log.Printf("User ID from token: %d 💡", userID)
+	// Checked before submitting, a result from the cache is formatted right away
+	resultFormat, err := GetUserResultFormat(r, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	locale := GetUserLocale(r, userID).Name
+	if calculation.AST != nil {
+		locale = ""
+	}
 
 	// gRPC call to send the user data and calculation
-	conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+	conn, err := dialOrchestrator()
 	if err != nil {
 		http.Error(w, "Failed to connect to gRPC server", http.StatusInternalServerError)
 		return
@@ -180,7 +353,10 @@ func Calculate(w http.ResponseWriter, r *http.Request) {
 	req := &user.UserDataRequest{
 		UserId:   int32(userID),
 		Calculation: &user.Calculation{
-			Expression: calculation.Expression,
+			Expression:  calculation.Expression,
+			Notation:    calculation.Notation,
+			Locale:      locale,
+			CallbackUrl: calculation.CallbackUrl,
 		},
 	}
 
@@ -189,19 +365,162 @@ func Calculate(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	// Send the request
-	res, err := client.SendUserData(ctx, req)
+	res, err := client.SendUserData(withToken(ctx, r), req)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Failed to send user data to gRPC server", http.StatusInternalServerError)
+		writeGRPCError(w, err, "Failed to send user data to gRPC server")
 		return
 	}
 
 	// Log the response from the gRPC server
 	log.Printf("Server says: %s 🗣️", res.Message)
 
-	// Send the response back to the client
+	// Send the response back to the client, the calculation itself runs in the background
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": res.Message})
+	// A result known from the cache is there already
+	if res.Status == database.StatusDone {
+		result, err := resultFormat.Format(res.Result, GetUserLocale(r, userID))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      res.Id,
+			"status":  res.Status,
+			"result":  result,
+			"message": res.Message,
+		})
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      res.Id,
+		"status":  res.Status,
+		"message": res.Message,
+	})
+}
+
+// CalculateBatch saves several expressions at once. Every item is answered on its own,
+// the batch is accepted if at least one of them was saved
+func CalculateBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var batch Batch
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if len(batch.Expressions) == 0 {
+		http.Error(w, "No expressions provided", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := GetUserIdFromToken(w, r, w.Header().Get("Authorization"))
+	if err != nil {
+		http.Error(w, "Failed to get userId from token", http.StatusUnauthorized)
+		return
+	}
+	userLocale := GetUserLocale(r, userID).Name
+
+	// Items rejected here are not sent, indexes maps the sent ones back to the batch
+	items := make([]BatchItem, len(batch.Expressions))
+	indexes := []int{}
+	calculations := []*user.Calculation{}
+	for i, calculation := range batch.Expressions {
+		items[i] = BatchItem{Index: i, Status: "rejected"}
+		if !calculate.IsNotation(calculation.Notation) {
+			items[i].Error = "Unknown notation, use infix, rpn or prefix"
+			continue
+		}
+		locale := userLocale
+		if calculation.AST != nil {
+			if err := calculation.AST.Validate(); err != nil {
+				items[i].Error = fmt.Sprintf("Invalid ast: %v", err)
+				continue
+			}
+			calculation.Expression = calculation.AST.String()
+			calculation.Notation = calculate.NotationInfix
+			locale = ""
+		}
+		indexes = append(indexes, i)
+		calculations = append(calculations, &user.Calculation{
+			Expression:  calculation.Expression,
+			Notation:    calculation.Notation,
+			Locale:      locale,
+			CallbackUrl: calculation.CallbackUrl,
+		})
+	}
+
+	accepted := false
+	if len(calculations) > 0 {
+		conn, err := dialOrchestrator()
+		if err != nil {
+			http.Error(w, "Failed to connect to gRPC server", http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+
+		client := user.NewUserServiceClient(conn)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		res, err := client.SendBatch(withToken(ctx, r), &user.BatchRequest{UserId: int32(userID), Calculations: calculations})
+		if err != nil {
+			writeGRPCError(w, err, "Failed to send the batch to gRPC server")
+			return
+		}
+		for _, item := range res.Items {
+			i := indexes[item.Index]
+			items[i].Id = int(item.Id)
+			items[i].Status = item.Status
+			items[i].Error = item.Error
+			if item.Status != "rejected" {
+				accepted = true
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if accepted {
+		w.WriteHeader(http.StatusAccepted)
+	} else {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
+
+func ParseExpression(w http.ResponseWriter, r *http.Request) {
+	var calculation Calculation
+	err := json.NewDecoder(r.Body).Decode(&calculation)
+	if err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if !calculate.IsNotation(calculation.Notation) {
+		http.Error(w, "Unknown notation, use infix, rpn or prefix", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := GetUserIdFromToken(w, r, w.Header().Get("Authorization"))
+	if err != nil {
+		http.Error(w, "Failed to get userId from token", http.StatusUnauthorized)
+		return
+	}
+	log.Printf("User ID from token: %d 🌳", userID)
+
+	node, err := calculate.ParseWithLocale(calculation.Expression, calculation.Notation, GetUserLocale(r, userID))
+	if err != nil {
+		writeExpressionError(w, err.Error(), calculate.ErrorPosition(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ParsedExpression{
+		Expression: node.String(),
+		AST:        node,
+	})
 }
 
 func GetExpressions(w http.ResponseWriter, r *http.Request) {
@@ -212,7 +531,13 @@ func GetExpressions(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("User ID from token: %d 📊", userID)
 
-	conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+	resultFormat, err := GetUserResultFormat(r, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := dialOrchestrator()
 	if err != nil {
 		http.Error(w, "Failed to connect to gRPC server", http.StatusInternalServerError)
 		return
@@ -222,21 +547,31 @@ func GetExpressions(w http.ResponseWriter, r *http.Request) {
 	client := user.NewUserServiceClient(conn)
 
 	// Send gRPC request
-	res, err := client.GetUserCalculations(context.Background(), &user.UserIdRequest{
+	res, err := client.GetUserCalculations(withToken(context.Background(), r), &user.UserIdRequest{
 		UserId: int32(userID),
 	})
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Failed to get calculations", http.StatusInternalServerError)
+		writeGRPCError(w, err, "Failed to get calculations")
 		return
 	}
 
 	// Convert gRPC response to your `Calculations` struct
+	locale := GetUserLocale(r, userID)
 	calcs := Calculations{}
 	for _, c := range res.Calculations {
-		calcs.Calculations = append(calcs.Calculations, Calculation{
+		expression := Expression{
+			Id:         int(c.Id),
 			Expression: c.Expression,
-		})
+			Status:     c.Status,
+		}
+		if c.Status == database.StatusDone {
+			expression.Result, err = resultFormat.Format(float32ToFloat64(c.Result), locale)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		calcs.Calculations = append(calcs.Calculations, expression)
 	}
 
 	// Respond to client
@@ -245,6 +580,19 @@ func GetExpressions(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetExpressionById(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete || strings.HasSuffix(r.URL.Path, "/cancel") {
+		CancelExpression(w, r)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, "/events") {
+		WatchExpression(w, r)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, "/plan") {
+		ExpressionPlan(w, r)
+		return
+	}
+
 	userID, err := GetUserIdFromToken(w, r, w.Header().Get("Authorization"))
 	if err != nil {
 		http.Error(w, "Failed to get userId from token", http.StatusUnauthorized)
@@ -260,9 +608,21 @@ func GetExpressionById(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("Expression ID: %d ✨", expressionIDInt)
 
-	conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure()) // Replace with your server address
+	format := r.URL.Query().Get("format")
+	if format != "" && !calculate.IsFormat(format) {
+		http.Error(w, "Unknown format, use latex, mathml, unicode or infix-minimal", http.StatusBadRequest)
+		return
+	}
+	resultFormat, err := GetUserResultFormat(r, userID)
 	if err != nil {
-		log.Fatalf("❌ Failed to connect to server: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := dialOrchestrator()
+	if err != nil {
+		http.Error(w, "Failed to connect to gRPC server", http.StatusInternalServerError)
+		return
 	}
 	defer conn.Close()
 
@@ -270,30 +630,387 @@ func GetExpressionById(w http.ResponseWriter, r *http.Request) {
 	client := user.NewUserServiceClient(conn)
 
 	// Create the request object with the userId and customId
-	request := &user.UserDataRequest{
+	request := &user.GetUserCalculationRequest{
 		UserId:   int32(userID),
 		CustomId: int32(expressionIDInt),
+		Format:   format,
+	}
+
+	response, err := client.GetUserCalculation(withToken(context.Background(), r), request)
+	if err != nil {
+		writeGRPCError(w, err, "Failed to get expression")
+		return
+	}
+
+	expression := Expression{
+		Id:         int(response.GetId()),
+		Expression: response.GetExpression(),
+		Status:     response.GetStatus(),
+		Error:      response.GetError(),
+		CreatedAt:  response.GetCreatedAt(),
+		UpdatedAt:  response.GetUpdatedAt(),
+		Message:    fmt.Sprintf("✅ Retrieved expression: %s", response.GetExpression()),
+	}
+	if expression.Status == database.StatusDone {
+		expression.Result, err = resultFormat.Format(response.GetResult(), GetUserLocale(r, userID))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if format != "" {
+		expression.Format = format
+		expression.Formatted = response.GetFormatted()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(expression)
+}
+
+func Preferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIdFromToken(w, r, w.Header().Get("Authorization"))
+	if err != nil {
+		http.Error(w, "Failed to get userId from token", http.StatusUnauthorized)
+		return
+	}
+	log.Printf("User ID from token: %d ⚙️", userID)
+
+	preferences, err := database.GetPreferences(config.GetDatabasePath(), userID)
+	if err != nil {
+		http.Error(w, "Failed to get preferences", http.StatusInternalServerError)
+		return
 	}
 
-	// Send the request using the SendUserData method
-	response, err := client.SendUserData(context.Background(), request)
+	if r.Method == http.MethodPut || r.Method == http.MethodPost {
+		// Only the fields that are sent are changed
+		var update struct {
+			Locale       *string `json:"locale"`
+			ResultFormat *string `json:"resultFormat"`
+			Digits       *int    `json:"digits"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&update)
+		if err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if update.Locale != nil && *update.Locale == "" {
+			preferences.Locale = ""
+		} else if update.Locale != nil {
+			locale, ok := calculate.GetLocale(*update.Locale)
+			if !ok {
+				http.Error(w, "Unknown locale", http.StatusBadRequest)
+				return
+			}
+			preferences.Locale = locale.Name
+		}
+		if update.ResultFormat != nil {
+			if !calculate.IsResultMode(*update.ResultFormat) {
+				http.Error(w, "Unknown result format, use fixed, significant, scientific, engineering or fraction", http.StatusBadRequest)
+				return
+			}
+			preferences.ResultFormat = *update.ResultFormat
+		}
+		if update.Digits != nil {
+			preferences.Digits = *update.Digits
+		}
+		format := calculate.ResultFormat{Mode: preferences.ResultFormat, Digits: preferences.Digits}
+		if err := format.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = database.SavePreferences(config.GetDatabasePath(), *preferences)
+		if err != nil {
+			http.Error(w, "Failed to save preferences", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preferences)
+}
+
+// CancelExpression stops a calculation, either with DELETE /api/v1/expression/{id}
+// or POST /api/v1/expression/{id}/cancel
+func CancelExpression(w http.ResponseWriter, r *http.Request) {
+	cancelPath := strings.HasSuffix(r.URL.Path, "/cancel")
+	if (cancelPath && r.Method != http.MethodPost) || (!cancelPath && r.Method != http.MethodDelete) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := GetUserIdFromToken(w, r, w.Header().Get("Authorization"))
 	if err != nil {
-		log.Fatalf("❌ Failed to send data: %v", err)
+		http.Error(w, "Failed to get userId from token", http.StatusUnauthorized)
+		return
 	}
 
-	// Print the response message
-	log.Printf("Response from server: %s 💬", response.GetMessage())
+	expressionID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/expression/"), "/cancel")
+	expressionIDInt, err := strconv.Atoi(expressionID)
+	if err != nil {
+		http.Error(w, "Invalid expression ID", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := dialOrchestrator()
+	if err != nil {
+		http.Error(w, "Failed to connect to gRPC server", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	client := user.NewUserServiceClient(conn)
+	res, err := client.CancelCalculation(withToken(context.Background(), r), &user.CancelCalculationRequest{
+		UserId: int32(userID),
+		Id:     int32(expressionIDInt),
+	})
+	if err != nil {
+		writeGRPCError(w, err, "Failed to cancel calculation")
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": response.GetMessage()})
+	json.NewEncoder(w).Encode(Expression{
+		Id:         int(res.Id),
+		Expression: res.Expression,
+		Status:     res.Status,
+		Message:    res.Message,
+	})
+}
+
+// WatchExpression streams the status changes and partial results of a calculation
+// as Server-Sent Events on GET /api/v1/expression/{id}/events
+func WatchExpression(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIdFromToken(w, r, w.Header().Get("Authorization"))
+	if err != nil {
+		http.Error(w, "Failed to get userId from token", http.StatusUnauthorized)
+		return
+	}
+
+	expressionID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/expression/"), "/events")
+	expressionIDInt, err := strconv.Atoi(expressionID)
+	if err != nil {
+		http.Error(w, "Invalid expression ID", http.StatusBadRequest)
+		return
+	}
+	resultFormat, err := GetUserResultFormat(r, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	locale := GetUserLocale(r, userID)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	conn, err := dialOrchestrator()
+	if err != nil {
+		http.Error(w, "Failed to connect to gRPC server", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	// The stream ends when the client goes away
+	client := user.NewUserServiceClient(conn)
+	stream, err := client.WatchCalculation(withToken(r.Context(), r), &user.WatchCalculationRequest{
+		UserId: int32(userID),
+		Id:     int32(expressionIDInt),
+	})
+	if err != nil {
+		writeGRPCError(w, err, "Failed to watch calculation")
+		return
+	}
+	// Errors of a stream arrive with the first message
+	first, err := stream.Recv()
+	if err != nil {
+		writeGRPCError(w, err, "Failed to watch calculation")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	for event := first; err == nil; event, err = stream.Recv() {
+		data := ExpressionEvent{
+			Id:             int(event.Id),
+			Status:         event.Status,
+			Error:          event.Error,
+			Path:           event.Path,
+			CompletedTasks: int(event.CompletedTasks),
+			TotalTasks:     int(event.TotalTasks),
+		}
+		if event.Status == database.StatusDone {
+			data.Result, _ = resultFormat.Format(event.Result, locale)
+		}
+		if event.Type == "progress" {
+			data.Value, _ = resultFormat.Format(event.Value, locale)
+		}
+		payload, _ := json.Marshal(data)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, payload)
+		flusher.Flush()
+	}
+}
+
+// ExpressionPlan shows the operations of a calculation, which of them share a result
+// and when they were computed
+func ExpressionPlan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, err := GetUserIdFromToken(w, r, w.Header().Get("Authorization"))
+	if err != nil {
+		http.Error(w, "Failed to get userId from token", http.StatusUnauthorized)
+		return
+	}
+
+	expressionID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/expression/"), "/plan")
+	expressionIDInt, err := strconv.Atoi(expressionID)
+	if err != nil {
+		http.Error(w, "Invalid expression ID", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := dialOrchestrator()
+	if err != nil {
+		http.Error(w, "Failed to connect to gRPC server", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	client := user.NewUserServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	res, err := client.GetCalculationPlan(withToken(ctx, r), &user.CalculationPlanRequest{
+		UserId: int32(userID),
+		Id:     int32(expressionIDInt),
+	})
+	if err != nil {
+		writeGRPCError(w, err, "Failed to get the plan of the calculation")
+		return
+	}
+
+	plan := ExpressionPlanResponse{
+		Id:             int(res.Id),
+		Status:         res.Status,
+		Expression:     res.Expression,
+		Live:           res.Live,
+		CompletedTasks: int(res.CompletedTasks),
+		TotalTasks:     int(res.TotalTasks),
+		Nodes:          []PlanNode{},
+	}
+	for _, node := range res.Nodes {
+		planNode := PlanNode{
+			Id:         int(node.Id),
+			Path:       node.Path,
+			Op:         node.Op,
+			Value:      node.Value,
+			DependsOn:  []int{},
+			Uses:       int(node.Uses),
+			State:      node.State,
+			AgentId:    node.AgentId,
+			ReadyAt:    node.ReadyAt,
+			StartedAt:  node.StartedAt,
+			FinishedAt: node.FinishedAt,
+		}
+		for _, id := range node.DependsOn {
+			planNode.DependsOn = append(planNode.DependsOn, int(id))
+		}
+		plan.Nodes = append(plan.Nodes, planNode)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+// WebhookSecret returns the secret the webhooks of the user are signed with (GET)
+// or replaces it with a new one (POST)
+func WebhookSecret(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIdFromToken(w, r, w.Header().Get("Authorization"))
+	if err != nil {
+		http.Error(w, "Failed to get userId from token", http.StatusUnauthorized)
+		return
+	}
+
+	var secret string
+	switch r.Method {
+	case http.MethodGet:
+		secret, err = database.GetWebhookSecret(config.GetDatabasePath(), userID)
+	case http.MethodPost:
+		secret, err = database.RotateWebhookSecret(config.GetDatabasePath(), userID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Failed to get webhook secret", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"secret": secret})
+}
+
+// Metrics shows how busy the calculation workers are
+func Metrics(w http.ResponseWriter, r *http.Request) {
+	_, err := GetUserIdFromToken(w, r, w.Header().Get("Authorization"))
+	if err != nil {
+		http.Error(w, "Failed to get userId from token", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := dialOrchestrator()
+	if err != nil {
+		http.Error(w, "Failed to connect to gRPC server", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	client := user.NewUserServiceClient(conn)
+	res, err := client.GetMetrics(withToken(context.Background(), r), &user.MetricsRequest{})
+	if err != nil {
+		writeGRPCError(w, err, "Failed to get metrics")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"workers":               res.Workers,
+		"busyWorkers":           res.BusyWorkers,
+		"utilisation":           res.Utilisation,
+		"queueDepth":            res.QueueDepth,
+		"queueCapacity":         res.QueueCapacity,
+		"interactiveQueueDepth": res.InteractiveQueueDepth,
+		"batchQueueDepth":       res.BatchQueueDepth,
+		"processed":             res.Processed,
+		"rejected":              res.Rejected,
+		"taskQueueDepth":        res.TaskQueueDepth,
+		"tasksRunning":          res.TasksRunning,
+		"cacheEnabled":          res.CacheEnabled,
+		"cacheHits":             res.CacheHits,
+		"cacheMisses":           res.CacheMisses,
+		"cacheEntries":          res.CacheEntries,
+	})
 }
 
 func StartApplicationServer() {
 	http.HandleFunc("/api/v1/register", SaveRegUser)
 	http.HandleFunc("/api/v1/login", LoginUser)
 	http.HandleFunc("/api/v1/calculate", Calculate)
+	http.HandleFunc("/api/v1/calculate/batch", CalculateBatch)
+	http.HandleFunc("/api/v1/parse", ParseExpression)
 	http.HandleFunc("/api/v1/expressions", GetExpressions)
 	http.HandleFunc("/api/v1/expression/", GetExpressionById)
+	http.HandleFunc("/api/v1/preferences", Preferences)
+	http.HandleFunc("/api/v1/metrics", Metrics)
+	http.HandleFunc("/api/v1/webhook/secret", WebhookSecret)
+	http.HandleFunc("/api/v1/schedules", Schedules)
+	http.HandleFunc("/api/v1/schedules/", ScheduleById)
+	http.HandleFunc("/api/v1/admin/agents", Agents)
+	http.HandleFunc("/api/v1/admin/dead-letters", DeadLetters)
+	http.HandleFunc("/api/v1/admin/dead-letters/", RequeueDeadLetter)
 	log.Println("Server started at http://localhost:8082 🚀")
 	http.ListenAndServe(":8082", nil)
 }
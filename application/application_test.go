@@ -2,13 +2,39 @@ package application
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	calculate "github.com/ArteShow/Calculator/pkg/Calculation"
+	MyJWT "github.com/ArteShow/Calculator/pkg/JWT"
+	"github.com/ArteShow/Calculator/pkg/setup"
+	user "github.com/ArteShow/Calculator/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "application")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("DB_PATH", filepath.Join(dir, "test.db"))
+	setup.Setup()
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestSaveRegUser(t *testing.T) {
 	payload := `{"login": "testuser", "password": "testpass"}`
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/register", bytes.NewBufferString(payload))
@@ -86,3 +112,442 @@ func TestGetExpressionById_InvalidToken(t *testing.T) {
 		t.Errorf("expected 401 for invalid token, got %d", res.StatusCode)
 	}
 }
+
+func TestGetExpressionById_UnknownFormat(t *testing.T) {
+	token, err := MyJWT.CreateJWT(1, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/expression/1?format=roman", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+
+	GetExpressionById(w, req)
+	res := w.Result()
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown format, got %d", res.StatusCode)
+	}
+}
+
+// fakeUserService knows calculation 1 of user 1 only and wants the caller's token
+type fakeUserService struct {
+	user.UnimplementedUserServiceServer
+}
+
+func (f *fakeUserService) GetUserCalculation(ctx context.Context, req *user.GetUserCalculationRequest) (*user.UserCalculationResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("authorization"); len(values) == 0 || !strings.HasPrefix(values[0], "Bearer ") {
+		return nil, status.Error(codes.Unauthenticated, "Missing bearer token")
+	}
+	if req.UserId != 1 || req.CustomId != 1 {
+		return nil, status.Error(codes.NotFound, "No calculation found")
+	}
+	return &user.UserCalculationResponse{Id: 1, Expression: "(1+2)", Status: "done", Result: 3}, nil
+}
+
+func TestGetExpressionById_GRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:50051")
+	if err != nil {
+		t.Skipf("orchestrator port is in use: %v", err)
+	}
+	server := grpc.NewServer()
+	user.RegisterUserServiceServer(server, &fakeUserService{})
+	go server.Serve(listener)
+	defer server.Stop()
+
+	token, err := MyJWT.CreateJWT(1, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	for path, code := range map[string]int{
+		"/api/v1/expression/1": http.StatusOK,
+		"/api/v1/expression/2": http.StatusNotFound,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		GetExpressionById(w, req)
+		if w.Code != code {
+			t.Fatalf("%s: expected %d, got %d", path, code, w.Code)
+		}
+		if code == http.StatusOK && !strings.Contains(w.Body.String(), `"result":"3"`) {
+			t.Fatalf("unexpected body %s", w.Body.String())
+		}
+	}
+}
+
+// computingUserService reads the calculations it gets the way the orchestrator does
+// and keeps their results
+type computingUserService struct {
+	user.UnimplementedUserServiceServer
+	results []float64
+}
+
+func (f *computingUserService) compute(calculation *user.Calculation) error {
+	locale := calculate.DefaultLocale
+	if calculation.Locale != "" {
+		locale, _ = calculate.GetLocale(calculation.Locale)
+	}
+	node, err := calculate.ParseWithLocale(calculation.Expression, calculation.Notation, locale)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	result, err, _ := calculate.Evaluate(node)
+	f.results = append(f.results, result)
+	return err
+}
+
+func (f *computingUserService) SendUserData(ctx context.Context, req *user.UserDataRequest) (*user.UserDataResponse, error) {
+	if err := f.compute(req.Calculation); err != nil {
+		return nil, err
+	}
+	return &user.UserDataResponse{Id: 1, Status: "pending"}, nil
+}
+
+func (f *computingUserService) SendBatch(ctx context.Context, req *user.BatchRequest) (*user.BatchResponse, error) {
+	res := &user.BatchResponse{}
+	for i, calculation := range req.Calculations {
+		if err := f.compute(calculation); err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &user.BatchItem{Index: int32(i), Id: int32(i + 1), Status: "pending"})
+	}
+	return res, nil
+}
+
+// A tree has its numbers as JSON numbers, the user's locale must not change how they are read
+func TestCalculate_ASTWithLocale(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:50051")
+	if err != nil {
+		t.Skipf("orchestrator port is in use: %v", err)
+	}
+	fake := &computingUserService{}
+	server := grpc.NewServer()
+	user.RegisterUserServiceServer(server, fake)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	token, err := MyJWT.CreateJWT(1, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	ast := `{"op": "+", "args": [{"op": "num", "value": 1.234}, {"op": "num", "value": 2.5}]}`
+	for _, send := range []struct {
+		handler func(http.ResponseWriter, *http.Request)
+		path    string
+		payload string
+	}{
+		{Calculate, "/api/v1/calculate", `{"ast": ` + ast + `}`},
+		{CalculateBatch, "/api/v1/calculate/batch", `{"expressions": [{"ast": ` + ast + `}]}`},
+	} {
+		req := httptest.NewRequest(http.MethodPost, send.path, bytes.NewBufferString(send.payload))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept-Language", "de-DE")
+		w := httptest.NewRecorder()
+		send.handler(w, req)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("%s: expected 202, got %d: %s", send.path, w.Code, w.Body.String())
+		}
+	}
+	if len(fake.results) != 2 || fake.results[0] != 3.734 || fake.results[1] != 3.734 {
+		t.Fatalf("expected 3.734 twice, got %v", fake.results)
+	}
+}
+
+func TestCancelExpression_BadRequests(t *testing.T) {
+	token, err := MyJWT.CreateJWT(1, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	cases := []struct {
+		method, path string
+		status       int
+	}{
+		{http.MethodGet, "/api/v1/expression/1/cancel", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/api/v1/expression/abc", http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/expression/abc/cancel", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+
+		GetExpressionById(w, req)
+		if w.Result().StatusCode != c.status {
+			t.Errorf("%s %s: expected %d, got %d", c.method, c.path, c.status, w.Result().StatusCode)
+		}
+	}
+}
+
+func TestCalculateBatch_Rejected(t *testing.T) {
+	token, err := MyJWT.CreateJWT(1, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	cases := []struct {
+		method, payload string
+		status          int
+	}{
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, `{"expressions": []}`, http.StatusBadRequest},
+		// Every item is rejected before anything is sent to the gRPC server
+		{http.MethodPost, `{"expressions": [{"expression": "1 2 +", "notation": "postfix"}]}`, http.StatusUnprocessableEntity},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, "/api/v1/calculate/batch", strings.NewReader(c.payload))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+
+		CalculateBatch(w, req)
+		if w.Result().StatusCode != c.status {
+			t.Errorf("%s %s: expected %d, got %d", c.method, c.payload, c.status, w.Result().StatusCode)
+		}
+	}
+}
+
+func TestExpressionPlan_BadRequests(t *testing.T) {
+	token, err := MyJWT.CreateJWT(1, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	cases := []struct {
+		method, path string
+		status       int
+	}{
+		{http.MethodPost, "/api/v1/expression/1/plan", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/v1/expression/abc/plan", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+
+		GetExpressionById(w, req)
+		if w.Result().StatusCode != c.status {
+			t.Errorf("%s %s: expected %d, got %d", c.method, c.path, c.status, w.Result().StatusCode)
+		}
+	}
+}
+
+func TestParseExpression(t *testing.T) {
+	token, err := MyJWT.CreateJWT(1, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	payload := `{"expression": "1 2 + 3 *", "notation": "rpn"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/parse", bytes.NewBufferString(payload))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+
+	ParseExpression(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+	body, _ := io.ReadAll(res.Body)
+	expected := `{"expression":"((1+2)*3)","ast":{"op":"*","args":[{"op":"+","args":[{"op":"num","value":1},{"op":"num","value":2}]},{"op":"num","value":3}]}}`
+	if strings.TrimSpace(string(body)) != expected {
+		t.Errorf("unexpected body %s", body)
+	}
+}
+
+func TestParseExpression_Invalid(t *testing.T) {
+	token, err := MyJWT.CreateJWT(1, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/parse", bytes.NewBufferString(`{"expression": "2+"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+
+	ParseExpression(w, req)
+	res := w.Result()
+
+	if res.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for invalid expression, got %d", res.StatusCode)
+	}
+}
+
+func TestCalculate_InvalidAST(t *testing.T) {
+	payload := `{"ast": {"op": "%", "args": [{"op": "num", "value": 1}, {"op": "num", "value": 2}]}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", bytes.NewBufferString(payload))
+	w := httptest.NewRecorder()
+
+	Calculate(w, req)
+	res := w.Result()
+
+	if res.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for invalid ast, got %d", res.StatusCode)
+	}
+}
+
+// A bad result format is refused before the expression is submitted, as when listing
+func TestCalculate_InvalidResultFormat(t *testing.T) {
+	token, err := MyJWT.CreateJWT(5, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	for _, query := range []string{"?result=roman", "?result=fixed&digits=1000", "?digits=abc"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate"+query, bytes.NewBufferString(`{"expression": "1+2"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+
+		Calculate(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestPreferences(t *testing.T) {
+	token, err := MyJWT.CreateJWT(2, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/preferences", bytes.NewBufferString(`{"locale": "de"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+
+	Preferences(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+	body, _ := io.ReadAll(res.Body)
+	if !strings.Contains(string(body), `"locale":"de-DE"`) {
+		t.Errorf("expected saved locale de-DE, got %s", body)
+	}
+
+	// The saved locale is used to read numbers
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/parse", bytes.NewBufferString(`{"expression": "1.000,5*2"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	ParseExpression(w, req)
+	body, _ = io.ReadAll(w.Result().Body)
+	if !strings.Contains(string(body), `"expression":"(1000.5*2)"`) {
+		t.Errorf("expected de-DE number parsing, got %s", body)
+	}
+
+	// The Accept-Language header overrides it
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/parse", bytes.NewBufferString(`{"expression": "1,000.5*2"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept-Language", "en-US")
+	w = httptest.NewRecorder()
+	ParseExpression(w, req)
+	body, _ = io.ReadAll(w.Result().Body)
+	if !strings.Contains(string(body), `"expression":"(1000.5*2)"`) {
+		t.Errorf("expected en-US number parsing, got %s", body)
+	}
+}
+
+func TestPreferences_UnknownLocale(t *testing.T) {
+	token, err := MyJWT.CreateJWT(2, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/preferences", bytes.NewBufferString(`{"locale": "xx-YY"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+
+	Preferences(w, req)
+
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown locale, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestGetUserResultFormat(t *testing.T) {
+	token, err := MyJWT.CreateJWT(3, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/preferences", bytes.NewBufferString(`{"resultFormat": "fraction"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	Preferences(w, req)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Result().StatusCode)
+	}
+
+	// The saved default is used without query parameters
+	format, err := GetUserResultFormat(httptest.NewRequest(http.MethodGet, "/api/v1/expressions", nil), 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, _ := format.Format(1.0/3, calculate.DefaultLocale)
+	if result != "1/3" {
+		t.Errorf("expected 1/3, got %s", result)
+	}
+
+	// The request overrides it
+	format, err = GetUserResultFormat(httptest.NewRequest(http.MethodGet, "/api/v1/expressions?result=fixed&digits=3", nil), 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, _ = format.Format(1.0/3, calculate.DefaultLocale)
+	if result != "0.333" {
+		t.Errorf("expected 0.333, got %s", result)
+	}
+
+	_, err = GetUserResultFormat(httptest.NewRequest(http.MethodGet, "/api/v1/expressions?result=roman", nil), 3)
+	if err == nil {
+		t.Errorf("expected error for unknown result format")
+	}
+
+	_, err = GetUserResultFormat(httptest.NewRequest(http.MethodGet, "/api/v1/expressions?result=fixed&digits=1000000000", nil), 3)
+	if err == nil {
+		t.Errorf("expected error for too many digits")
+	}
+}
+
+func TestPreferences_TooManyDigits(t *testing.T) {
+	token, err := MyJWT.CreateJWT(3, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	for _, body := range []string{`{"resultFormat": "fixed", "digits": 18}`, `{"resultFormat": "fraction", "digits": 1000000000}`} {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/preferences", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+
+		Preferences(w, req)
+
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", body, w.Result().StatusCode)
+		}
+	}
+}
+
+func TestWebhookSecret(t *testing.T) {
+	token, err := MyJWT.CreateJWT(4, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	getSecret := func(method string) string {
+		req := httptest.NewRequest(method, "/api/v1/webhook/secret", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		WebhookSecret(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", method, w.Code)
+		}
+		var body map[string]string
+		json.NewDecoder(w.Body).Decode(&body)
+		return body["secret"]
+	}
+
+	secret := getSecret(http.MethodGet)
+	if secret == "" || getSecret(http.MethodGet) != secret {
+		t.Fatalf("expected a stable secret, got %q", secret)
+	}
+	rotated := getSecret(http.MethodPost)
+	if rotated == secret || getSecret(http.MethodGet) != rotated {
+		t.Fatalf("expected the secret to be rotated")
+	}
+}
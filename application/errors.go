@@ -0,0 +1,107 @@
+package application
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorInfo reason the gRPC server sends for an expression that can not be parsed
+const reasonInvalidExpression = "INVALID_EXPRESSION"
+
+// httpStatus translates the code of a gRPC error into an HTTP status. An invalid
+// expression is 422 Unprocessable Entity, any other invalid argument 400 Bad Request
+func httpStatus(st *status.Status) int {
+	switch st.Code() {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument:
+		if _, ok := expressionErrorInfo(st); ok {
+			return http.StatusUnprocessableEntity
+		}
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.FailedPrecondition, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted, codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// The message of these codes tells the user what to change, the others are only logged
+func userError(code codes.Code) bool {
+	switch code {
+	case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.FailedPrecondition,
+		codes.Aborted, codes.PermissionDenied, codes.Unauthenticated, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+func expressionErrorInfo(st *status.Status) (*errdetails.ErrorInfo, bool) {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Reason == reasonInvalidExpression {
+			return info, true
+		}
+	}
+	return nil, false
+}
+
+// retryAfter is the Retry-After header for an error, in whole seconds. It follows the
+// RetryInfo of the error and is one second without one
+func retryAfter(st *status.Status) string {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.RetryDelay.AsDuration() > 0 {
+			return strconv.Itoa(int(math.Ceil(info.RetryDelay.AsDuration().Seconds())))
+		}
+	}
+	return "1"
+}
+
+// writeGRPCError answers a failed gRPC call with the matching HTTP status. Errors the
+// user can do something about are passed on with their message, the others are
+// logged and answered with fallback
+func writeGRPCError(w http.ResponseWriter, err error, fallback string) {
+	st := status.Convert(err)
+	code := httpStatus(st)
+	if code == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", retryAfter(st))
+	}
+	if !userError(st.Code()) {
+		log.Printf("❌ %s: %v", fallback, err)
+		http.Error(w, fallback, code)
+		return
+	}
+	if info, ok := expressionErrorInfo(st); ok {
+		position, _ := strconv.Atoi(info.Metadata["position"])
+		writeExpressionError(w, st.Message(), position)
+		return
+	}
+	http.Error(w, st.Message(), code)
+}
+
+// writeExpressionError answers 422 for an expression that can not be parsed, with
+// the 1-based position of the problem if it is known
+func writeExpressionError(w http.ResponseWriter, message string, position int) {
+	body := map[string]interface{}{"message": message}
+	if position > 0 {
+		body["position"] = position
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,79 @@
+package application
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestWriteGRPCError(t *testing.T) {
+	queueFull, _ := status.New(codes.ResourceExhausted, "Too many calculations").
+		WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(2500 * time.Millisecond)})
+	cases := []struct {
+		err        error
+		code       int
+		body       string
+		retryAfter string
+	}{
+		{status.Error(codes.InvalidArgument, "Unknown locale xx"), http.StatusBadRequest, "Unknown locale xx", ""},
+		{status.Error(codes.NotFound, "No calculation found"), http.StatusNotFound, "No calculation found", ""},
+		{status.Error(codes.FailedPrecondition, "Calculation 1 is already done"), http.StatusConflict, "already done", ""},
+		{status.Error(codes.PermissionDenied, "Not your calculation"), http.StatusForbidden, "Not your calculation", ""},
+		{status.Error(codes.Unauthenticated, "Invalid token"), http.StatusUnauthorized, "Invalid token", ""},
+		{queueFull.Err(), http.StatusServiceUnavailable, "Too many calculations", "3"},
+		{status.Error(codes.ResourceExhausted, "Too many calculations"), http.StatusServiceUnavailable, "Too many calculations", "1"},
+		{status.Error(codes.DeadlineExceeded, "context deadline exceeded"), http.StatusGatewayTimeout, "Failed", ""},
+		{status.Error(codes.Internal, "disk full"), http.StatusInternalServerError, "Failed", ""},
+		{errors.New("not a status"), http.StatusInternalServerError, "Failed", ""},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		writeGRPCError(w, c.err, "Failed")
+		if w.Code != c.code {
+			t.Errorf("%v: expected %d, got %d", c.err, c.code, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), c.body) || strings.Contains(w.Body.String(), "disk full") {
+			t.Errorf("%v: unexpected body %s", c.err, w.Body.String())
+		}
+		if got := w.Header().Get("Retry-After"); got != c.retryAfter {
+			t.Errorf("%v: expected Retry-After %q, got %q", c.err, c.retryAfter, got)
+		}
+	}
+}
+
+func TestWriteGRPCError_InvalidExpression(t *testing.T) {
+	st, err := status.New(codes.InvalidArgument, "Invalid expression: Unexpected end of expression at position 3").
+		WithDetails(&errdetails.ErrorInfo{
+			Reason:   reasonInvalidExpression,
+			Domain:   "calculator",
+			Metadata: map[string]string{"field": "expression", "position": "3"},
+		})
+	if err != nil {
+		t.Fatalf("WithDetails failed: %v", err)
+	}
+	w := httptest.NewRecorder()
+	writeGRPCError(w, st.Err(), "Failed")
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", w.Code)
+	}
+	var body struct {
+		Message  string `json:"message"`
+		Position int    `json:"position"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if body.Position != 3 || !strings.Contains(body.Message, "Unexpected end") {
+		t.Fatalf("unexpected body %+v", body)
+	}
+}
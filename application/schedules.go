@@ -0,0 +1,204 @@
+package application
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	calculate "github.com/ArteShow/Calculator/pkg/Calculation"
+	config "github.com/ArteShow/Calculator/pkg/Config"
+	cron "github.com/ArteShow/Calculator/pkg/Cron"
+	database "github.com/ArteShow/Calculator/pkg/Database"
+)
+
+// ScheduleRequest creates a schedule, or changes the fields that are sent
+type ScheduleRequest struct {
+	Expression  *string             `json:"expression"`
+	Variables   *map[string]float64 `json:"variables"`
+	Cron        *string             `json:"cron"`
+	CallbackUrl *string             `json:"callbackUrl"`
+	Enabled     *bool               `json:"enabled"`
+}
+
+// apply copies the sent fields into the schedule and works out its next run.
+// It returns the status code and message for an invalid schedule
+func (req ScheduleRequest) apply(schedule *database.Schedule, now time.Time) (int, string) {
+	if req.Expression != nil {
+		schedule.Expression = *req.Expression
+	}
+	if req.Variables != nil {
+		schedule.Variables = *req.Variables
+	}
+	if req.Cron != nil {
+		schedule.Cron = *req.Cron
+	}
+	if req.CallbackUrl != nil {
+		schedule.CallbackUrl = *req.CallbackUrl
+	}
+	if req.Enabled != nil {
+		schedule.Enabled = *req.Enabled
+	}
+
+	if schedule.Expression == "" || schedule.Cron == "" {
+		return http.StatusBadRequest, "expression and cron are required"
+	}
+	cronSchedule, err := cron.Parse(schedule.Cron)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Sprintf("Invalid cron: %v", err)
+	}
+	next, err := cronSchedule.Next(now.UTC())
+	if err != nil {
+		return http.StatusBadRequest, fmt.Sprintf("Invalid cron: %v", err)
+	}
+	if schedule.CallbackUrl != "" {
+		parsed, err := url.Parse(schedule.CallbackUrl)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return http.StatusBadRequest, "Invalid callbackUrl, use an http or https URL"
+		}
+	}
+	// Try the expression with the values it would get now
+	expanded, err := calculate.Expand(schedule.Expression, schedule.Variables, now)
+	if err == nil {
+		_, err = calculate.Parse(expanded)
+	}
+	if err != nil {
+		return http.StatusUnprocessableEntity, fmt.Sprintf("Invalid expression: %v", err)
+	}
+	schedule.NextRunAt = database.ScheduleTime(next)
+	return http.StatusOK, ""
+}
+
+// Schedules lists the schedules of the user (GET) or creates one (POST)
+func Schedules(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIdFromToken(w, r, w.Header().Get("Authorization"))
+	if err != nil {
+		http.Error(w, "Failed to get userId from token", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ScheduleRequest
+	schedule := database.Schedule{UserId: userID, Enabled: true}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if code, message := req.apply(&schedule, time.Now()); message != "" {
+			http.Error(w, message, code)
+			return
+		}
+	}
+
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		http.Error(w, "Failed to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodGet {
+		schedules, err := database.GetSchedulesByUserId(db, userID)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Failed to get schedules", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"schedules": schedules})
+		return
+	}
+
+	schedule.Id, err = database.InsertSchedule(db, schedule)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Failed to save schedule", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("User %d created schedule %d ⏰", userID, schedule.Id)
+	saved, err := database.GetSchedule(db, userID, schedule.Id)
+	if err != nil {
+		http.Error(w, "Failed to get schedule", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// ScheduleById shows (GET), changes (PUT) or removes (DELETE) a schedule of the user
+func ScheduleById(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIdFromToken(w, r, w.Header().Get("Authorization"))
+	if err != nil {
+		http.Error(w, "Failed to get userId from token", http.StatusUnauthorized)
+		return
+	}
+	scheduleID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/v1/schedules/"))
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		http.Error(w, "Failed to connect to database", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	schedule, err := database.GetSchedule(db, userID, scheduleID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Failed to get schedule", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		if _, err := database.DeleteSchedule(db, userID, scheduleID); err != nil {
+			log.Println(err)
+			http.Error(w, "Failed to delete schedule", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodPut:
+		var req ScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if code, message := req.apply(schedule, time.Now()); message != "" {
+			http.Error(w, message, code)
+			return
+		}
+		if _, err := database.UpdateSchedule(db, *schedule); err != nil {
+			log.Println(err)
+			http.Error(w, "Failed to save schedule", http.StatusInternalServerError)
+			return
+		}
+		if schedule, err = database.GetSchedule(db, userID, scheduleID); err != nil {
+			http.Error(w, "Failed to get schedule", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
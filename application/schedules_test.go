@@ -0,0 +1,95 @@
+package application
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	database "github.com/ArteShow/Calculator/pkg/Database"
+	MyJWT "github.com/ArteShow/Calculator/pkg/JWT"
+)
+
+func scheduleRequest(t *testing.T, userID int, method string, path string, payload string) *httptest.ResponseRecorder {
+	token, err := MyJWT.CreateJWT(userID, "user", MyJWT.GetJWTKey())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	req := httptest.NewRequest(method, path, strings.NewReader(payload))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	if path == "/api/v1/schedules" {
+		Schedules(w, req)
+	} else {
+		ScheduleById(w, req)
+	}
+	return w
+}
+
+func TestSchedules(t *testing.T) {
+	w := scheduleRequest(t, 5, http.MethodPost, "/api/v1/schedules",
+		`{"expression": "now()/3600-$hours", "variables": {"hours": 480000}, "cron": "0 * * * *"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created database.Schedule
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.Id == 0 || !created.Enabled || created.NextRunAt == "" || created.Variables["hours"] != 480000 {
+		t.Fatalf("unexpected schedule: %+v", created)
+	}
+	path := "/api/v1/schedules/" + strconv.Itoa(created.Id)
+
+	// Only the sent fields change
+	w = scheduleRequest(t, 5, http.MethodPut, path, `{"enabled": false, "cron": "@daily"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var updated database.Schedule
+	json.NewDecoder(w.Body).Decode(&updated)
+	if updated.Enabled || updated.Cron != "@daily" || updated.Expression != created.Expression || !strings.HasSuffix(updated.NextRunAt, "T00:00:00Z") {
+		t.Fatalf("unexpected schedule: %+v", updated)
+	}
+
+	w = scheduleRequest(t, 5, http.MethodGet, "/api/v1/schedules", "")
+	var list struct {
+		Schedules []database.Schedule `json:"schedules"`
+	}
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list.Schedules) != 1 || list.Schedules[0].Id != created.Id {
+		t.Fatalf("unexpected schedules: %+v", list.Schedules)
+	}
+
+	// Other users do not see it
+	if w := scheduleRequest(t, 6, http.MethodGet, path, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+
+	if w := scheduleRequest(t, 5, http.MethodDelete, path, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if w := scheduleRequest(t, 5, http.MethodGet, path, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestSchedules_Invalid(t *testing.T) {
+	cases := []struct {
+		payload string
+		status  int
+	}{
+		{`{"expression": "1+1"}`, http.StatusBadRequest},
+		{`{"expression": "1+1", "cron": "* * *"}`, http.StatusBadRequest},
+		{`{"expression": "1+1", "cron": "0 0 30 2 *"}`, http.StatusBadRequest},
+		{`{"expression": "1+1", "cron": "@hourly", "callbackUrl": "ftp://example.com"}`, http.StatusBadRequest},
+		{`{"expression": "$x+1", "cron": "@hourly"}`, http.StatusUnprocessableEntity},
+		{`{"expression": "1+", "cron": "@hourly"}`, http.StatusUnprocessableEntity},
+	}
+	for _, c := range cases {
+		w := scheduleRequest(t, 5, http.MethodPost, "/api/v1/schedules", c.payload)
+		if w.Code != c.status {
+			t.Errorf("%s: expected %d, got %d", c.payload, c.status, w.Code)
+		}
+	}
+}
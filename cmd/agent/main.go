@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+
+	agent "github.com/ArteShow/Calculator/pkg/Agent"
+	certs "github.com/ArteShow/Calculator/pkg/Certs"
+	config "github.com/ArteShow/Calculator/pkg/Config"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	log.Println("Starting agent...")
+	calculatorConfig, err := config.LoadCalculatorConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	credentials, err := certs.DialOption(calculatorConfig)
+	if err != nil {
+		log.Fatalf("Failed to set up TLS: %v", err)
+	}
+
+	options := []grpc.DialOption{credentials}
+	if calculatorConfig.AgentToken != "" {
+		options = append(options, agent.WithToken(calculatorConfig.AgentToken))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := agent.Run(ctx, calculatorConfig.OrchestratorAddress, calculatorConfig.ComputingPower, calculatorConfig.OperationTimes(), options...); err != nil {
+		log.Fatalf("Agent stopped: %v", err)
+	}
+}
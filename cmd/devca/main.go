@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	certs "github.com/ArteShow/Calculator/pkg/Certs"
+)
+
+// Writes a dev CA with server and client certificates for trying out TLS locally
+func main() {
+	dir := flag.String("dir", "certs", "directory for the PEM files")
+	flag.Parse()
+
+	ca, err := certs.GenerateDevCA(*dir)
+	if err != nil {
+		log.Fatalf("Failed to generate the dev CA: %v", err)
+	}
+	log.Printf("🔐 Dev CA written to %s", *dir)
+	log.Printf("Server: GRPC_TLS_CERT=%s GRPC_TLS_KEY=%s GRPC_CLIENT_CA=%s", ca.ServerCert, ca.ServerKey, ca.CA)
+	log.Printf("Clients: GRPC_CA=%s GRPC_CLIENT_CERT=%s GRPC_CLIENT_KEY=%s", ca.CA, ca.ClientCert, ca.ClientKey)
+}
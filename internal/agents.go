@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	user "github.com/ArteShow/Calculator/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// States of a registered agent
+const (
+	AgentAlive = "alive"
+	AgentDead  = "dead"
+)
+
+// A dead agent is forgotten when it has not been seen for this long
+const agentForgetAfter = time.Hour
+
+// ErrUnknownAgent is returned for a heartbeat of an agent that did not register
+var ErrUnknownAgent = errors.New("unknown agent, register first")
+
+type agentState struct {
+	id           string
+	capacity     int
+	registeredAt time.Time
+	lastSeen     time.Time
+	completed    int64
+}
+
+// AgentInfo is what the orchestrator knows about a registered agent
+type AgentInfo struct {
+	Id             string
+	Status         string
+	Capacity       int
+	RunningTasks   int
+	CompletedTasks int64
+	RegisteredAt   time.Time
+	LastSeenAt     time.Time
+}
+
+// RegisterAgent starts tracking the agent and returns how often it has to send heartbeats.
+// An agent that registers again, e.g. after a restart, starts over
+func (o *Orchestrator) RegisterAgent(agentId string, capacity int, now time.Time) time.Duration {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.agents[agentId] = &agentState{id: agentId, capacity: capacity, registeredAt: now, lastSeen: now}
+	return o.heartbeatInterval
+}
+
+// Heartbeat marks the agent as alive. It returns the tasks the agent is computing
+// whose result is not needed any more, because the calculation ended or the task
+// was handed to another agent
+func (o *Orchestrator) Heartbeat(agentId string, taskIds []int64, now time.Time) ([]int64, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.agents[agentId]; !ok {
+		return nil, ErrUnknownAgent
+	}
+	o.seen(agentId, now)
+
+	var cancelled []int64
+	for _, id := range taskIds {
+		task, ok := o.running[id]
+		if ok && task.AgentId == agentId {
+			if _, ok := o.runs[task.ExpressionId]; ok {
+				continue
+			}
+		}
+		cancelled = append(cancelled, id)
+	}
+	return cancelled, nil
+}
+
+// seen updates when a registered agent was last heard of. The caller holds o.mu
+func (o *Orchestrator) seen(agentId string, now time.Time) {
+	if agent, ok := o.agents[agentId]; ok {
+		agent.lastSeen = now
+	}
+}
+
+// agentDead tells if a registered agent missed its heartbeats. Agents that never
+// registered only lose their tasks when the lease runs out. The caller holds o.mu
+func (o *Orchestrator) agentDead(agentId string, now time.Time) bool {
+	agent, ok := o.agents[agentId]
+	return ok && now.Sub(agent.lastSeen) > o.agentTimeout
+}
+
+// Agents returns the registered agents ordered by id
+func (o *Orchestrator) Agents(now time.Time) []AgentInfo {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	running := map[string]int{}
+	for _, task := range o.running {
+		running[task.AgentId]++
+	}
+	agents := make([]AgentInfo, 0, len(o.agents))
+	for id, agent := range o.agents {
+		if now.Sub(agent.lastSeen) > agentForgetAfter {
+			delete(o.agents, id)
+			continue
+		}
+		info := AgentInfo{
+			Id:             agent.id,
+			Status:         AgentAlive,
+			Capacity:       agent.capacity,
+			RunningTasks:   running[id],
+			CompletedTasks: agent.completed,
+			RegisteredAt:   agent.registeredAt,
+			LastSeenAt:     agent.lastSeen,
+		}
+		if o.agentDead(id, now) {
+			info.Status = AgentDead
+		}
+		agents = append(agents, info)
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Id < agents[j].Id })
+	return agents
+}
+
+func (s *AgentServer) RegisterAgent(ctx context.Context, req *user.RegisterAgentRequest) (*user.RegisterAgentResponse, error) {
+	if req.AgentId == "" || req.Capacity < 1 {
+		return nil, status.Error(codes.InvalidArgument, "agentId is required and capacity must be at least 1")
+	}
+	interval := s.orchestrator.RegisterAgent(req.AgentId, int(req.Capacity), time.Now())
+	log.Printf("🤖 Agent %s registered with capacity %d", req.AgentId, req.Capacity)
+	return &user.RegisterAgentResponse{HeartbeatIntervalMs: int32(interval.Milliseconds())}, nil
+}
+
+func (s *AgentServer) Heartbeat(ctx context.Context, req *user.HeartbeatRequest) (*user.HeartbeatResponse, error) {
+	cancelled, err := s.orchestrator.Heartbeat(req.AgentId, req.TaskIds, time.Now())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return &user.HeartbeatResponse{CancelledTaskIds: cancelled}, nil
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	calculate "github.com/ArteShow/Calculator/pkg/Calculation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadAgentTasksAreReassigned(t *testing.T) {
+	o := NewOrchestrator()
+	o.agentTimeout = 20 * time.Millisecond
+	o.RegisterAgent("test", 1, time.Now())
+	node, err := calculate.Parse("1+2")
+	assert.NoError(t, err)
+
+	done := make(chan float64)
+	go func() {
+		result, err := o.Evaluate(context.Background(), 1, node)
+		assert.NoError(t, err)
+		done <- result
+	}()
+
+	// The lease is long, but the agent stops sending heartbeats
+	first := nextTask(t, o)
+	time.Sleep(30 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	second, ok := o.NextTask(ctx, "other")
+	assert.True(t, ok)
+	assert.Equal(t, first.Id, second.Id)
+	assert.Equal(t, "other", second.AgentId)
+
+	// The dead agent can not complete the task it lost
+	assert.ErrorIs(t, o.Complete(first.Id, "test", 3, ""), ErrNotLeaseHolder)
+	assert.NoError(t, o.Complete(second.Id, "other", 3, ""))
+	assert.Equal(t, 3.0, <-done)
+
+	agents := o.Agents(time.Now())
+	assert.Len(t, agents, 1)
+	assert.Equal(t, AgentDead, agents[0].Status)
+
+	// The dead agent is told to drop the task it still works on
+	cancelled, err := o.Heartbeat("test", []int64{first.Id}, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{first.Id}, cancelled)
+	assert.Equal(t, AgentAlive, o.Agents(time.Now())[0].Status)
+}
+
+func TestHeartbeat(t *testing.T) {
+	o := NewOrchestrator()
+	_, err := o.Heartbeat("test", nil, time.Now())
+	assert.ErrorIs(t, err, ErrUnknownAgent)
+
+	assert.Equal(t, 5*time.Second, o.RegisterAgent("test", 4, time.Now()))
+	node, err := calculate.Parse("1+2")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := o.Evaluate(ctx, 1, node)
+		done <- err
+	}()
+	task := nextTask(t, o)
+
+	cancelled, err := o.Heartbeat("test", []int64{task.Id}, time.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, cancelled)
+	agents := o.Agents(time.Now())
+	assert.Equal(t, AgentInfo{
+		Id:           "test",
+		Status:       AgentAlive,
+		Capacity:     4,
+		RunningTasks: 1,
+		RegisteredAt: agents[0].RegisteredAt,
+		LastSeenAt:   agents[0].LastSeenAt,
+	}, agents[0])
+
+	// Once the calculation is cancelled its task is not needed any more
+	cancel()
+	<-done
+	cancelled, err = o.Heartbeat("test", []int64{task.Id}, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{task.Id}, cancelled)
+
+	// Agents that were dead for long are forgotten
+	assert.Empty(t, o.Agents(time.Now().Add(2*agentForgetAfter)))
+}
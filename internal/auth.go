@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	agent "github.com/ArteShow/Calculator/pkg/Agent"
+	MyJWT "github.com/ArteShow/Calculator/pkg/JWT"
+	user "github.com/ArteShow/Calculator/proto"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Calls of agents carry no user token, they do not act for a user. They are let in
+// with the agent token or a client certificate instead
+var agentServicePrefix = "/" + user.AgentService_ServiceDesc.ServiceName + "/"
+
+// Calls only admins may make
+var adminMethods = map[string]bool{
+	user.UserService_GetAgents_FullMethodName:         true,
+	user.UserService_GetDeadLetters_FullMethodName:    true,
+	user.UserService_RequeueDeadLetter_FullMethodName: true,
+}
+
+type userIdKey struct{}
+
+// UserIdFromContext returns the user whose token came with the call
+func UserIdFromContext(ctx context.Context) (int, bool) {
+	userId, ok := ctx.Value(userIdKey{}).(int)
+	return userId, ok
+}
+
+// Auth checks the bearer JWT in the "authorization" metadata of every call except
+// those of agents, and puts the user id of the token into the context of the call.
+// A userId field in the request is filled in from the token if it is empty and
+// rejected if it names another user. Agents must send the agent token or connect
+// with a client certificate the server verified
+type Auth struct {
+	// Key signs the tokens, the same as the HTTP server's
+	Key []byte
+	// IsAdmin tells if a user may make the admin calls
+	IsAdmin func(userId int) bool
+	// AgentToken lets agents in, empty means only client certificates do
+	AgentToken string
+}
+
+func (a *Auth) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if strings.HasPrefix(method, agentServicePrefix) {
+		if !a.isAgent(ctx) {
+			return nil, status.Error(codes.Unauthenticated, "❌ Agents must send the agent token or a client certificate")
+		}
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "❌ Missing bearer token")
+	}
+	tokenString, found := strings.CutPrefix(values[0], "Bearer ")
+	if !found {
+		return nil, status.Error(codes.Unauthenticated, "❌ Invalid authorization format, use Bearer <token>")
+	}
+	token, err := MyJWT.ParseJWT(tokenString, a.Key)
+	if err != nil || !token.Valid {
+		return nil, status.Error(codes.Unauthenticated, "❌ Invalid token")
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "❌ Invalid claims")
+	}
+	userIdFloat, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "❌ user_id not found in token")
+	}
+	userId := int(userIdFloat)
+
+	if adminMethods[method] && (a.IsAdmin == nil || !a.IsAdmin(userId)) {
+		return nil, status.Error(codes.PermissionDenied, "❌ Only admins may do this")
+	}
+	return context.WithValue(ctx, userIdKey{}, userId), nil
+}
+
+// isAgent tells if the call comes with the agent token or over mutual TLS
+func (a *Auth) isAgent(ctx context.Context) bool {
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+			return true
+		}
+	}
+	if a.AgentToken == "" {
+		return false
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, token := range md.Get(agent.TokenMetadataKey) {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(a.AgentToken)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkUserId makes the userId field of the request, if it has one, the user of the context
+func checkUserId(ctx context.Context, req any) error {
+	userId, ok := UserIdFromContext(ctx)
+	message, isMessage := req.(proto.Message)
+	if !ok || !isMessage {
+		return nil
+	}
+	m := message.ProtoReflect()
+	field := m.Descriptor().Fields().ByName("userId")
+	if field == nil || field.Kind() != protoreflect.Int32Kind {
+		return nil
+	}
+	sent := int(m.Get(field).Int())
+	if sent == 0 {
+		m.Set(field, protoreflect.ValueOfInt32(int32(userId)))
+		return nil
+	}
+	if sent != userId {
+		return status.Errorf(codes.PermissionDenied, "❌ The token is for user %d, not for user %d", userId, sent)
+	}
+	return nil
+}
+
+// UnaryInterceptor authenticates unary calls
+func (a *Auth) UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUserId(ctx, req); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamInterceptor authenticates streaming calls, the requests are checked as they are received
+func (a *Auth) StreamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authenticatedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return checkUserId(s.ctx, m)
+}
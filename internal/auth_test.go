@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+
+	agent "github.com/ArteShow/Calculator/pkg/Agent"
+	MyJWT "github.com/ArteShow/Calculator/pkg/JWT"
+	proto "github.com/ArteShow/Calculator/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+func withBearer(t *testing.T, userId int, key string) context.Context {
+	token, err := MyJWT.CreateJWT(userId, "user", key)
+	assert.NoError(t, err)
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func TestAuth_UnaryInterceptor(t *testing.T) {
+	auth := &Auth{Key: []byte("secret"), IsAdmin: func(userId int) bool { return userId == 1 }}
+	var seen *proto.UserIdRequest
+	handler := func(ctx context.Context, req any) (any, error) {
+		seen = req.(*proto.UserIdRequest)
+		userId, ok := UserIdFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, int(seen.UserId), userId)
+		return nil, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: proto.UserService_GetUserCalculations_FullMethodName}
+
+	// The userId is taken from the token when it is not sent
+	_, err := auth.UnaryInterceptor(withBearer(t, 7, "secret"), &proto.UserIdRequest{}, info, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(7), seen.UserId)
+	_, err = auth.UnaryInterceptor(withBearer(t, 7, "secret"), &proto.UserIdRequest{UserId: 7}, info, handler)
+	assert.NoError(t, err)
+
+	cases := []struct {
+		ctx  context.Context
+		req  *proto.UserIdRequest
+		code codes.Code
+	}{
+		{context.Background(), &proto.UserIdRequest{UserId: 7}, codes.Unauthenticated},
+		{metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Basic abc")), &proto.UserIdRequest{}, codes.Unauthenticated},
+		{withBearer(t, 7, "other"), &proto.UserIdRequest{UserId: 7}, codes.Unauthenticated},
+		{withBearer(t, 7, "secret"), &proto.UserIdRequest{UserId: 8}, codes.PermissionDenied},
+	}
+	for _, c := range cases {
+		seen = nil
+		_, err := auth.UnaryInterceptor(c.ctx, c.req, info, handler)
+		assert.Equal(t, c.code, grpcstatus.Code(err))
+		assert.Nil(t, seen)
+	}
+}
+
+func TestAuth_AdminAndAgentCalls(t *testing.T) {
+	auth := &Auth{Key: []byte("secret"), IsAdmin: func(userId int) bool { return userId == 1 }}
+	handler := func(ctx context.Context, req any) (any, error) { return nil, nil }
+
+	admin := &grpc.UnaryServerInfo{FullMethod: proto.UserService_GetAgents_FullMethodName}
+	_, err := auth.UnaryInterceptor(withBearer(t, 2, "secret"), &proto.AgentsRequest{}, admin, handler)
+	assert.Equal(t, codes.PermissionDenied, grpcstatus.Code(err))
+	_, err = auth.UnaryInterceptor(withBearer(t, 1, "secret"), &proto.AgentsRequest{}, admin, handler)
+	assert.NoError(t, err)
+
+	// Agents send the agent token instead of a user token
+	agentCall := &grpc.UnaryServerInfo{FullMethod: proto.AgentService_GetTask_FullMethodName}
+	_, err = auth.UnaryInterceptor(context.Background(), &proto.GetTaskRequest{}, agentCall, handler)
+	assert.Equal(t, codes.Unauthenticated, grpcstatus.Code(err))
+	auth.AgentToken = "agent-secret"
+	for _, token := range []string{"", "wrong"} {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(agent.TokenMetadataKey, token))
+		_, err = auth.UnaryInterceptor(ctx, &proto.GetTaskRequest{}, agentCall, handler)
+		assert.Equal(t, codes.Unauthenticated, grpcstatus.Code(err))
+	}
+	// A user token is no agent token
+	_, err = auth.UnaryInterceptor(withBearer(t, 1, "secret"), &proto.GetTaskRequest{}, agentCall, handler)
+	assert.Equal(t, codes.Unauthenticated, grpcstatus.Code(err))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(agent.TokenMetadataKey, "agent-secret"))
+	_, err = auth.UnaryInterceptor(ctx, &proto.GetTaskRequest{}, agentCall, handler)
+	assert.NoError(t, err)
+
+	// Or connect with a verified client certificate
+	auth.AgentToken = ""
+	verified := credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}}
+	ctx = peer.NewContext(context.Background(), &peer.Peer{AuthInfo: verified})
+	_, err = auth.UnaryInterceptor(ctx, &proto.GetTaskRequest{}, agentCall, handler)
+	assert.NoError(t, err)
+	ctx = peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{}})
+	_, err = auth.UnaryInterceptor(ctx, &proto.GetTaskRequest{}, agentCall, handler)
+	assert.Equal(t, codes.Unauthenticated, grpcstatus.Code(err))
+}
+
+// fakeServerStream hands out one request
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	req *proto.WatchCalculationRequest
+}
+
+func (s *fakeServerStream) Context() context.Context { return s.ctx }
+
+func (s *fakeServerStream) RecvMsg(m any) error {
+	m.(*proto.WatchCalculationRequest).UserId = s.req.UserId
+	m.(*proto.WatchCalculationRequest).Id = s.req.Id
+	return nil
+}
+
+func TestAuth_StreamInterceptor(t *testing.T) {
+	auth := &Auth{Key: []byte("secret")}
+	info := &grpc.StreamServerInfo{FullMethod: proto.UserService_WatchCalculation_FullMethodName, IsServerStream: true}
+	handler := func(srv any, stream grpc.ServerStream) error {
+		userId, ok := UserIdFromContext(stream.Context())
+		assert.True(t, ok)
+		assert.Equal(t, 7, userId)
+		return stream.RecvMsg(&proto.WatchCalculationRequest{})
+	}
+
+	stream := &fakeServerStream{ctx: withBearer(t, 7, "secret"), req: &proto.WatchCalculationRequest{UserId: 7, Id: 1}}
+	assert.NoError(t, auth.StreamInterceptor(nil, stream, info, handler))
+	stream.req.UserId = 8
+	assert.Equal(t, codes.PermissionDenied, grpcstatus.Code(auth.StreamInterceptor(nil, stream, info, handler)))
+	stream.ctx = context.Background()
+	assert.Equal(t, codes.Unauthenticated, grpcstatus.Code(auth.StreamInterceptor(nil, stream, info, handler)))
+}
@@ -0,0 +1,186 @@
+package internal
+
+import (
+	"container/list"
+	"database/sql"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	calculate "github.com/ArteShow/Calculator/pkg/Calculation"
+	config "github.com/ArteShow/Calculator/pkg/Config"
+	database "github.com/ArteShow/Calculator/pkg/Database"
+)
+
+// evaluationOptions is part of every cache key. Results only depend on the tree and
+// on computing with float64, a change here keeps old persisted results from being used
+const evaluationOptions = "float64"
+
+// CacheKey is the same for expressions that are computed the same way: the operands
+// of + and * are ordered, since swapping them does not change a float64 result
+func CacheKey(node *calculate.Node) string {
+	return canonical(node) + "|" + evaluationOptions
+}
+
+func canonical(node *calculate.Node) string {
+	if node.Op == calculate.OpNumber {
+		return node.String()
+	}
+	if len(node.Args) == 1 {
+		return "(" + node.Op + canonical(node.Args[0]) + ")"
+	}
+	operands := []string{canonical(node.Args[0]), canonical(node.Args[1])}
+	if node.Op == "+" || node.Op == "*" {
+		sort.Strings(operands)
+	}
+	return "(" + operands[0] + node.Op + operands[1] + ")"
+}
+
+// ResultCache keeps the results of the size most recently used expressions.
+// With a database new results are written through, so the cache survives a restart.
+// Hits only change the order in memory, it is written with the next Put so a
+// lookup never waits for the database
+type ResultCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+	// Keys used since the order was last written to the database
+	touched map[string]bool
+	db      *sql.DB
+	hits    atomic.Int64
+	misses  atomic.Int64
+}
+
+type cacheEntry struct {
+	key    string
+	result float64
+}
+
+// CacheMetrics counts the lookups of the cache
+type CacheMetrics struct {
+	Hits    int64
+	Misses  int64
+	Entries int
+}
+
+func NewResultCache(size int) *ResultCache {
+	return &ResultCache{size: size, order: list.New(), entries: map[string]*list.Element{}, touched: map[string]bool{}}
+}
+
+// Persist loads the results saved in the database and keeps saving them there
+func (c *ResultCache) Persist(db *sql.DB) error {
+	saved, err := database.GetCachedResults(db, c.size)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.db = db
+	// The most recently used is loaded last, so it ends up in front
+	for i := len(saved) - 1; i >= 0; i-- {
+		c.add(saved[i].Key, saved[i].Result)
+	}
+	return nil
+}
+
+// Get returns the result of the expression if it was computed before
+func (c *ResultCache) Get(key string) (float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		c.misses.Add(1)
+		return 0, false
+	}
+	c.hits.Add(1)
+	c.order.MoveToFront(element)
+	entry := element.Value.(*cacheEntry)
+	if c.db != nil {
+		c.touched[key] = true
+	}
+	return entry.result, true
+}
+
+// Put saves a result, the least recently used one is dropped when the cache is full
+func (c *ResultCache) Put(key string, result float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.add(key, result)
+	c.saveTouched(key)
+	c.save(key, result)
+}
+
+// add puts the result in front, the caller holds c.mu
+func (c *ResultCache) add(key string, result float64) {
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*cacheEntry).result = result
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, result: result})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		key := oldest.Value.(*cacheEntry).key
+		delete(c.entries, key)
+		delete(c.touched, key)
+		if c.db != nil {
+			if err := database.DeleteCachedResult(c.db, key); err != nil {
+				log.Printf("❌ Failed to drop cached result: %v", err)
+			}
+		}
+	}
+}
+
+// saveTouched writes the entries used since the last Put except key, least recently
+// used first so they are loaded in the same order. The caller holds c.mu
+func (c *ResultCache) saveTouched(key string) {
+	if len(c.touched) == 0 {
+		return
+	}
+	for element := c.order.Back(); element != nil; element = element.Prev() {
+		entry := element.Value.(*cacheEntry)
+		if c.touched[entry.key] && entry.key != key {
+			c.save(entry.key, entry.result)
+		}
+	}
+	c.touched = map[string]bool{}
+}
+
+// save writes the result to the database if there is one, the caller holds c.mu
+func (c *ResultCache) save(key string, result float64) {
+	if c.db == nil {
+		return
+	}
+	if err := database.SaveCachedResult(c.db, key, result); err != nil {
+		log.Printf("❌ Failed to save cached result: %v", err)
+	}
+}
+
+func (c *ResultCache) Metrics() CacheMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheMetrics{Hits: c.hits.Load(), Misses: c.misses.Load(), Entries: c.order.Len()}
+}
+
+var (
+	cache     *ResultCache
+	cacheOnce sync.Once
+)
+
+// resultCache is nil when cacheEnabled is off in the calculator config
+func resultCache() *ResultCache {
+	cacheOnce.Do(func() {
+		calculatorConfig, err := config.LoadCalculatorConfig()
+		if err != nil {
+			log.Printf("❌ Failed to load calculator config, using the defaults: %v", err)
+			calculatorConfig = config.DefaultCalculatorConfig()
+		}
+		if calculatorConfig.CacheEnabled {
+			cache = NewResultCache(calculatorConfig.CacheSize)
+		}
+	})
+	return cache
+}
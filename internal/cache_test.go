@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	calculate "github.com/ArteShow/Calculator/pkg/Calculation"
+	database "github.com/ArteShow/Calculator/pkg/Database"
+	proto "github.com/ArteShow/Calculator/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func cacheKeyOf(t *testing.T, expression string) string {
+	node, err := calculate.Parse(expression)
+	assert.NoError(t, err)
+	return CacheKey(node)
+}
+
+func TestCacheKey(t *testing.T) {
+	assert.Equal(t, cacheKeyOf(t, "1+2*3"), cacheKeyOf(t, "3*2+1"))
+	assert.Equal(t, cacheKeyOf(t, "(1+2)"), cacheKeyOf(t, "1+2"))
+	assert.NotEqual(t, cacheKeyOf(t, "1-2"), cacheKeyOf(t, "2-1"))
+	assert.NotEqual(t, cacheKeyOf(t, "1/2"), cacheKeyOf(t, "2/1"))
+	// Only direct operands are swapped, regrouping can change a float64 result
+	assert.NotEqual(t, cacheKeyOf(t, "(1+2)+3"), cacheKeyOf(t, "1+(2+3)"))
+}
+
+func TestResultCache(t *testing.T) {
+	c := NewResultCache(2)
+	c.Put("a", 1)
+	c.Put("b", 2)
+
+	value, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, value)
+
+	// b is the least recently used now and makes room for c
+	c.Put("c", 3)
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	_, ok = c.Get("c")
+	assert.True(t, ok)
+
+	assert.Equal(t, CacheMetrics{Hits: 2, Misses: 1, Entries: 2}, c.Metrics())
+}
+
+func TestResultCache_Persist(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	c := NewResultCache(2)
+	assert.NoError(t, c.Persist(db))
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("c", 3)
+
+	// A new cache starts with what the old one had
+	restarted := NewResultCache(2)
+	assert.NoError(t, restarted.Persist(db))
+	assert.Equal(t, 2, restarted.Metrics().Entries)
+	_, ok := restarted.Get("a")
+	assert.False(t, ok)
+	value, ok := restarted.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 3.0, value)
+}
+
+func TestResultCache_PersistHits(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	c := NewResultCache(3)
+	assert.NoError(t, c.Persist(db))
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("c", 3)
+
+	// A hit does not write to the database
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+	saved, err := database.GetCachedResults(db, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", saved[2].Key)
+
+	// The next Put writes the new order, b is the least recently used
+	c.Put("d", 4)
+	restarted := NewResultCache(3)
+	assert.NoError(t, restarted.Persist(db))
+	_, ok = restarted.Get("b")
+	assert.False(t, ok)
+	_, ok = restarted.Get("a")
+	assert.True(t, ok)
+	saved, err = database.GetCachedResults(db, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"d", "a", "c"}, []string{saved[0].Key, saved[1].Key, saved[2].Key})
+}
+
+func TestSendUserData_Cached(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	os.Setenv("DB_PATH", testDBPath)
+
+	server := &Server{}
+	req := &proto.UserDataRequest{UserId: 8, Calculation: &proto.Calculation{Expression: "17*3+4"}}
+	resp, err := server.SendUserData(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", resp.Status)
+	_, result, _ := waitForCalculation(t, db, int(resp.Id))
+	assert.Equal(t, 55.0, result)
+
+	// The same expression written differently is not computed again
+	req.Calculation.Expression = "4+3*17"
+	resp, err = server.SendUserData(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "done", resp.Status)
+	assert.Equal(t, 55.0, resp.Result)
+
+	var status string
+	assert.NoError(t, db.QueryRow(`SELECT status, result FROM calculations WHERE id = ?`, resp.Id).Scan(&status, &result))
+	assert.Equal(t, "done", status)
+	assert.Equal(t, 55.0, result)
+}
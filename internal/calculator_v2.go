@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strconv"
+	"time"
+
+	config "github.com/ArteShow/Calculator/pkg/Config"
+	database "github.com/ArteShow/Calculator/pkg/Database"
+	user "github.com/ArteShow/Calculator/proto"
+	calculatorv2 "github.com/ArteShow/Calculator/proto/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Page sizes of ListExpressions
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+var expressionStatuses = map[string]calculatorv2.ExpressionStatus{
+	database.StatusPending:   calculatorv2.ExpressionStatus_EXPRESSION_STATUS_PENDING,
+	database.StatusRunning:   calculatorv2.ExpressionStatus_EXPRESSION_STATUS_RUNNING,
+	database.StatusDone:      calculatorv2.ExpressionStatus_EXPRESSION_STATUS_DONE,
+	database.StatusFailed:    calculatorv2.ExpressionStatus_EXPRESSION_STATUS_FAILED,
+	database.StatusCancelled: calculatorv2.ExpressionStatus_EXPRESSION_STATUS_CANCELLED,
+}
+
+// CalculatorServer is the v2 API, every RPC does one thing and answers with
+// structured messages instead of the messages of UserService
+type CalculatorServer struct {
+	calculatorv2.UnimplementedCalculatorServiceServer
+}
+
+// timestamp reads the times saved in the calculations table, nil if there is none
+func timestamp(value string) *timestamppb.Timestamp {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func expressionMessage(calculation *database.Calculation) *calculatorv2.Expression {
+	return &calculatorv2.Expression{
+		Id:          int32(calculation.Id),
+		Expression:  calculation.Expression,
+		Status:      expressionStatuses[calculation.Status],
+		Result:      calculation.Result,
+		Error:       calculation.Error,
+		CreatedAt:   timestamp(calculation.CreatedAt),
+		UpdatedAt:   timestamp(calculation.UpdatedAt),
+		CallbackUrl: calculation.CallbackUrl,
+	}
+}
+
+// getExpression loads the calculation of the user as it is now
+func getExpression(userId int, id int) (*calculatorv2.Expression, error) {
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		return nil, storageError("open database", err)
+	}
+	defer db.Close()
+
+	calculation, err := database.GetCalculation(db, userId, id)
+	if err == sql.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "expression %d not found", id)
+	}
+	if err != nil {
+		return nil, storageError("get expression", err)
+	}
+	return expressionMessage(calculation), nil
+}
+
+func (s *CalculatorServer) SubmitExpression(ctx context.Context, req *calculatorv2.SubmitExpressionRequest) (*calculatorv2.Expression, error) {
+	if req.Expression == "" {
+		return nil, status.Error(codes.InvalidArgument, "expression is required")
+	}
+	userId := int(req.UserId)
+	expression, node, err := parseCalculation(&user.Calculation{
+		Expression:  req.Expression,
+		Notation:    req.Notation,
+		Locale:      req.Locale,
+		CallbackUrl: req.CallbackUrl,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var id int
+	if cache := resultCache(); cache != nil {
+		if result, ok := cache.Get(CacheKey(node)); ok {
+			id, err = saveCachedCalculation(userId, expression, req.CallbackUrl, result)
+		}
+	}
+	if id == 0 && err == nil {
+		id, err = submitCalculation(userId, expression, node, req.CallbackUrl, PriorityInteractive)
+	}
+	if err != nil {
+		return nil, submitError(err)
+	}
+	return getExpression(userId, id)
+}
+
+func (s *CalculatorServer) GetExpression(ctx context.Context, req *calculatorv2.GetExpressionRequest) (*calculatorv2.Expression, error) {
+	return getExpression(int(req.UserId), int(req.Id))
+}
+
+// ListExpressions pages by id, the page token is the id of the last expression of the page
+func (s *CalculatorServer) ListExpressions(ctx context.Context, req *calculatorv2.ListExpressionsRequest) (*calculatorv2.ListExpressionsResponse, error) {
+	pageSize := int(req.PageSize)
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize < 0 || pageSize > maxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "pageSize must be between 1 and %d", maxPageSize)
+	}
+	afterId := 0
+	if req.PageToken != "" {
+		var err error
+		if afterId, err = strconv.Atoi(req.PageToken); err != nil || afterId < 0 {
+			return nil, status.Error(codes.InvalidArgument, "invalid pageToken")
+		}
+	}
+	statusFilter := ""
+	if req.Status != calculatorv2.ExpressionStatus_EXPRESSION_STATUS_UNSPECIFIED {
+		for name, value := range expressionStatuses {
+			if value == req.Status {
+				statusFilter = name
+			}
+		}
+		if statusFilter == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown status %v", req.Status)
+		}
+	}
+
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		return nil, storageError("open database", err)
+	}
+	defer db.Close()
+
+	// One more than asked for tells if there is a next page
+	calculations, err := database.GetCalculationsPage(db, int(req.UserId), afterId, statusFilter, pageSize+1)
+	if err != nil {
+		return nil, storageError("list expressions", err)
+	}
+	response := &calculatorv2.ListExpressionsResponse{}
+	if len(calculations) > pageSize {
+		calculations = calculations[:pageSize]
+		response.NextPageToken = strconv.Itoa(calculations[pageSize-1].Id)
+	}
+	for i := range calculations {
+		response.Expressions = append(response.Expressions, expressionMessage(&calculations[i]))
+	}
+	return response, nil
+}
+
+func (s *CalculatorServer) DeleteExpression(ctx context.Context, req *calculatorv2.DeleteExpressionRequest) (*calculatorv2.DeleteExpressionResponse, error) {
+	userId, id := int(req.UserId), int(req.Id)
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		return nil, storageError("open database", err)
+	}
+	defer db.Close()
+
+	if _, err := database.GetCalculation(db, userId, id); err == sql.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "expression %d not found", id)
+	} else if err != nil {
+		return nil, storageError("get expression", err)
+	}
+
+	// A calculation that is not finished yet is cancelled first, so its watchers learn about it
+	cancelled, err := database.UpdateCalculationFrom(db, id,
+		[]string{database.StatusPending, database.StatusRunning}, database.StatusCancelled, 0, "Deleted by the user")
+	if err != nil {
+		return nil, storageError("cancel expression", err)
+	}
+	if cancelled {
+		stopEvaluation(id)
+		publishStatus(id, database.StatusCancelled, 0, "Deleted by the user")
+	}
+	deleted, err := database.DeleteCalculation(db, userId, id)
+	if err != nil {
+		return nil, storageError("delete expression", err)
+	}
+	if !deleted {
+		return nil, status.Errorf(codes.NotFound, "expression %d not found", id)
+	}
+	log.Printf("🗑️ User %d deleted expression %d", userId, id)
+	return &calculatorv2.DeleteExpressionResponse{}, nil
+}
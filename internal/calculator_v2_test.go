@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	calculatorv2 "github.com/ArteShow/Calculator/proto/v2"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+func TestCalculatorServer(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	os.Setenv("DB_PATH", testDBPath)
+	server := &CalculatorServer{}
+	ctx := context.Background()
+
+	submitted, err := server.SubmitExpression(ctx, &calculatorv2.SubmitExpressionRequest{UserId: 6, Expression: "11 12 *", Notation: "rpn"})
+	assert.NoError(t, err)
+	assert.Equal(t, "(11*12)", submitted.Expression)
+	assert.NotNil(t, submitted.CreatedAt)
+	waitForCalculation(t, db, int(submitted.Id))
+
+	expression, err := server.GetExpression(ctx, &calculatorv2.GetExpressionRequest{UserId: 6, Id: submitted.Id})
+	assert.NoError(t, err)
+	assert.Equal(t, calculatorv2.ExpressionStatus_EXPRESSION_STATUS_DONE, expression.Status)
+	assert.Equal(t, 132.0, expression.Result)
+
+	_, err = server.SubmitExpression(ctx, &calculatorv2.SubmitExpressionRequest{UserId: 6, Expression: "1+"})
+	assert.Equal(t, codes.InvalidArgument, grpcstatus.Code(err))
+	_, err = server.GetExpression(ctx, &calculatorv2.GetExpressionRequest{UserId: 7, Id: submitted.Id})
+	assert.Equal(t, codes.NotFound, grpcstatus.Code(err))
+
+	// Two more, one of them still waiting
+	_, err = db.Exec(`INSERT INTO calculations (userId, calculation, result, status) VALUES
+		(6, '(13-5)', 8, 'done'), (6, '(2^10)', 0, 'pending')`)
+	assert.NoError(t, err)
+
+	page, err := server.ListExpressions(ctx, &calculatorv2.ListExpressionsRequest{UserId: 6, PageSize: 2})
+	assert.NoError(t, err)
+	assert.Len(t, page.Expressions, 2)
+	assert.NotEmpty(t, page.NextPageToken)
+	page, err = server.ListExpressions(ctx, &calculatorv2.ListExpressionsRequest{UserId: 6, PageSize: 2, PageToken: page.NextPageToken})
+	assert.NoError(t, err)
+	assert.Len(t, page.Expressions, 1)
+	assert.Empty(t, page.NextPageToken)
+	pending := page.Expressions[0]
+	assert.Equal(t, calculatorv2.ExpressionStatus_EXPRESSION_STATUS_PENDING, pending.Status)
+	assert.Nil(t, pending.CreatedAt)
+
+	page, err = server.ListExpressions(ctx, &calculatorv2.ListExpressionsRequest{UserId: 6, Status: calculatorv2.ExpressionStatus_EXPRESSION_STATUS_DONE})
+	assert.NoError(t, err)
+	assert.Len(t, page.Expressions, 2)
+	_, err = server.ListExpressions(ctx, &calculatorv2.ListExpressionsRequest{UserId: 6, PageToken: "next"})
+	assert.Equal(t, codes.InvalidArgument, grpcstatus.Code(err))
+	_, err = server.ListExpressions(ctx, &calculatorv2.ListExpressionsRequest{UserId: 6, PageSize: 5000})
+	assert.Equal(t, codes.InvalidArgument, grpcstatus.Code(err))
+
+	// The waiting one is cancelled before it is deleted
+	_, err = server.DeleteExpression(ctx, &calculatorv2.DeleteExpressionRequest{UserId: 6, Id: pending.Id})
+	assert.NoError(t, err)
+	_, err = server.GetExpression(ctx, &calculatorv2.GetExpressionRequest{UserId: 6, Id: pending.Id})
+	assert.Equal(t, codes.NotFound, grpcstatus.Code(err))
+	_, err = server.DeleteExpression(ctx, &calculatorv2.DeleteExpressionRequest{UserId: 6, Id: pending.Id})
+	assert.Equal(t, codes.NotFound, grpcstatus.Code(err))
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	calculate "github.com/ArteShow/Calculator/pkg/Calculation"
+	config "github.com/ArteShow/Calculator/pkg/Config"
+	database "github.com/ArteShow/Calculator/pkg/Database"
+	user "github.com/ArteShow/Calculator/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func deadLetterMessage(deadLetter *database.DeadLetter) *user.DeadLetter {
+	return &user.DeadLetter{
+		Id:           int32(deadLetter.Id),
+		TaskId:       deadLetter.TaskId,
+		ExpressionId: int32(deadLetter.ExpressionId),
+		UserId:       int32(deadLetter.UserId),
+		Expression:   deadLetter.Expression,
+		Path:         deadLetter.Path,
+		Operation:    deadLetter.Operation,
+		Left:         deadLetter.Left,
+		Right:        deadLetter.Right,
+		Attempts:     int32(deadLetter.Attempts),
+		Error:        deadLetter.Error,
+		CreatedAt:    deadLetter.CreatedAt,
+		RequeuedAt:   deadLetter.RequeuedAt,
+	}
+}
+
+func (s *Server) GetDeadLetters(ctx context.Context, req *user.DeadLettersRequest) (*user.DeadLettersResponse, error) {
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		return nil, storageError("open database", err)
+	}
+	defer db.Close()
+
+	deadLetters, err := database.GetDeadLetters(db, req.PendingOnly)
+	if err != nil {
+		return nil, storageError("get dead letters", err)
+	}
+	response := &user.DeadLettersResponse{}
+	for i := range deadLetters {
+		response.DeadLetters = append(response.DeadLetters, deadLetterMessage(&deadLetters[i]))
+	}
+	return response, nil
+}
+
+// RequeueDeadLetter sets the failed calculation of the dead letter back to pending and
+// queues it again. A dead letter is requeued once, if the calculation fails again
+// a new one is saved
+func (s *Server) RequeueDeadLetter(ctx context.Context, req *user.RequeueDeadLetterRequest) (*user.DeadLetter, error) {
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		return nil, storageError("open database", err)
+	}
+	defer db.Close()
+
+	deadLetter, err := database.GetDeadLetter(db, int(req.Id))
+	if err == sql.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "❌ Dead letter %d not found", req.Id)
+	}
+	if err != nil {
+		return nil, storageError("get dead letter", err)
+	}
+	if deadLetter.RequeuedAt != "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "❌ Dead letter %d was already requeued", req.Id)
+	}
+	calculation, err := database.GetCalculationById(db, deadLetter.ExpressionId)
+	if err == sql.ErrNoRows {
+		return nil, status.Errorf(codes.FailedPrecondition, "❌ Calculation %d does not exist any more", deadLetter.ExpressionId)
+	}
+	if err != nil {
+		return nil, storageError("get calculation", err)
+	}
+	if calculation.Status != database.StatusFailed {
+		return nil, status.Errorf(codes.FailedPrecondition, "❌ Calculation %d is %s, only failed calculations can be requeued", calculation.Id, calculation.Status)
+	}
+	node, err := calculate.Parse(calculation.Expression)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "❌ Calculation %d can not be parsed: %v", calculation.Id, err)
+	}
+
+	pool := calculationPool()
+	if err := pool.Reserve(); err != nil {
+		return nil, submitError(err)
+	}
+	updated, err := updateCalculationFrom(db, calculation.Id, []string{database.StatusFailed}, database.StatusPending, 0, "")
+	if err != nil || !updated {
+		pool.Release()
+		if err != nil {
+			return nil, storageError("requeue calculation", err)
+		}
+		return nil, status.Errorf(codes.FailedPrecondition, "❌ Calculation %d is not failed any more", calculation.Id)
+	}
+	if _, err := database.MarkDeadLetterRequeued(db, deadLetter.Id); err != nil {
+		log.Printf("❌ Failed to mark dead letter %d as requeued: %v", deadLetter.Id, err)
+	}
+	publishStatus(calculation.Id, database.StatusPending, 0, "")
+	expressionID := calculation.Id
+	pool.Run(calculation.UserId, PriorityBatch, func() { runCalculation(expressionID, node) })
+	log.Printf("🔁 Dead letter %d requeued calculation %d", deadLetter.Id, calculation.Id)
+
+	if requeued, err := database.GetDeadLetter(db, deadLetter.Id); err == nil {
+		deadLetter = requeued
+	}
+	return deadLetterMessage(deadLetter), nil
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	calculate "github.com/ArteShow/Calculator/pkg/Calculation"
+	database "github.com/ArteShow/Calculator/pkg/Database"
+	proto "github.com/ArteShow/Calculator/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+func TestTaskOutOfAttemptsIsDeadLettered(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	o := NewOrchestrator()
+	o.leaseTimeout = 10 * time.Millisecond
+	o.maxAttempts = 2
+	o.retryBackoff = 10 * time.Millisecond
+	assert.NoError(t, o.Persist(db))
+	node, err := calculate.Parse("1+2")
+	assert.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		_, err := o.Evaluate(context.Background(), 1, node)
+		done <- err
+	}()
+
+	// The agent never answers, the task waits for the backoff before it is handed out again
+	first := nextTask(t, o)
+	time.Sleep(15 * time.Millisecond)
+	second := nextTask(t, o)
+	assert.Equal(t, first.Id, second.Id)
+	assert.False(t, second.NotBefore.IsZero())
+	time.Sleep(15 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	o.NextTask(ctx, "test")
+	assert.Error(t, <-done)
+
+	deadLetters, err := database.GetDeadLetters(db, true)
+	assert.NoError(t, err)
+	assert.Len(t, deadLetters, 1)
+	assert.Equal(t, first.Id, deadLetters[0].TaskId)
+	assert.Equal(t, 2, deadLetters[0].Attempts)
+	assert.Equal(t, "no agent finished an operation after 2 attempts", deadLetters[0].Error)
+}
+
+func TestRequeueDeadLetter(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	os.Setenv("DB_PATH", testDBPath)
+
+	_, err := db.Exec(`INSERT INTO calculations (userId, calculation, result, status, error, id) VALUES
+		(4, '(4+5)*6', 0, 'failed', 'no agent finished an operation after 3 attempts', 1), (4, '2+2', 4, 'done', '', 2)`)
+	assert.NoError(t, err)
+	for _, expressionId := range []int{1, 2} {
+		_, err = database.InsertDeadLetter(db, database.DeadLetter{TaskId: 1, ExpressionId: expressionId, Operation: "+", Left: 1, Right: 2, Attempts: 3, Error: "lost"})
+		assert.NoError(t, err)
+	}
+
+	server := &Server{}
+	res, err := server.GetDeadLetters(context.Background(), &proto.DeadLettersRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, res.DeadLetters, 2)
+	assert.Equal(t, "(4+5)*6", res.DeadLetters[1].Expression)
+
+	deadLetter, err := server.RequeueDeadLetter(context.Background(), &proto.RequeueDeadLetterRequest{Id: 1})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, deadLetter.RequeuedAt)
+	status, result, _ := waitForCalculation(t, db, 1)
+	assert.Equal(t, "done", status)
+	assert.Equal(t, 54.0, result)
+
+	_, err = server.RequeueDeadLetter(context.Background(), &proto.RequeueDeadLetterRequest{Id: 1})
+	assert.Equal(t, codes.FailedPrecondition, grpcstatus.Code(err))
+	// The calculation of the second one did not fail
+	_, err = server.RequeueDeadLetter(context.Background(), &proto.RequeueDeadLetterRequest{Id: 2})
+	assert.Equal(t, codes.FailedPrecondition, grpcstatus.Code(err))
+	_, err = server.RequeueDeadLetter(context.Background(), &proto.RequeueDeadLetterRequest{Id: 3})
+	assert.Equal(t, codes.NotFound, grpcstatus.Code(err))
+
+	res, err = server.GetDeadLetters(context.Background(), &proto.DeadLettersRequest{PendingOnly: true})
+	assert.NoError(t, err)
+	assert.Len(t, res.DeadLetters, 1)
+}
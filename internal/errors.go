@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	calculate "github.com/ArteShow/Calculator/pkg/Calculation"
+	database "github.com/ArteShow/Calculator/pkg/Database"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Domain of the ErrorInfo details sent by this server
+const ErrorDomain = "calculator"
+
+// ReasonInvalidExpression is the ErrorInfo reason of an expression that can not be parsed.
+// Its metadata holds the field and, if known, the 1-based position of the problem
+const ReasonInvalidExpression = "INVALID_EXPRESSION"
+
+// How long clients are asked to wait when the queue is full
+const queueFullRetryDelay = time.Second
+
+// withDetails adds details to the status. The status is sent without them
+// if they can not be encoded
+func withDetails(st *status.Status, details ...protoadapt.MessageV1) error {
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// invalidArgument is the INVALID_ARGUMENT status of a request field with a BadRequest naming it
+func invalidArgument(field string, message string) error {
+	st := status.New(codes.InvalidArgument, message)
+	return withDetails(st, &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: message}},
+	})
+}
+
+// invalidExpression is the INVALID_ARGUMENT status of an expression that can not be parsed.
+// The message, the BadRequest and the ErrorInfo say where in the expression the problem is
+func invalidExpression(notation string, err error) error {
+	message := fmt.Sprintf("Invalid expression: %v", err)
+	if notation != "" && notation != calculate.NotationInfix {
+		message = fmt.Sprintf("Invalid %s expression: %v", notation, err)
+	}
+	metadata := map[string]string{"field": "expression"}
+	if position := calculate.ErrorPosition(err); position > 0 {
+		message = fmt.Sprintf("%s at position %d", message, position)
+		metadata["position"] = strconv.Itoa(position)
+	}
+
+	st := status.New(codes.InvalidArgument, message)
+	return withDetails(st,
+		&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{{
+				Field:       "expression",
+				Description: message,
+				Reason:      ReasonInvalidExpression,
+			}},
+		},
+		&errdetails.ErrorInfo{Reason: ReasonInvalidExpression, Domain: ErrorDomain, Metadata: metadata},
+	)
+}
+
+// A full queue is reported as RESOURCE_EXHAUSTED with a RetryInfo, so clients know
+// to retry later. Other errors come from the database
+func submitError(err error) error {
+	if errors.Is(err, ErrQueueFull) {
+		st := status.New(codes.ResourceExhausted, err.Error())
+		return withDetails(st, &errdetails.RetryInfo{RetryDelay: durationpb.New(queueFullRetryDelay)})
+	}
+	return storageError("save calculation", err)
+}
+
+// storageError is the status of a failed database call. A busy database is
+// UNAVAILABLE, so clients may retry
+func storageError(what string, err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case database.IsBusy(err):
+		code = codes.Unavailable
+	}
+	return status.Errorf(code, "❌ Failed to %s: %v", what, err)
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	database "github.com/ArteShow/Calculator/pkg/Database"
+	proto "github.com/ArteShow/Calculator/proto"
+	calculatorv2 "github.com/ArteShow/Calculator/proto/v2"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+func TestSendUserData_InvalidExpressionDetails(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	os.Setenv("DB_PATH", testDBPath)
+	server := &Server{}
+
+	// Positions count in the expression as sent, not in its normalized form
+	req := &proto.UserDataRequest{UserId: 4, Calculation: &proto.Calculation{Expression: "1.000,5*(2+", Locale: "de-DE"}}
+	_, err := server.SendUserData(context.Background(), req)
+	st := grpcstatus.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "Invalid expression: Unexpected end of expression at position 12", st.Message())
+
+	var badRequest *errdetails.BadRequest
+	var info *errdetails.ErrorInfo
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.BadRequest:
+			badRequest = detail
+		case *errdetails.ErrorInfo:
+			info = detail
+		}
+	}
+	if assert.NotNil(t, badRequest) && assert.Len(t, badRequest.FieldViolations, 1) {
+		assert.Equal(t, "expression", badRequest.FieldViolations[0].Field)
+		assert.Equal(t, ReasonInvalidExpression, badRequest.FieldViolations[0].Reason)
+	}
+	if assert.NotNil(t, info) {
+		assert.Equal(t, ReasonInvalidExpression, info.Reason)
+		assert.Equal(t, ErrorDomain, info.Domain)
+		assert.Equal(t, "12", info.Metadata["position"])
+	}
+
+	_, err = server.SendUserData(context.Background(), &proto.UserDataRequest{UserId: 4})
+	assert.Equal(t, codes.InvalidArgument, grpcstatus.Code(err))
+}
+
+func TestStorageError(t *testing.T) {
+	st := grpcstatus.Convert(submitError(ErrQueueFull))
+	if assert.Len(t, st.Details(), 1) {
+		retry, ok := st.Details()[0].(*errdetails.RetryInfo)
+		assert.True(t, ok)
+		assert.Equal(t, time.Second, retry.RetryDelay.AsDuration())
+	}
+
+	assert.Equal(t, codes.Unavailable, grpcstatus.Code(submitError(errors.New("database is locked (5) (SQLITE_BUSY)"))))
+	assert.Equal(t, codes.DeadlineExceeded, grpcstatus.Code(storageError("query", fmt.Errorf("query: %w", context.DeadlineExceeded))))
+	assert.Equal(t, codes.Internal, grpcstatus.Code(storageError("query", errors.New("no such table"))))
+}
+
+// A busy database is UNAVAILABLE in both APIs
+func TestStorageError_BusyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "busy.db")
+	db, err := database.OpenDatabase(path)
+	assert.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("CREATE TABLE calculations (id INTEGER)")
+	assert.NoError(t, err)
+	conn, err := db.Conn(context.Background())
+	assert.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.ExecContext(context.Background(), "BEGIN EXCLUSIVE")
+	assert.NoError(t, err)
+	defer conn.ExecContext(context.Background(), "ROLLBACK")
+
+	t.Setenv("DB_PATH", path+"?_pragma=busy_timeout(10)")
+	_, err = (&Server{}).GetUserCalculation(context.Background(), &proto.GetUserCalculationRequest{UserId: 1, CustomId: 1})
+	assert.Equal(t, codes.Unavailable, grpcstatus.Code(err))
+	_, err = (&CalculatorServer{}).GetExpression(context.Background(), &calculatorv2.GetExpressionRequest{UserId: 1, Id: 1})
+	assert.Equal(t, codes.Unavailable, grpcstatus.Code(err))
+	_, err = (&CalculatorServer{}).ListExpressions(context.Background(), &calculatorv2.ListExpressionsRequest{UserId: 1, PageSize: 10})
+	assert.Equal(t, codes.Unavailable, grpcstatus.Code(err))
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"log"
+	"sync"
+
+	database "github.com/ArteShow/Calculator/pkg/Database"
+)
+
+// Kinds of calculation events
+const (
+	EventStatus   = "status"   // The status of the calculation changed
+	EventProgress = "progress" // An operation of the calculation was computed
+)
+
+// CalculationEvent is sent to everyone watching a calculation
+type CalculationEvent struct {
+	Id             int
+	Type           string
+	Status         string
+	Result         float64
+	Error          string
+	Path           string
+	Value          float64
+	CompletedTasks int
+	TotalTasks     int
+}
+
+// Finished reports whether no more events follow this one
+func (e CalculationEvent) Finished() bool {
+	return e.Type == EventStatus && (e.Status == database.StatusDone ||
+		e.Status == database.StatusFailed || e.Status == database.StatusCancelled)
+}
+
+// eventHub passes calculation events on to the watchers of the calculation
+type eventHub struct {
+	mu       sync.Mutex
+	watchers map[int]map[chan CalculationEvent]struct{}
+}
+
+var events = &eventHub{watchers: map[int]map[chan CalculationEvent]struct{}{}}
+
+// Subscribe starts watching a calculation, call the returned function to stop
+func (h *eventHub) Subscribe(id int) (<-chan CalculationEvent, func()) {
+	ch := make(chan CalculationEvent, 64)
+	h.mu.Lock()
+	if h.watchers[id] == nil {
+		h.watchers[id] = map[chan CalculationEvent]struct{}{}
+	}
+	h.watchers[id][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.watchers[id], ch)
+		if len(h.watchers[id]) == 0 {
+			delete(h.watchers, id)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// Publish never blocks, a watcher that does not keep up misses progress events
+// but always gets the final status
+func (h *eventHub) Publish(event CalculationEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.watchers[event.Id] {
+		select {
+		case ch <- event:
+			continue
+		default:
+		}
+		if !event.Finished() {
+			log.Printf("⚠️ Watcher of calculation %d is too slow, dropped an event", event.Id)
+			continue
+		}
+		// Make room for the final status
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- event
+	}
+}
+
+func (h *eventHub) watcherCount(id int) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.watchers[id])
+}
+
+func publishStatus(id int, status string, result float64, errorText string) {
+	events.Publish(CalculationEvent{Id: id, Type: EventStatus, Status: status, Result: result, Error: errorText})
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	proto "github.com/ArteShow/Calculator/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+func TestEventHub(t *testing.T) {
+	hub := &eventHub{watchers: map[int]map[chan CalculationEvent]struct{}{}}
+	watched, stop := hub.Subscribe(1)
+
+	hub.Publish(CalculationEvent{Id: 1, Type: EventStatus, Status: "running"})
+	hub.Publish(CalculationEvent{Id: 2, Type: EventStatus, Status: "running"})
+	assert.Equal(t, "running", (<-watched).Status)
+	assert.Empty(t, watched)
+
+	// A slow watcher misses progress but still gets the final status
+	for i := 0; i < 100; i++ {
+		hub.Publish(CalculationEvent{Id: 1, Type: EventProgress, CompletedTasks: i})
+	}
+	hub.Publish(CalculationEvent{Id: 1, Type: EventStatus, Status: "done"})
+	var last CalculationEvent
+	for len(watched) > 0 {
+		last = <-watched
+	}
+	assert.True(t, last.Finished())
+
+	stop()
+	assert.Equal(t, 0, hub.watcherCount(1))
+}
+
+// watchStream collects what WatchCalculation sends
+type watchStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan *proto.CalculationEvent
+}
+
+func (s *watchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *watchStream) Send(event *proto.CalculationEvent) error {
+	s.sent <- event
+	return nil
+}
+
+func TestWatchCalculation(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	os.Setenv("DB_PATH", testDBPath)
+
+	_, err := db.Exec(`INSERT INTO calculations (userId, calculation, result, status, id) VALUES
+		(6, '(1+2)*3', 0, 'running', 1), (6, '2+2', 4, 'done', 2)`)
+	assert.NoError(t, err)
+
+	server := &Server{}
+	stream := &watchStream{ctx: context.Background(), sent: make(chan *proto.CalculationEvent, 10)}
+	done := make(chan error)
+	go func() {
+		done <- server.WatchCalculation(&proto.WatchCalculationRequest{UserId: 6, Id: 1}, stream)
+	}()
+
+	assert.Equal(t, "running", (<-stream.sent).Status)
+	assert.Eventually(t, func() bool { return events.watcherCount(1) == 1 }, time.Second, 5*time.Millisecond)
+	events.Publish(CalculationEvent{Id: 1, Type: EventProgress, Status: "running", Path: "0", Value: 3, CompletedTasks: 1, TotalTasks: 2})
+	publishStatus(1, "done", 9, "")
+
+	progress := <-stream.sent
+	assert.Equal(t, "progress", progress.Type)
+	assert.Equal(t, "0", progress.Path)
+	assert.Equal(t, 3.0, progress.Value)
+	assert.Equal(t, int32(2), progress.TotalTasks)
+	final := <-stream.sent
+	assert.Equal(t, "done", final.Status)
+	assert.Equal(t, 9.0, final.Result)
+	assert.NoError(t, <-done)
+	assert.Equal(t, 0, events.watcherCount(1))
+
+	// A finished calculation only sends its status
+	err = server.WatchCalculation(&proto.WatchCalculationRequest{UserId: 6, Id: 2}, stream)
+	assert.NoError(t, err)
+	assert.Equal(t, "done", (<-stream.sent).Status)
+
+	err = server.WatchCalculation(&proto.WatchCalculationRequest{UserId: 7, Id: 2}, stream)
+	assert.Equal(t, codes.NotFound, grpcstatus.Code(err))
+}
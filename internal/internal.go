@@ -3,130 +3,669 @@ package internal
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net"
-	"strings"
 	"sync"
 	"time"
 
 	calculate "github.com/ArteShow/Calculator/pkg/Calculation"
+	certs "github.com/ArteShow/Calculator/pkg/Certs"
 	config "github.com/ArteShow/Calculator/pkg/Config"
 	database "github.com/ArteShow/Calculator/pkg/Database"
+	MyJWT "github.com/ArteShow/Calculator/pkg/JWT"
 
 	user "github.com/ArteShow/Calculator/proto"
+	calculatorv2 "github.com/ArteShow/Calculator/proto/v2"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Server struct {
 	user.UnimplementedUserServiceServer
 }
 
+// CalculationExpression saves the expression as pending and calculates it in the background
 func CalculationExpression(userId int, expression string) string {
+	node, err := calculate.Parse(expression)
+	if err != nil {
+		return fmt.Sprintf("❌ Invalid expression: %v", err)
+	}
+	expressionID, err := submitCalculation(userId, expression, node, "", PriorityInteractive)
+	if err != nil {
+		return fmt.Sprintf("❌ Failed to save calculation: %v", err)
+	}
+	return fmt.Sprintf("Your expression was saved with ID %d", expressionID)
+}
+
+// Save the calculation as pending and queue it in the worker pool, which lets the orchestrator
+// calculate it. The status in the calculations table goes pending -> running -> done or failed.
+// Returns ErrQueueFull without saving anything if the queue is full. A non-empty
+// callbackUrl is notified when the calculation is finished
+func submitCalculation(userId int, expression string, node *calculate.Node, callbackUrl string, priority string) (int, error) {
 	log.Printf("User %d requested: %s", userId, expression)
 
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	var finalResult float64
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	pool := calculationPool()
+	if err := pool.Reserve(); err != nil {
+		return 0, err
+	}
 
-	parts := strings.Fields(expression)
-	wg.Add(len(parts))
-	for _, expr := range parts {
-		go func(expr string) {
-			defer wg.Done()
-			select {
-			case <-ctx.Done():
-				log.Println("Timeout: Calculation aborted")
-				return
-			default:
-				result, err, code := calculate.Calc(expr)
-				mu.Lock()
-				if err != nil {
-					log.Println("Error in calculation:", err)
-				} else {
-					log.Printf("Calculation: %s = %f, StatusCode: %d\n", expr, result, code)
-					finalResult += result
-				}
-				mu.Unlock()
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		pool.Release()
+		return 0, fmt.Errorf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var expressionID int
+	err = retryBusy("save a calculation", func() error {
+		expressionID, err = database.InsertCalculation(db, userId, expression, callbackUrl)
+		return err
+	})
+	if err != nil {
+		pool.Release()
+		return 0, err
+	}
+
+	pool.Run(userId, priority, func() { runCalculation(expressionID, node) })
+	return expressionID, nil
+}
+
+// MaxBatchSize is the most expressions accepted in one batch
+const MaxBatchSize = 1000
+
+// Status of a batch item that was not saved
+const BatchRejected = "rejected"
+
+// SendBatch saves the valid expressions of the batch in one transaction and queues them.
+// An invalid expression or a full queue only rejects its own item
+func (s *Server) SendBatch(ctx context.Context, req *user.BatchRequest) (*user.BatchResponse, error) {
+	userId := int(req.UserId)
+	if len(req.Calculations) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "❌ The batch is empty")
+	}
+	if len(req.Calculations) > MaxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "❌ The batch has %d expressions, at most %d are allowed", len(req.Calculations), MaxBatchSize)
+	}
+	log.Printf("User %d requested a batch of %d expressions", userId, len(req.Calculations))
+
+	pool := calculationPool()
+	items := make([]*user.BatchItem, len(req.Calculations))
+	nodes := map[int]*calculate.Node{}
+	accepted := []int{}
+	newCalculations := []database.NewCalculation{}
+	for i, calculation := range req.Calculations {
+		items[i] = &user.BatchItem{Index: int32(i), Status: BatchRejected}
+		if calculation.GetExpression() == "" {
+			items[i].Error = "❌ No expression provided"
+			continue
+		}
+		expression, node, err := parseCalculation(calculation)
+		if err != nil {
+			items[i].Error = status.Convert(err).Message()
+			continue
+		}
+		if err := pool.Reserve(); err != nil {
+			items[i].Error = err.Error()
+			continue
+		}
+		nodes[i] = node
+		accepted = append(accepted, i)
+		newCalculations = append(newCalculations, database.NewCalculation{
+			Expression:  expression,
+			CallbackUrl: calculation.CallbackUrl,
+		})
+	}
+
+	if len(accepted) > 0 {
+		ids, err := insertBatch(userId, newCalculations)
+		if err != nil {
+			for range accepted {
+				pool.Release()
 			}
-		}(expr)
+			return nil, storageError("save the batch", err)
+		}
+		for n, i := range accepted {
+			expressionID, node := ids[n], nodes[i]
+			items[i].Id = int32(expressionID)
+			items[i].Status = database.StatusPending
+			pool.Run(userId, PriorityBatch, func() { runCalculation(expressionID, node) })
+		}
 	}
+	return &user.BatchResponse{Items: items}, nil
+}
 
-	wg.Wait()
-	dbPath := config.GetDatabasePath()
-	db, err := database.OpenDatabase(dbPath)
+func insertBatch(userId int, calculations []database.NewCalculation) ([]int, error) {
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	var ids []int
+	err = retryBusy("save a batch", func() error {
+		ids, err = database.InsertCalculations(db, userId, calculations)
+		return err
+	})
+	return ids, err
+}
+
+// saveCachedCalculation saves a calculation whose result came from the cache as done
+func saveCachedCalculation(userId int, expression string, callbackUrl string, result float64) (int, error) {
+	log.Printf("User %d requested: %s, the result is cached", userId, expression)
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		return 0, fmt.Errorf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	expressionID, err := database.InsertCalculation(db, userId, expression, callbackUrl)
+	if err != nil {
+		return 0, err
+	}
+	if err := database.UpdateCalculation(db, expressionID, database.StatusDone, result, ""); err != nil {
+		return 0, err
+	}
+	publishStatus(expressionID, database.StatusDone, result, "")
+	notifyCallback(db, expressionID)
+	return expressionID, nil
+}
+
+// Cancel functions of the calculations being evaluated, by calculation id
+var (
+	cancelsMu sync.Mutex
+	cancels   = map[int]context.CancelFunc{}
+)
+
+func runCalculation(expressionID int, node *calculate.Node) {
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		log.Printf("❌ Failed to open database for calculation %d: %v", expressionID, err)
+		return
+	}
+	defer db.Close()
+
+	// A calculation cancelled while it waited in the queue is skipped
+	started, err := updateCalculationFrom(db, expressionID,
+		[]string{database.StatusPending, database.StatusRunning}, database.StatusRunning, 0, "")
 	if err != nil {
-		log.Fatalf("Failed to open DB: %v", err)
+		// It stays pending and is resumed on the next start
+		log.Printf("❌ Failed to start calculation %d: %v", expressionID, err)
+		return
+	}
+	if !started {
+		return
+	}
+	publishStatus(expressionID, database.StatusRunning, 0, "")
+
+	calculatorConfig, err := config.LoadCalculatorConfig()
+	if err != nil {
+		log.Printf("❌ Failed to load calculator config: %v", err)
+		database.UpdateCalculation(db, expressionID, database.StatusFailed, 0, err.Error())
+		publishStatus(expressionID, database.StatusFailed, 0, err.Error())
+		notifyCallback(db, expressionID)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(calculatorConfig.CalculationTimeoutMs)*time.Millisecond)
+	defer cancel()
+	cancelsMu.Lock()
+	if _, ok := cancels[expressionID]; ok {
+		// The running evaluation saves the result, its cancel must stay reachable
+		cancelsMu.Unlock()
+		log.Printf("⚠️ Calculation %d is already running", expressionID)
+		return
+	}
+	cancels[expressionID] = cancel
+	cancelsMu.Unlock()
+	defer func() {
+		cancelsMu.Lock()
+		delete(cancels, expressionID)
+		cancelsMu.Unlock()
+	}()
+
+	result, err := orchestrator.Evaluate(ctx, expressionID, node)
+	if errors.Is(err, ErrAlreadyRunning) {
+		log.Printf("⚠️ Calculation %d is already running", expressionID)
+		return
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		log.Printf("🛑 Calculation %d was cancelled", expressionID)
+		return
 	}
-	expressionID, err := database.GetMaxExpressionIdByUserId(db, userId)
+	running := []string{database.StatusRunning}
 	if err != nil {
-		log.Fatalf("Failed to get max expression ID: %v", err)
+		log.Printf("❌ Calculation %d failed: %v", expressionID, err)
+		if updated, _ := updateCalculationFrom(db, expressionID, running, database.StatusFailed, 0, err.Error()); updated {
+			publishStatus(expressionID, database.StatusFailed, 0, err.Error())
+			notifyCallback(db, expressionID)
+		}
+		return
+	}
+	log.Printf("✅ Calculation %d done: %f", expressionID, result)
+	if cache := resultCache(); cache != nil {
+		cache.Put(CacheKey(node), result)
+	}
+	if updated, err := updateCalculationFrom(db, expressionID, running, database.StatusDone, result, ""); err != nil {
+		log.Printf("❌ Failed to save the result of calculation %d, it is calculated again on the next start: %v", expressionID, err)
+	} else if updated {
+		publishStatus(expressionID, database.StatusDone, result, "")
+		notifyCallback(db, expressionID)
 	}
-	expressionID++
+}
 
-	err = database.InsertData(db, "calculations", map[string]interface{}{
-		"userId":     userId,
-		"calculation": expression,
-		"result":     finalResult,
-		"id":         expressionID,
+// updateCalculationFrom is database.UpdateCalculationFrom, retried while the database is busy
+func updateCalculationFrom(db *sql.DB, id int, from []string, status string, result float64, errorText string) (bool, error) {
+	var updated bool
+	err := retryBusy("update a calculation", func() error {
+		var err error
+		updated, err = database.UpdateCalculationFrom(db, id, from, status, result, errorText)
+		return err
 	})
+	return updated, err
+}
+
+// stopEvaluation stops a calculation being evaluated, the orchestrator drops
+// the tasks that are still queued
+func stopEvaluation(expressionID int) {
+	cancelsMu.Lock()
+	if cancel, ok := cancels[expressionID]; ok {
+		cancel()
+	}
+	cancelsMu.Unlock()
+}
+
+// CancelCalculation stops a pending or running calculation of the user
+func (s *Server) CancelCalculation(ctx context.Context, req *user.CancelCalculationRequest) (*user.UserDataResponse, error) {
+	userId, expressionID := int(req.UserId), int(req.Id)
+
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		return nil, storageError("connect to database", err)
+	}
+	defer db.Close()
 
+	calculation, err := database.GetCalculation(db, userId, expressionID)
+	if err == sql.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "❌ No calculation found for UserId=%d and ExpressionId=%d", userId, expressionID)
+	}
 	if err != nil {
-		log.Fatalf("Failed to save calculation: %v", err)
+		return nil, storageError("retrieve calculation", err)
 	}
 
-	return fmt.Sprintf("Your expression was saved with ID %d", expressionID)
+	cancelled, err := database.UpdateCalculationFrom(db, expressionID,
+		[]string{database.StatusPending, database.StatusRunning}, database.StatusCancelled, 0, "Cancelled by the user")
+	if err != nil {
+		return nil, storageError("cancel calculation", err)
+	}
+	if !cancelled {
+		calculation, err = database.GetCalculation(db, userId, expressionID)
+		if err != nil {
+			return nil, storageError("retrieve calculation", err)
+		}
+		return nil, status.Errorf(codes.FailedPrecondition, "❌ Calculation %d is already %s", expressionID, calculation.Status)
+	}
+
+	stopEvaluation(expressionID)
+
+	log.Printf("🛑 User %d cancelled calculation %d", userId, expressionID)
+	publishStatus(expressionID, database.StatusCancelled, 0, "Cancelled by the user")
+	notifyCallback(db, expressionID)
+	return &user.UserDataResponse{
+		Message:    fmt.Sprintf("Calculation %d was cancelled", expressionID),
+		Id:         int32(expressionID),
+		Status:     database.StatusCancelled,
+		Expression: calculation.Expression,
+	}, nil
+}
+
+// ResumeCalculations queues the calculations that were still pending or running when
+// the server stopped. Operations that were finished before are not computed again
+func ResumeCalculations() error {
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	calculations, err := database.GetUnfinishedCalculations(db)
+	if err != nil {
+		return err
+	}
+	pool := calculationPool()
+	for _, calculation := range calculations {
+		node, err := calculate.Parse(calculation.Expression)
+		if err != nil {
+			database.UpdateCalculation(db, calculation.Id, database.StatusFailed, 0, err.Error())
+			continue
+		}
+		expressionID := calculation.Id
+		// Resumed calculations wait behind the ones sent since the start
+		pool.ReserveWait()
+		pool.Run(calculation.UserId, PriorityBatch, func() { runCalculation(expressionID, node) })
+	}
+	if len(calculations) > 0 {
+		log.Printf("🔁 Resumed %d unfinished calculations", len(calculations))
+	}
+	return nil
 }
 
 func (s *Server) SendUserData(ctx context.Context, req *user.UserDataRequest) (*user.UserDataResponse, error) {
 	userId := int(req.UserId)
 	expressionID := int(req.CustomId)
 
-	var expressionInput string
-	if req.Calculation != nil {
-		expressionInput = req.Calculation.Expression
-	}
-
 	// If both are empty/zero, return error
-	if expressionID == 0 && expressionInput == "" {
-		return &user.UserDataResponse{
-			Message: "❌ No expression or ID provided",
-		}, nil
+	if expressionID == 0 && req.Calculation.GetExpression() == "" {
+		return nil, invalidArgument("expression", "No expression or ID provided")
 	}
 
 	// Case: Calculation input present
-	if expressionInput != "" {
-		message := CalculationExpression(userId, expressionInput)
-		return &user.UserDataResponse{
-			Message: message,
-		}, nil
+	if req.Calculation.GetExpression() != "" {
+		expression, node, err := parseCalculation(req.Calculation)
+		if err != nil {
+			return nil, err
+		}
+		// The same expression was computed before, its result is saved right away
+		if cache := resultCache(); cache != nil {
+			if result, ok := cache.Get(CacheKey(node)); ok {
+				id, err := saveCachedCalculation(userId, expression, req.Calculation.CallbackUrl, result)
+				if err != nil {
+					return nil, submitError(err)
+				}
+				return &user.UserDataResponse{
+					Message: fmt.Sprintf("Your expression was saved with ID %d, its result was known already", id),
+					Id:      int32(id),
+					Status:  database.StatusDone,
+					Result:  result,
+				}, nil
+			}
+		}
+		id, err := submitCalculation(userId, expression, node, req.Calculation.CallbackUrl, PriorityInteractive)
+		if err != nil {
+			return nil, submitError(err)
+		}
+		return submittedResponse(id), nil
 	}
 
 	// Case: ID present, fetch from DB
-	dbPath := config.GetDatabasePath()
-	db, err := database.OpenDatabase(dbPath)
+	calculation, err := s.GetUserCalculation(ctx, &user.GetUserCalculationRequest{
+		UserId:   req.UserId,
+		CustomId: req.CustomId,
+		Format:   req.Format,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user.UserDataResponse{
+		Message:    fmt.Sprintf("✅ Retrieved expression: %s", calculation.Expression),
+		Formatted:  calculation.Formatted,
+		Id:         calculation.Id,
+		Status:     calculation.Status,
+		Result:     calculation.Result,
+		Error:      calculation.Error,
+		Expression: calculation.Expression,
+		CreatedAt:  calculation.CreatedAt,
+		UpdatedAt:  calculation.UpdatedAt,
+	}, nil
+}
+
+// GetUserCalculation returns one calculation of the user, typeset in req.Format if set
+func (s *Server) GetUserCalculation(ctx context.Context, req *user.GetUserCalculationRequest) (*user.UserCalculationResponse, error) {
+	userId, expressionID := int(req.UserId), int(req.CustomId)
+	if req.Format != "" && !calculate.IsFormat(req.Format) {
+		return nil, status.Errorf(codes.InvalidArgument, "❌ Unknown format %s", req.Format)
+	}
+
+	db, err := database.OpenDatabase(config.GetDatabasePath())
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %v", err)
+		return nil, storageError("connect to database", err)
 	}
 	defer db.Close()
 
-	var expression string
-	query := `SELECT calculation FROM calculations WHERE userId = ? AND id = ?`
-	err = db.QueryRow(query, userId, expressionID).Scan(&expression)
+	calculation, err := database.GetCalculation(db, userId, expressionID)
+	if err == sql.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "❌ No calculation found for UserId=%d and ExpressionId=%d", userId, expressionID)
+	}
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("❌ No calculation found for UserId=%d and ExpressionId=%d", userId, expressionID)
+		return nil, storageError("retrieve calculation", err)
+	}
+
+	var formatted string
+	if req.Format != "" {
+		node, err := calculate.Parse(calculation.Expression)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "❌ Failed to parse stored expression: %v", err)
+		}
+		formatted, err = calculate.Format(node, req.Format)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "❌ Failed to format expression: %v", err)
 		}
-		return nil, fmt.Errorf("❌ Failed to retrieve calculation: %v", err)
 	}
 
-	return &user.UserDataResponse{
-		Message: fmt.Sprintf("✅ Retrieved expression: %s", expression),
+	return &user.UserCalculationResponse{
+		Expression: calculation.Expression,
+		Id:         int32(calculation.Id),
+		Status:     calculation.Status,
+		Result:     calculation.Result,
+		Error:      calculation.Error,
+		CreatedAt:  calculation.CreatedAt,
+		UpdatedAt:  calculation.UpdatedAt,
+		Formatted:  formatted,
+	}, nil
+}
+
+// WatchCalculation sends the current status of the calculation, then every change
+// and every computed operation until the calculation is finished
+func (s *Server) WatchCalculation(req *user.WatchCalculationRequest, stream user.UserService_WatchCalculationServer) error {
+	userId, expressionID := int(req.UserId), int(req.Id)
+
+	// Subscribe before reading the status, so no change in between is missed
+	watched, stop := events.Subscribe(expressionID)
+	defer stop()
+
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		return storageError("connect to database", err)
+	}
+	calculation, err := database.GetCalculation(db, userId, expressionID)
+	db.Close()
+	if err == sql.ErrNoRows {
+		return status.Errorf(codes.NotFound, "❌ No calculation found for UserId=%d and ExpressionId=%d", userId, expressionID)
+	}
+	if err != nil {
+		return storageError("retrieve calculation", err)
+	}
+
+	event := CalculationEvent{
+		Id:     calculation.Id,
+		Type:   EventStatus,
+		Status: calculation.Status,
+		Result: calculation.Result,
+		Error:  calculation.Error,
+	}
+	for {
+		if err := stream.Send(eventMessage(event)); err != nil {
+			return err
+		}
+		if event.Finished() {
+			return nil
+		}
+		select {
+		case event = <-watched:
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// GetCalculationPlan shows the DAG of a calculation. Calculations that are not
+// being evaluated and did not finish recently get a plan without states and timing
+func (s *Server) GetCalculationPlan(ctx context.Context, req *user.CalculationPlanRequest) (*user.CalculationPlan, error) {
+	userId, expressionID := int(req.UserId), int(req.Id)
+
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		return nil, storageError("connect to database", err)
+	}
+	calculation, err := database.GetCalculation(db, userId, expressionID)
+	db.Close()
+	if err == sql.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "❌ No calculation found for UserId=%d and ExpressionId=%d", userId, expressionID)
+	}
+	if err != nil {
+		return nil, storageError("retrieve calculation", err)
+	}
+
+	plan, ok := orchestrator.Plan(expressionID)
+	if !ok {
+		node, err := calculate.Parse(calculation.Expression)
+		if err != nil {
+			return nil, status.Errorf(codes.FailedPrecondition, "❌ Calculation %d can not be parsed: %v", expressionID, err)
+		}
+		plan = ExpressionPlan(node)
+	}
+
+	res := &user.CalculationPlan{
+		Id:             int32(calculation.Id),
+		Status:         calculation.Status,
+		Expression:     calculation.Expression,
+		Live:           plan.Live,
+		CompletedTasks: int32(plan.CompletedTasks),
+		TotalTasks:     int32(plan.TotalTasks),
+	}
+	for _, node := range plan.Nodes {
+		planNode := &user.PlanNode{
+			Id:         int32(node.Id),
+			Path:       node.Path,
+			Op:         node.Op,
+			Value:      node.Value,
+			Uses:       int32(node.Uses),
+			State:      node.State,
+			AgentId:    node.AgentId,
+			ReadyAt:    planTime(node.ReadyAt),
+			StartedAt:  planTime(node.StartedAt),
+			FinishedAt: planTime(node.FinishedAt),
+		}
+		for _, id := range node.DependsOn {
+			planNode.DependsOn = append(planNode.DependsOn, int32(id))
+		}
+		res.Nodes = append(res.Nodes, planNode)
+	}
+	return res, nil
+}
+
+func planTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func eventMessage(event CalculationEvent) *user.CalculationEvent {
+	return &user.CalculationEvent{
+		Id:             int32(event.Id),
+		Type:           event.Type,
+		Status:         event.Status,
+		Result:         event.Result,
+		Error:          event.Error,
+		Path:           event.Path,
+		Value:          event.Value,
+		CompletedTasks: int32(event.CompletedTasks),
+		TotalTasks:     int32(event.TotalTasks),
+	}
+}
+
+func (s *Server) GetMetrics(ctx context.Context, req *user.MetricsRequest) (*user.MetricsResponse, error) {
+	metrics := calculationPool().Metrics()
+	metrics.TaskQueueDepth, metrics.TasksRunning = orchestrator.TaskCounts()
+	var cacheMetrics CacheMetrics
+	if cache := resultCache(); cache != nil {
+		cacheMetrics = cache.Metrics()
+	}
+	return &user.MetricsResponse{
+		Workers:               int32(metrics.Workers),
+		BusyWorkers:           int32(metrics.BusyWorkers),
+		Utilisation:           metrics.Utilisation,
+		QueueDepth:            int32(metrics.QueueDepth),
+		QueueCapacity:         int32(metrics.QueueCapacity),
+		InteractiveQueueDepth: int32(metrics.InteractiveQueueDepth),
+		BatchQueueDepth:       int32(metrics.BatchQueueDepth),
+		Processed:             metrics.Processed,
+		Rejected:              metrics.Rejected,
+		TaskQueueDepth:        int32(metrics.TaskQueueDepth),
+		TasksRunning:          int32(metrics.TasksRunning),
+		CacheEnabled:          resultCache() != nil,
+		CacheHits:             cacheMetrics.Hits,
+		CacheMisses:           cacheMetrics.Misses,
+		CacheEntries:          int32(cacheMetrics.Entries),
 	}, nil
 }
 
+func (s *Server) GetAgents(ctx context.Context, req *user.AgentsRequest) (*user.AgentsResponse, error) {
+	response := &user.AgentsResponse{}
+	for _, agent := range orchestrator.Agents(time.Now()) {
+		response.Agents = append(response.Agents, &user.AgentStatus{
+			Id:             agent.Id,
+			Status:         agent.Status,
+			Capacity:       int32(agent.Capacity),
+			RunningTasks:   int32(agent.RunningTasks),
+			CompletedTasks: agent.CompletedTasks,
+			RegisteredAt:   planTime(agent.RegisteredAt),
+			LastSeenAt:     planTime(agent.LastSeenAt),
+		})
+	}
+	return response, nil
+}
+
+// parseCalculation reads the expression in its notation and locale. It returns the
+// expression as it is saved and its tree, or an INVALID_ARGUMENT status saying what
+// is wrong with it
+func parseCalculation(calculation *user.Calculation) (string, *calculate.Node, error) {
+	locale := calculate.DefaultLocale
+	if calculation.Locale != "" {
+		var ok bool
+		locale, ok = calculate.GetLocale(calculation.Locale)
+		if !ok {
+			return "", nil, invalidArgument("locale", fmt.Sprintf("Unknown locale %s", calculation.Locale))
+		}
+	}
+
+	if calculation.CallbackUrl != "" {
+		if err := checkCallbackUrl(context.Background(), calculation.CallbackUrl); err != nil {
+			return "", nil, invalidArgument("callbackUrl", fmt.Sprintf("Invalid callbackUrl %s: %v", calculation.CallbackUrl, err))
+		}
+	}
+
+	notation := calculation.Notation
+	if notation != "" && notation != calculate.NotationInfix {
+		node, err := calculate.ParseWithLocale(calculation.Expression, notation, locale)
+		if err != nil {
+			return "", nil, invalidExpression(notation, err)
+		}
+		return node.String(), node, nil
+	}
+
+	// Infix is saved as written, only with plain numbers. It is parsed as sent,
+	// so positions in errors point into the user's expression
+	node, err := calculate.ParseWithLocale(calculation.Expression, calculate.NotationInfix, locale)
+	if err != nil {
+		return "", nil, invalidExpression(notation, err)
+	}
+	normalized, err := locale.Normalize(calculation.Expression)
+	if err != nil {
+		return "", nil, invalidExpression(notation, err)
+	}
+	return normalized, node, nil
+}
+
+func submittedResponse(id int) *user.UserDataResponse {
+	return &user.UserDataResponse{
+		Message: fmt.Sprintf("Your expression was saved with ID %d", id),
+		Id:      int32(id),
+		Status:  database.StatusPending,
+	}
+}
+
 
 func (s *Server) GetUserCalculations(ctx context.Context, req *user.UserIdRequest) (*user.UserCalculationsResponse, error) {
 	userId := int(req.UserId)
@@ -134,27 +673,22 @@ func (s *Server) GetUserCalculations(ctx context.Context, req *user.UserIdReques
 	dbPath := config.GetDatabasePath()
 	db, err := database.OpenDatabase(dbPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %v", err)
+		return nil, storageError("open database", err)
 	}
 	defer db.Close()
 
-	rows, err := db.Query("SELECT calculation, result FROM calculations WHERE userId = ?", userId)
+	stored, err := database.GetCalculationsByUserId(db, userId)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query calculations: %v", err)
+		return nil, storageError("query calculations", err)
 	}
-	defer rows.Close()
 
 	var calculations []*user.Calculation
-	for rows.Next() {
-		var expression string
-		var result float64
-		err := rows.Scan(&expression, &result)
-		if err != nil {
-			continue
-		}
+	for _, calculation := range stored {
 		calculations = append(calculations, &user.Calculation{
-			Expression: expression,
-			Result:     float32(result),
+			Expression: calculation.Expression,
+			Result:     float32(calculation.Result),
+			Id:         int32(calculation.Id),
+			Status:     calculation.Status,
 		})
 	}
 
@@ -169,9 +703,58 @@ func StartTCPListener() {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	calculatorConfig, err := config.LoadCalculatorConfig()
+	if err != nil {
+		log.Fatalf("Failed to load calculator config: %v", err)
+	}
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	orchestrator.leaseTimeout = time.Duration(calculatorConfig.TaskLeaseMs) * time.Millisecond
+	orchestrator.maxAttempts = calculatorConfig.TaskMaxAttempts
+	retryBackoff = time.Duration(calculatorConfig.RetryBackoffMs) * time.Millisecond
+	orchestrator.retryBackoff = retryBackoff
+	orchestrator.heartbeatInterval = time.Duration(calculatorConfig.AgentHeartbeatMs) * time.Millisecond
+	orchestrator.agentTimeout = time.Duration(calculatorConfig.AgentTimeoutMs) * time.Millisecond
+	if err := orchestrator.Persist(db); err != nil {
+		log.Fatalf("Failed to load tasks: %v", err)
+	}
+	if cache := resultCache(); cache != nil && calculatorConfig.CachePersist {
+		if err := cache.Persist(db); err != nil {
+			log.Fatalf("Failed to load cached results: %v", err)
+		}
+	}
+	go func() {
+		if err := ResumeCalculations(); err != nil {
+			log.Printf("❌ Failed to resume calculations: %v", err)
+		}
+	}()
+	go RunScheduler(context.Background())
+
+	options, err := certs.ServerOptions(calculatorConfig)
+	if err != nil {
+		log.Fatalf("Failed to set up TLS: %v", err)
+	}
+	auth := &Auth{Key: []byte(MyJWT.GetJWTKey()), IsAdmin: calculatorConfig.IsAdmin, AgentToken: calculatorConfig.AgentToken}
+	grpcServer := grpc.NewServer(append(options,
+		grpc.UnaryInterceptor(auth.UnaryInterceptor),
+		grpc.StreamInterceptor(auth.StreamInterceptor),
+	)...)
 	user.RegisterUserServiceServer(grpcServer, &Server{})
+	user.RegisterAgentServiceServer(grpcServer, &AgentServer{orchestrator: orchestrator})
+	calculatorv2.RegisterCalculatorServiceServer(grpcServer, &CalculatorServer{})
 
+	if calculatorConfig.GrpcTLSCert != "" {
+		log.Printf("🔒 gRPC uses TLS, client certificates required: %t", calculatorConfig.GrpcClientCA != "")
+	}
+	if calculatorConfig.AgentToken == "" && calculatorConfig.GrpcClientCA == "" {
+		log.Fatalf("Agents could not authenticate: set agentToken (AGENT_TOKEN) or grpcClientCa (GRPC_CLIENT_CA)")
+	}
+	if calculatorConfig.AgentToken == config.DevAgentToken {
+		log.Println("⚠️ Agents use the dev token, set AGENT_TOKEN for anything but local development")
+	}
 	log.Println("Server is listening on port 50051...")
 	if err := grpcServer.Serve(listener); err != nil {
 		log.Fatalf("Failed to serve: %v", err)
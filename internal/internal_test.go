@@ -8,14 +8,45 @@ import (
 	"testing"
 	"time"
 
+	agent "github.com/ArteShow/Calculator/pkg/Agent"
+	calculate "github.com/ArteShow/Calculator/pkg/Calculation"
 	Database "github.com/ArteShow/Calculator/pkg/Database"
 	proto "github.com/ArteShow/Calculator/proto"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
 )
 
 var testDBPath = "./test.db"
 
+// TestMain stands in for an agent so the orchestrator can finish calculations
+func TestMain(m *testing.M) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go runLocalAgent(ctx, orchestrator, nil)
+	code := m.Run()
+	cancel()
+	os.Exit(code)
+}
+
+func runLocalAgent(ctx context.Context, o *Orchestrator, times agent.OperationTimes) {
+	for {
+		task, ok := o.NextTask(ctx, "test")
+		if !ok {
+			return
+		}
+		result, err := agent.Compute(ctx, &proto.Task{Operation: task.Op, Left: task.Left, Right: task.Right}, times)
+		if ctx.Err() != nil {
+			return
+		}
+		errText := ""
+		if err != nil {
+			errText = err.Error()
+		}
+		o.Complete(task.Id, "test", result, errText)
+	}
+}
+
 func setupTestDB(t *testing.T) *sql.DB {
 	os.Remove(testDBPath)
 	db, err := Database.OpenDatabase(testDBPath)
@@ -25,10 +56,69 @@ func setupTestDB(t *testing.T) *sql.DB {
 
 	_, err = db.Exec(`
 		CREATE TABLE calculations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			userId INTEGER,
 			calculation TEXT,
 			result REAL,
-			id INTEGER
+			status TEXT NOT NULL DEFAULT 'done',
+			error TEXT NOT NULL DEFAULT '',
+			createdAt TEXT NOT NULL DEFAULT '',
+			updatedAt TEXT NOT NULL DEFAULT '',
+			callbackUrl TEXT NOT NULL DEFAULT ''
+		);
+		CREATE TABLE webhook_secrets (
+			userId INTEGER PRIMARY KEY,
+			secret TEXT NOT NULL,
+			createdAt TEXT NOT NULL DEFAULT ''
+		);
+		CREATE TABLE tasks (
+			id INTEGER PRIMARY KEY,
+			expressionId INTEGER NOT NULL,
+			path TEXT NOT NULL,
+			operation TEXT NOT NULL,
+			leftValue REAL NOT NULL,
+			rightValue REAL NOT NULL,
+			status TEXT NOT NULL DEFAULT 'queued',
+			result REAL NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			agentId TEXT NOT NULL DEFAULT '',
+			leaseUntil TEXT NOT NULL DEFAULT '',
+			createdAt TEXT NOT NULL DEFAULT '',
+			updatedAt TEXT NOT NULL DEFAULT ''
+		);
+		CREATE TABLE schedules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			userId INTEGER NOT NULL,
+			expression TEXT NOT NULL,
+			variables TEXT NOT NULL DEFAULT '',
+			cron TEXT NOT NULL,
+			callbackUrl TEXT NOT NULL DEFAULT '',
+			enabled INTEGER NOT NULL DEFAULT 1,
+			nextRunAt TEXT NOT NULL DEFAULT '',
+			lastRunAt TEXT NOT NULL DEFAULT '',
+			lastCalculationId INTEGER NOT NULL DEFAULT 0,
+			lastError TEXT NOT NULL DEFAULT '',
+			createdAt TEXT NOT NULL DEFAULT '',
+			updatedAt TEXT NOT NULL DEFAULT ''
+		);
+		CREATE TABLE result_cache (
+			key TEXT PRIMARY KEY,
+			result REAL NOT NULL,
+			usedAt TEXT NOT NULL DEFAULT ''
+		);
+		CREATE TABLE dead_letters (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			taskId INTEGER NOT NULL,
+			expressionId INTEGER NOT NULL,
+			path TEXT NOT NULL DEFAULT '',
+			operation TEXT NOT NULL,
+			leftValue REAL NOT NULL,
+			rightValue REAL NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			createdAt TEXT NOT NULL DEFAULT '',
+			requeuedAt TEXT NOT NULL DEFAULT ''
 		);
 	`)
 	if err != nil {
@@ -37,6 +127,22 @@ func setupTestDB(t *testing.T) *sql.DB {
 	return db
 }
 
+// waitForCalculation waits until the background job of the calculation has finished
+func waitForCalculation(t *testing.T, db *sql.DB, id int) (string, float64, string) {
+	var status, errorText string
+	var result float64
+	for i := 0; i < 100; i++ {
+		err := db.QueryRow(`SELECT status, result, error FROM calculations WHERE id = ?`, id).Scan(&status, &result, &errorText)
+		assert.NoError(t, err)
+		if status != "pending" && status != "running" {
+			return status, result, errorText
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("calculation %d did not finish, status %s", id, status)
+	return "", 0, ""
+}
+
 func TestCalculationExpression(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -47,12 +153,98 @@ func TestCalculationExpression(t *testing.T) {
 	defer os.Setenv("DB_PATH", oldPath)
 
 	result := CalculationExpression(1, "2+2 3*3")
+	assert.Contains(t, result, "Invalid expression")
+
+	result = CalculationExpression(1, "2+2+3*3")
 	assert.Contains(t, result, "saved with ID")
 
 	var count int
 	err := db.QueryRow(`SELECT COUNT(*) FROM calculations`).Scan(&count)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, count)
+
+	status, value, _ := waitForCalculation(t, db, 1)
+	assert.Equal(t, "done", status)
+	assert.Equal(t, 13.0, value)
+}
+
+func TestCalculationExpression_Failed(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	os.Setenv("DB_PATH", testDBPath)
+
+	CalculationExpression(1, "1/0")
+	status, _, errorText := waitForCalculation(t, db, 1)
+	assert.Equal(t, "failed", status)
+	assert.Equal(t, "Division by zero", errorText)
+}
+
+func TestResumeCalculations(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	os.Setenv("DB_PATH", testDBPath)
+
+	_, err := db.Exec(`INSERT INTO calculations (userId, calculation, result, status, id) VALUES
+		(1, '2*5', 0, 'running', 1), (1, '2*', 0, 'pending', 2), (1, '1+1', 2, 'done', 3)`)
+	assert.NoError(t, err)
+
+	assert.NoError(t, ResumeCalculations())
+
+	status, result, _ := waitForCalculation(t, db, 1)
+	assert.Equal(t, "done", status)
+	assert.Equal(t, 10.0, result)
+	status, _, errorText := waitForCalculation(t, db, 2)
+	assert.Equal(t, "failed", status)
+	assert.NotEmpty(t, errorText)
+}
+
+func TestCancelCalculation(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	os.Setenv("DB_PATH", testDBPath)
+
+	_, err := db.Exec(`INSERT INTO calculations (userId, calculation, result, status, id) VALUES
+		(4, '1+1', 0, 'running', 1), (4, '2+2', 4, 'done', 2)`)
+	assert.NoError(t, err)
+
+	// Pretend calculation 1 is being evaluated
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelsMu.Lock()
+	cancels[1] = cancel
+	cancelsMu.Unlock()
+	defer func() {
+		cancelsMu.Lock()
+		delete(cancels, 1)
+		cancelsMu.Unlock()
+	}()
+
+	server := &Server{}
+	resp, err := server.CancelCalculation(context.Background(), &proto.CancelCalculationRequest{UserId: 4, Id: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "cancelled", resp.Status)
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+
+	var status string
+	assert.NoError(t, db.QueryRow(`SELECT status FROM calculations WHERE id = 1`).Scan(&status))
+	assert.Equal(t, "cancelled", status)
+
+	// A cancelled calculation is not started any more
+	node, _ := calculate.Parse("1+1")
+	runCalculation(1, node)
+	assert.NoError(t, db.QueryRow(`SELECT status FROM calculations WHERE id = 1`).Scan(&status))
+	assert.Equal(t, "cancelled", status)
+
+	_, err = server.CancelCalculation(context.Background(), &proto.CancelCalculationRequest{UserId: 4, Id: 2})
+	assert.Equal(t, codes.FailedPrecondition, grpcstatus.Code(err))
+	_, err = server.CancelCalculation(context.Background(), &proto.CancelCalculationRequest{UserId: 5, Id: 1})
+	assert.Equal(t, codes.NotFound, grpcstatus.Code(err))
 }
 
 func startGRPCServer(t *testing.T, srv proto.UserServiceServer) net.Listener {
@@ -65,7 +257,7 @@ func startGRPCServer(t *testing.T, srv proto.UserServiceServer) net.Listener {
 
 	go func() {
 		if err := s.Serve(lis); err != nil {
-			t.Fatalf("failed to serve: %v", err)
+			t.Errorf("failed to serve: %v", err)
 		}
 	}()
 	time.Sleep(time.Second) // wait for server
@@ -83,6 +275,18 @@ func TestGetExpressionByID(t *testing.T) {
 
 	os.Setenv("DB_PATH", testDBPath)
 
+	server := &Server{}
+	resp, err := server.GetUserCalculation(context.Background(), &proto.GetUserCalculationRequest{UserId: 42, CustomId: 1, Format: "unicode"})
+	assert.NoError(t, err)
+	assert.Equal(t, "5+5", resp.Expression)
+	assert.Equal(t, "done", resp.Status)
+	assert.Equal(t, 10.0, resp.Result)
+	assert.Equal(t, "5 + 5", resp.Formatted)
+
+	_, err = server.GetUserCalculation(context.Background(), &proto.GetUserCalculationRequest{UserId: 43, CustomId: 1})
+	assert.Equal(t, codes.NotFound, grpcstatus.Code(err))
+	_, err = server.GetUserCalculation(context.Background(), &proto.GetUserCalculationRequest{UserId: 42, CustomId: 1, Format: "roman"})
+	assert.Equal(t, codes.InvalidArgument, grpcstatus.Code(err))
 }
 
 func TestSendUserData(t *testing.T) {
@@ -101,4 +305,138 @@ func TestSendUserData(t *testing.T) {
 	resp, err := server.SendUserData(context.Background(), req)
 	assert.NoError(t, err)
 	assert.Contains(t, resp.Message, "1+1")
+	assert.Equal(t, "done", resp.Status)
+	assert.Equal(t, 2.0, resp.Result)
+}
+
+func TestSendUserData_RPN(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	os.Setenv("DB_PATH", testDBPath)
+
+	server := &Server{}
+	req := &proto.UserDataRequest{UserId: 3, Calculation: &proto.Calculation{Expression: "1 2 + 3 *", Notation: "rpn"}}
+	resp, err := server.SendUserData(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Contains(t, resp.Message, "saved with ID 1")
+	assert.Equal(t, "pending", resp.Status)
+
+	_, result, _ := waitForCalculation(t, db, int(resp.Id))
+	assert.Equal(t, 9.0, result)
+
+	var expression string
+	err = db.QueryRow(`SELECT calculation FROM calculations WHERE userId = 3`).Scan(&expression)
+	assert.NoError(t, err)
+	assert.Equal(t, "((1+2)*3)", expression)
+
+	req.Calculation.Expression = "1 +"
+	_, err = server.SendUserData(context.Background(), req)
+	assert.Equal(t, codes.InvalidArgument, grpcstatus.Code(err))
+	assert.Contains(t, grpcstatus.Convert(err).Message(), "Invalid rpn expression")
+}
+
+func TestSendUserData_Format(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	_, err := db.Exec(`INSERT INTO calculations (userId, calculation, result, id) VALUES (?, ?, ?, ?)`,
+		7, "((1+2)*(3/4))", 2.25, 1)
+	assert.NoError(t, err)
+
+	os.Setenv("DB_PATH", testDBPath)
+
+	server := &Server{}
+	req := &proto.UserDataRequest{UserId: 7, CustomId: 1, Format: "latex"}
+	resp, err := server.SendUserData(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, `\left(1 + 2\right) \cdot \frac{3}{4}`, resp.Formatted)
+}
+
+func TestSendUserData_Locale(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	os.Setenv("DB_PATH", testDBPath)
+
+	server := &Server{}
+	req := &proto.UserDataRequest{UserId: 5, Calculation: &proto.Calculation{Expression: "1.000,5*2", Locale: "de-DE"}}
+	resp, err := server.SendUserData(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Contains(t, resp.Message, "saved with ID 1")
+
+	_, result, _ := waitForCalculation(t, db, int(resp.Id))
+	assert.Equal(t, 2001.0, result)
+
+	var expression string
+	err = db.QueryRow(`SELECT calculation FROM calculations WHERE userId = 5`).Scan(&expression)
+	assert.NoError(t, err)
+	assert.Equal(t, "1000.5*2", expression)
+
+	req.Calculation.Locale = "xx-YY"
+	_, err = server.SendUserData(context.Background(), req)
+	assert.Equal(t, codes.InvalidArgument, grpcstatus.Code(err))
+	assert.Contains(t, grpcstatus.Convert(err).Message(), "Unknown locale")
+}
+
+func TestSendBatch(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	os.Setenv("DB_PATH", testDBPath)
+
+	server := &Server{}
+	resp, err := server.SendBatch(context.Background(), &proto.BatchRequest{UserId: 6, Calculations: []*proto.Calculation{
+		{Expression: "2+3"},
+		{Expression: "2+"},
+		{Expression: "3 4 *", Notation: "rpn"},
+		{},
+	}})
+	assert.NoError(t, err)
+	assert.Len(t, resp.Items, 4)
+
+	assert.Equal(t, "pending", resp.Items[0].Status)
+	assert.Equal(t, "rejected", resp.Items[1].Status)
+	assert.Contains(t, resp.Items[1].Error, "Invalid expression")
+	assert.Equal(t, int32(1), resp.Items[1].Index)
+	assert.Equal(t, "pending", resp.Items[2].Status)
+	assert.Equal(t, "rejected", resp.Items[3].Status)
+
+	_, result, _ := waitForCalculation(t, db, int(resp.Items[0].Id))
+	assert.Equal(t, 5.0, result)
+	_, result, _ = waitForCalculation(t, db, int(resp.Items[2].Id))
+	assert.Equal(t, 12.0, result)
+
+	_, err = server.SendBatch(context.Background(), &proto.BatchRequest{UserId: 6})
+	assert.Equal(t, codes.InvalidArgument, grpcstatus.Code(err))
+}
+
+func TestGetCalculationPlan(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	os.Setenv("DB_PATH", testDBPath)
+
+	_, err := db.Exec(`INSERT INTO calculations (userId, calculation, result, status, id) VALUES
+		(4, '(1+2)*(1+2)', 9, 'done', 1000)`)
+	assert.NoError(t, err)
+
+	// Not evaluated since the start, the plan comes from the expression
+	server := &Server{}
+	plan, err := server.GetCalculationPlan(context.Background(), &proto.CalculationPlanRequest{UserId: 4, Id: 1000})
+	assert.NoError(t, err)
+	assert.Equal(t, "done", plan.Status)
+	assert.False(t, plan.Live)
+	assert.Equal(t, int32(2), plan.TotalTasks)
+	assert.Len(t, plan.Nodes, 4)
+	assert.Equal(t, []int32{1, 1}, plan.Nodes[0].DependsOn)
+	assert.Equal(t, int32(2), plan.Nodes[1].Uses)
+
+	_, err = server.GetCalculationPlan(context.Background(), &proto.CalculationPlanRequest{UserId: 5, Id: 1000})
+	assert.Equal(t, codes.NotFound, grpcstatus.Code(err))
 }
@@ -0,0 +1,675 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	calculate "github.com/ArteShow/Calculator/pkg/Calculation"
+	database "github.com/ArteShow/Calculator/pkg/Database"
+	user "github.com/ArteShow/Calculator/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUnknownTask is returned for a result of a task that is not being computed
+var ErrUnknownTask = errors.New("Unknown task")
+
+// ErrNotLeaseHolder is returned for a result of a task leased to another agent,
+// e.g. because the lease of the submitting agent ran out and the task was handed out again
+var ErrNotLeaseHolder = errors.New("the task is leased to another agent")
+
+// ErrAlreadyRunning is returned when a calculation is evaluated while an
+// earlier evaluation of it has not finished yet
+var ErrAlreadyRunning = errors.New("the calculation is already running")
+
+// Task is one binary operation whose operands are already known,
+// so an agent can compute it without knowing the rest of the expression
+type Task struct {
+	Id           int64
+	ExpressionId int
+	Op           string
+	Left         float64
+	Right        float64
+	Attempts     int
+	LeaseUntil   time.Time
+	AgentId      string
+	// A task handed out again waits until then, so a failing agent is not hammered
+	NotBefore time.Time
+
+	node *taskNode
+}
+
+// States of a node in the plan of a calculation
+const (
+	NodeWaiting = "waiting" // Operands are missing
+	NodeQueued  = "queued"  // Waits for an agent
+	NodeRunning = "running" // Leased to an agent
+	NodeDone    = "done"
+	NodeFailed  = "failed"
+)
+
+// taskNode is a node of an expression that is being calculated. Equal sub-expressions
+// share one node, so the nodes form a DAG and parents has one entry for every use
+// as an operand. pending counts the operands that are not known yet, path is the
+// position of the first use in the tree, e.g. "0.1" for the right operand of the left operand
+type taskNode struct {
+	id      int
+	op      string
+	value   float64
+	path    string
+	args    []*taskNode
+	parents []*taskNode
+	pending int
+
+	state      string
+	agentId    string
+	readyAt    time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+}
+
+// run is one expression being calculated, nodes are in the order they were built
+type run struct {
+	id        int
+	result    chan runResult
+	total     int
+	completed int
+	nodes     []*taskNode
+}
+
+// Number of finished calculations whose plan is kept
+const recentPlans = 100
+
+type runResult struct {
+	value float64
+	err   error
+}
+
+// Orchestrator splits expressions into tasks, hands them out to agents and
+// puts the results back together. An agent leases a task for leaseTimeout, if no
+// result comes back in that time the task is handed out again after retryBackoff, doubled
+// for every further attempt, up to maxAttempts times. Tasks of a registered agent that missed
+// its heartbeats are handed out again without waiting for the lease. A task out of attempts
+// is saved as a dead letter and fails its calculation
+type Orchestrator struct {
+	mu           sync.Mutex
+	nextId       int64
+	queue        []*Task
+	wake         chan struct{}
+	running      map[int64]*Task
+	runs         map[int]*run
+	db           *sql.DB
+	leaseTimeout time.Duration
+	maxAttempts  int
+	retryBackoff time.Duration
+	progress     func(CalculationEvent)
+	recent       map[int]*run
+	recentOrder  []int
+
+	agents            map[string]*agentState
+	heartbeatInterval time.Duration
+	agentTimeout      time.Duration
+}
+
+func NewOrchestrator() *Orchestrator {
+	return &Orchestrator{
+		wake:         make(chan struct{}, 1),
+		running:      map[int64]*Task{},
+		runs:         map[int]*run{},
+		recent:       map[int]*run{},
+		leaseTimeout: 30 * time.Second,
+		maxAttempts:  3,
+		retryBackoff: 200 * time.Millisecond,
+
+		agents:            map[string]*agentState{},
+		heartbeatInterval: 5 * time.Second,
+		agentTimeout:      15 * time.Second,
+	}
+}
+
+// Persist keeps the tasks in the tasks table, so finished operations
+// are not computed again when a calculation is resumed after a restart
+func (o *Orchestrator) Persist(db *sql.DB) error {
+	maxId, err := database.GetMaxTaskId(db)
+	if err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.db = db
+	if maxId > o.nextId {
+		o.nextId = maxId
+	}
+	return nil
+}
+
+// orchestrator is shared by the gRPC services and the background calculations,
+// it reports every computed operation to the watchers of the calculation
+var orchestrator = func() *Orchestrator {
+	o := NewOrchestrator()
+	o.progress = events.Publish
+	return o
+}()
+
+// Evaluate calculates the expression with the help of the agents and waits for the result
+func (o *Orchestrator) Evaluate(ctx context.Context, expressionId int, node *calculate.Node) (float64, error) {
+	if err := node.Validate(); err != nil {
+		return 0, err
+	}
+	r := &run{id: expressionId, result: make(chan runResult, 1)}
+
+	o.mu.Lock()
+	// Replacing the run would leave the first caller waiting forever
+	if _, ok := o.runs[expressionId]; ok {
+		o.mu.Unlock()
+		return 0, ErrAlreadyRunning
+	}
+	dag := newDagBuilder(o.finishedTasks(expressionId), time.Now())
+	dag.build(node, nil, "")
+	r.nodes = dag.nodes
+	r.total = countTasks(dag.nodes)
+	o.runs[expressionId] = r
+	for _, leaf := range dag.leaves {
+		o.resolve(r, leaf)
+	}
+	o.mu.Unlock()
+
+	select {
+	case result := <-r.result:
+		return result.value, result.err
+	case <-ctx.Done():
+		// A result may have arrived just now, finish only fails the run if not
+		err := fmt.Errorf("timeout: calculation aborted")
+		if errors.Is(ctx.Err(), context.Canceled) {
+			err = fmt.Errorf("calculation cancelled")
+		}
+		o.mu.Lock()
+		o.finish(r, runResult{err: err})
+		o.mu.Unlock()
+		result := <-r.result
+		return result.value, result.err
+	}
+}
+
+// dagBuilder converts the tree into a DAG. Operations with a result in
+// finished and numbers become leaves, they are done from the start
+type dagBuilder struct {
+	finished map[string]float64
+	now      time.Time
+	seen     map[string]*taskNode
+	nodes    []*taskNode
+	leaves   []*taskNode
+}
+
+func newDagBuilder(finished map[string]float64, now time.Time) *dagBuilder {
+	return &dagBuilder{finished: finished, now: now, seen: map[string]*taskNode{}}
+}
+
+func (b *dagBuilder) build(node *calculate.Node, parent *taskNode, path string) *taskNode {
+	// The bracketed form is the same for equal sub-expressions
+	key := node.String()
+	n, ok := b.seen[key]
+	if !ok {
+		n = &taskNode{id: len(b.nodes), op: node.Op, value: node.Value, path: path, state: NodeWaiting}
+		b.seen[key] = n
+		b.nodes = append(b.nodes, n)
+		if value, ok := b.finished[path]; ok || node.Op == calculate.OpNumber {
+			if ok {
+				n.value = value
+			}
+			n.state = NodeDone
+			n.readyAt, n.finishedAt = b.now, b.now
+			b.leaves = append(b.leaves, n)
+		} else {
+			n.pending = len(node.Args)
+			for i, arg := range node.Args {
+				argPath := strconv.Itoa(i)
+				if path != "" {
+					argPath = path + "." + argPath
+				}
+				n.args = append(n.args, b.build(arg, n, argPath))
+			}
+		}
+	}
+	if parent != nil {
+		n.parents = append(n.parents, parent)
+	}
+	return n
+}
+
+// countTasks counts the binary operations that still have to be computed
+func countTasks(nodes []*taskNode) int {
+	count := 0
+	for _, n := range nodes {
+		if len(n.args) == 2 {
+			count++
+		}
+	}
+	return count
+}
+
+// finishedTasks loads the results of the operations that were already computed before
+// a restart and forgets the unfinished ones, those are created again.
+// The caller holds o.mu
+func (o *Orchestrator) finishedTasks(expressionId int) map[string]float64 {
+	finished := map[string]float64{}
+	if o.db == nil {
+		return finished
+	}
+	tasks, err := database.GetTasks(o.db, expressionId)
+	if err != nil {
+		log.Printf("❌ Failed to load tasks of calculation %d: %v", expressionId, err)
+		return finished
+	}
+	for _, task := range tasks {
+		if task.Status == database.TaskDone && task.Error == "" {
+			finished[task.Path] = task.Result
+		}
+	}
+	if err := database.DeleteTasks(o.db, expressionId, true); err != nil {
+		log.Printf("❌ Failed to delete tasks of calculation %d: %v", expressionId, err)
+	}
+	if len(finished) > 0 {
+		log.Printf("🔁 Calculation %d continues with %d finished operations", expressionId, len(finished))
+	}
+	return finished
+}
+
+// resolve is called once the value of n is known. It finishes the run at the root,
+// otherwise schedules every parent that got its last missing operand.
+// The caller holds o.mu
+func (o *Orchestrator) resolve(r *run, n *taskNode) {
+	now := time.Now()
+	known := []*taskNode{n}
+	for len(known) > 0 {
+		n := known[0]
+		known = known[1:]
+		n.state = NodeDone
+		if n.finishedAt.IsZero() {
+			n.finishedAt = now
+		}
+		if len(n.parents) == 0 {
+			o.finish(r, runResult{value: n.value})
+			return
+		}
+		for _, parent := range n.parents {
+			parent.pending--
+			if parent.pending > 0 {
+				continue
+			}
+			parent.readyAt = now
+			// The unary minus is too cheap to send to an agent
+			if len(parent.args) == 1 {
+				parent.value = -parent.args[0].value
+				known = append(known, parent)
+				continue
+			}
+			o.schedule(r, parent)
+		}
+	}
+}
+
+// schedule creates the task of a binary operation whose operands are known.
+// The caller holds o.mu
+func (o *Orchestrator) schedule(r *run, n *taskNode) {
+	o.nextId++
+	task := &Task{
+		Id:           o.nextId,
+		ExpressionId: r.id,
+		Op:           n.op,
+		Left:         n.args[0].value,
+		Right:        n.args[1].value,
+		node:         n,
+	}
+	if o.db != nil {
+		err := database.InsertTask(o.db, database.Task{
+			Id: task.Id, ExpressionId: task.ExpressionId, Path: n.path,
+			Operation: task.Op, Left: task.Left, Right: task.Right,
+		})
+		if err != nil {
+			log.Printf("❌ Failed to save task %d: %v", task.Id, err)
+		}
+	}
+	n.state = NodeQueued
+	o.push(task)
+}
+
+// finish delivers the result once and forgets the run, so late task results
+// are dropped. The caller holds o.mu
+func (o *Orchestrator) finish(r *run, result runResult) {
+	if o.runs[r.id] != r {
+		return
+	}
+	delete(o.runs, r.id)
+	o.keepPlan(r)
+	if o.db != nil {
+		if err := database.DeleteTasks(o.db, r.id, false); err != nil {
+			log.Printf("❌ Failed to delete tasks of calculation %d: %v", r.id, err)
+		}
+	}
+	queue := o.queue[:0]
+	for _, task := range o.queue {
+		if task.ExpressionId != r.id {
+			queue = append(queue, task)
+		}
+	}
+	o.queue = queue
+	r.result <- result
+}
+
+// keepPlan remembers the finished run for its plan, the oldest is forgotten
+// when there are too many. The caller holds o.mu
+func (o *Orchestrator) keepPlan(r *run) {
+	if _, ok := o.recent[r.id]; !ok {
+		o.recentOrder = append(o.recentOrder, r.id)
+	}
+	o.recent[r.id] = r
+	if len(o.recentOrder) > recentPlans {
+		delete(o.recent, o.recentOrder[0])
+		o.recentOrder = o.recentOrder[1:]
+	}
+}
+
+func (o *Orchestrator) push(task *Task) {
+	o.queue = append(o.queue, task)
+	o.signal()
+}
+
+// signal wakes up one waiting agent
+func (o *Orchestrator) signal() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// NextTask waits for a task until the context is done and leases it to the agent
+func (o *Orchestrator) NextTask(ctx context.Context, agentId string) (*Task, bool) {
+	for {
+		o.mu.Lock()
+		o.seen(agentId, time.Now())
+		o.expireLeases(time.Now())
+		index, wait := o.nextReady(time.Now())
+		if index >= 0 {
+			task := o.queue[index]
+			o.queue = append(o.queue[:index], o.queue[index+1:]...)
+			task.Attempts++
+			task.LeaseUntil = time.Now().Add(o.leaseTimeout)
+			task.AgentId = agentId
+			task.node.state = NodeRunning
+			task.node.agentId = agentId
+			task.node.startedAt = time.Now()
+			o.running[task.Id] = task
+			if o.db != nil {
+				if err := database.LeaseTask(o.db, task.Id, agentId, task.Attempts, task.LeaseUntil); err != nil {
+					log.Printf("❌ Failed to lease task %d: %v", task.Id, err)
+				}
+			}
+			if len(o.queue) > 0 {
+				o.signal()
+			}
+			o.mu.Unlock()
+			return task, true
+		}
+		o.mu.Unlock()
+
+		// Wake up now and then to look for expired leases
+		select {
+		case <-o.wake:
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+// nextReady returns the index of the first queued task that may be handed out, or -1
+// and how long to wait before looking again. The caller holds o.mu
+func (o *Orchestrator) nextReady(now time.Time) (int, time.Duration) {
+	wait := time.Second
+	for i, task := range o.queue {
+		if !now.Before(task.NotBefore) {
+			return i, 0
+		}
+		if until := task.NotBefore.Sub(now); until < wait {
+			wait = until
+		}
+	}
+	return -1, wait
+}
+
+// expireLeases hands out the tasks again whose agent did not answer in time or
+// stopped sending heartbeats, a task that ran out of attempts fails its calculation.
+// The caller holds o.mu
+func (o *Orchestrator) expireLeases(now time.Time) {
+	for id, task := range o.running {
+		agentDead := o.agentDead(task.AgentId, now)
+		if now.Before(task.LeaseUntil) && !agentDead {
+			continue
+		}
+		delete(o.running, id)
+		r, ok := o.runs[task.ExpressionId]
+		if !ok {
+			continue
+		}
+		if task.Attempts >= o.maxAttempts {
+			log.Printf("❌ Task %d got no result after %d attempts", task.Id, task.Attempts)
+			err := fmt.Errorf("no agent finished an operation after %d attempts", task.Attempts)
+			o.deadLetter(task, err)
+			o.finish(r, runResult{err: err})
+			continue
+		}
+		if agentDead {
+			log.Printf("💀 Agent %s stopped sending heartbeats, handing task %d out again", task.AgentId, task.Id)
+		} else {
+			log.Printf("⚠️ Lease of task %d ran out, handing it out again", task.Id)
+		}
+		task.AgentId = ""
+		task.NotBefore = now.Add(backoffAfter(o.retryBackoff, task.Attempts))
+		task.node.state = NodeQueued
+		task.node.agentId = ""
+		if o.db != nil {
+			if err := database.RequeueTask(o.db, task.Id); err != nil {
+				log.Printf("❌ Failed to requeue task %d: %v", task.Id, err)
+			}
+		}
+		o.push(task)
+	}
+}
+
+// deadLetter saves a task that failed for good, so an admin can look into it
+// and start its calculation again. The caller holds o.mu
+func (o *Orchestrator) deadLetter(task *Task, cause error) {
+	if o.db == nil {
+		return
+	}
+	_, err := database.InsertDeadLetter(o.db, database.DeadLetter{
+		TaskId:       task.Id,
+		ExpressionId: task.ExpressionId,
+		Path:         task.node.path,
+		Operation:    task.Op,
+		Left:         task.Left,
+		Right:        task.Right,
+		Attempts:     task.Attempts,
+		Error:        cause.Error(),
+	})
+	if err != nil {
+		log.Printf("❌ Failed to save dead letter of task %d: %v", task.Id, err)
+	}
+}
+
+// TaskCounts returns the number of tasks waiting for an agent and being computed
+func (o *Orchestrator) TaskCounts() (int, int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.queue), len(o.running)
+}
+
+// Complete takes the result of a task from the agent holding its lease. A non-empty
+// errText fails the whole expression
+func (o *Orchestrator) Complete(taskId int64, agentId string, result float64, errText string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	task, ok := o.running[taskId]
+	if !ok {
+		return ErrUnknownTask
+	}
+	if task.AgentId != agentId {
+		return ErrNotLeaseHolder
+	}
+	delete(o.running, taskId)
+	o.seen(task.AgentId, time.Now())
+	if agent, ok := o.agents[task.AgentId]; ok {
+		agent.completed++
+	}
+	if o.db != nil {
+		if err := database.CompleteTask(o.db, taskId, result, errText); err != nil {
+			log.Printf("❌ Failed to save the result of task %d: %v", taskId, err)
+		}
+	}
+
+	r, ok := o.runs[task.ExpressionId]
+	if !ok {
+		// The calculation timed out in the meantime
+		return nil
+	}
+	if errText != "" {
+		task.node.state = NodeFailed
+		task.node.finishedAt = time.Now()
+		o.finish(r, runResult{err: errors.New(errText)})
+		return nil
+	}
+	task.node.value = result
+	r.completed++
+	if o.progress != nil {
+		o.progress(CalculationEvent{
+			Id:             r.id,
+			Type:           EventProgress,
+			Status:         database.StatusRunning,
+			Path:           task.node.path,
+			Value:          result,
+			CompletedTasks: r.completed,
+			TotalTasks:     r.total,
+		})
+	}
+	o.resolve(r, task.node)
+	return nil
+}
+
+// PlanNode is one node of the DAG of a calculation, DependsOn lists the ids of its operands
+// and Uses how often its result is used as an operand
+type PlanNode struct {
+	Id         int
+	Path       string
+	Op         string
+	Value      float64
+	DependsOn  []int
+	Uses       int
+	State      string
+	AgentId    string
+	ReadyAt    time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Plan shows how a calculation is split into operations. Live is false for
+// a plan built from the expression alone, it has no states and timing
+type Plan struct {
+	Nodes          []PlanNode
+	Live           bool
+	CompletedTasks int
+	TotalTasks     int
+}
+
+// Plan returns the plan of a calculation being evaluated or recently finished
+func (o *Orchestrator) Plan(expressionId int) (Plan, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	r, ok := o.runs[expressionId]
+	if !ok {
+		r, ok = o.recent[expressionId]
+	}
+	if !ok {
+		return Plan{}, false
+	}
+	plan := planOf(r.nodes)
+	plan.Live = true
+	plan.CompletedTasks, plan.TotalTasks = r.completed, r.total
+	return plan, true
+}
+
+// ExpressionPlan builds the plan of an expression that is not being evaluated
+func ExpressionPlan(node *calculate.Node) Plan {
+	dag := newDagBuilder(nil, time.Time{})
+	dag.build(node, nil, "")
+	plan := planOf(dag.nodes)
+	for i := range plan.Nodes {
+		plan.Nodes[i].State = ""
+	}
+	plan.TotalTasks = countTasks(dag.nodes)
+	return plan
+}
+
+func planOf(nodes []*taskNode) Plan {
+	plan := Plan{}
+	for _, n := range nodes {
+		planNode := PlanNode{
+			Id:         n.id,
+			Path:       n.path,
+			Op:         n.op,
+			Uses:       len(n.parents),
+			State:      n.state,
+			AgentId:    n.agentId,
+			ReadyAt:    n.readyAt,
+			StartedAt:  n.startedAt,
+			FinishedAt: n.finishedAt,
+			DependsOn:  []int{},
+		}
+		if n.state == NodeDone {
+			planNode.Value = n.value
+		}
+		for _, arg := range n.args {
+			planNode.DependsOn = append(planNode.DependsOn, arg.id)
+		}
+		plan.Nodes = append(plan.Nodes, planNode)
+	}
+	return plan
+}
+
+// AgentServer lets agents pull tasks over gRPC
+type AgentServer struct {
+	user.UnimplementedAgentServiceServer
+	orchestrator *Orchestrator
+}
+
+func (s *AgentServer) GetTask(ctx context.Context, req *user.GetTaskRequest) (*user.Task, error) {
+	task, ok := s.orchestrator.NextTask(ctx, req.AgentId)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "no task")
+	}
+	return &user.Task{
+		Id:           task.Id,
+		ExpressionId: int32(task.ExpressionId),
+		Operation:    task.Op,
+		Left:         task.Left,
+		Right:        task.Right,
+	}, nil
+}
+
+func (s *AgentServer) SubmitResult(ctx context.Context, req *user.TaskResult) (*user.SubmitResultResponse, error) {
+	err := s.orchestrator.Complete(req.Id, req.AgentId, req.Result, req.Error)
+	if errors.Is(err, ErrNotLeaseHolder) {
+		return nil, status.Errorf(codes.FailedPrecondition, "task %d: %v", req.Id, err)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "task %d: %v", req.Id, err)
+	}
+	return &user.SubmitResultResponse{}, nil
+}
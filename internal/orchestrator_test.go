@@ -0,0 +1,345 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	agent "github.com/ArteShow/Calculator/pkg/Agent"
+	calculate "github.com/ArteShow/Calculator/pkg/Calculation"
+	database "github.com/ArteShow/Calculator/pkg/Database"
+	"github.com/stretchr/testify/assert"
+)
+
+func nextTask(t *testing.T, o *Orchestrator) *Task {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	task, ok := o.NextTask(ctx, "test")
+	if !ok {
+		t.Fatal("No task available")
+	}
+	return task
+}
+
+func TestOrchestratorSplitsIntoTasks(t *testing.T) {
+	o := NewOrchestrator()
+	node, err := calculate.Parse("(1+2)*(3-4)")
+	assert.NoError(t, err)
+
+	result := make(chan float64)
+	go func() {
+		value, err := o.Evaluate(context.Background(), 1, node)
+		assert.NoError(t, err)
+		result <- value
+	}()
+
+	// Both brackets are independent and handed out before any result is back
+	first, second := nextTask(t, o), nextTask(t, o)
+	assert.ElementsMatch(t, []string{"+", "-"}, []string{first.Op, second.Op})
+	assert.NoError(t, o.Complete(first.Id, "test", first.Left+first.Right, ""))
+	if second.Op == "-" {
+		assert.NoError(t, o.Complete(second.Id, "test", second.Left-second.Right, ""))
+	} else {
+		assert.NoError(t, o.Complete(second.Id, "test", second.Left+second.Right, ""))
+	}
+
+	last := nextTask(t, o)
+	assert.Equal(t, "*", last.Op)
+	assert.ElementsMatch(t, []float64{3, -1}, []float64{last.Left, last.Right})
+	assert.NoError(t, o.Complete(last.Id, "test", -3, ""))
+	assert.Equal(t, -3.0, <-result)
+
+	assert.Error(t, o.Complete(last.Id, "test", -3, ""))
+}
+
+func TestOrchestratorWithoutTasks(t *testing.T) {
+	o := NewOrchestrator()
+
+	value, err := o.Evaluate(context.Background(), 1, calculate.NewNumber(5))
+	assert.NoError(t, err)
+	assert.Equal(t, 5.0, value)
+
+	// The unary minus is resolved by the orchestrator itself
+	value, err = o.Evaluate(context.Background(), 2, calculate.NewOperation("-", calculate.NewNumber(5)))
+	assert.NoError(t, err)
+	assert.Equal(t, -5.0, value)
+}
+
+func TestOrchestratorFailure(t *testing.T) {
+	o := NewOrchestrator()
+	node, err := calculate.Parse("1/0+2/1")
+	assert.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		_, err := o.Evaluate(context.Background(), 1, node)
+		done <- err
+	}()
+
+	task := nextTask(t, o)
+	assert.NoError(t, o.Complete(task.Id, "test", 0, "Division by zero"))
+	assert.EqualError(t, <-done, "Division by zero")
+
+	// The other task of the failed expression is dropped
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, ok := o.NextTask(ctx, "test")
+	assert.False(t, ok)
+}
+
+func TestOrchestratorTimeout(t *testing.T) {
+	o := NewOrchestrator()
+	node, err := calculate.Parse("1+2")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = o.Evaluate(ctx, 1, node)
+	assert.EqualError(t, err, "timeout: calculation aborted")
+}
+
+func TestOrchestratorAlreadyRunning(t *testing.T) {
+	o := NewOrchestrator()
+	node, err := calculate.Parse("1+2")
+	assert.NoError(t, err)
+
+	result := make(chan float64)
+	go func() {
+		value, err := o.Evaluate(context.Background(), 1, node)
+		assert.NoError(t, err)
+		result <- value
+	}()
+
+	task := nextTask(t, o)
+	_, err = o.Evaluate(context.Background(), 1, node)
+	assert.ErrorIs(t, err, ErrAlreadyRunning)
+
+	// The first run is untouched and still gets its result
+	assert.NoError(t, o.Complete(task.Id, "test", 3, ""))
+	assert.Equal(t, 3.0, <-result)
+
+	// Once finished the calculation can be evaluated again
+	value, err := o.Evaluate(context.Background(), 1, calculate.NewNumber(5))
+	assert.NoError(t, err)
+	assert.Equal(t, 5.0, value)
+}
+
+func TestOrchestratorRunsTasksInParallel(t *testing.T) {
+	o := NewOrchestrator()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	times := agent.OperationTimes{"+": 100 * time.Millisecond}
+	go runLocalAgent(ctx, o, times)
+	go runLocalAgent(ctx, o, times)
+
+	// Three additions, the two brackets at the same time
+	node, err := calculate.Parse("(1+2)+(3+4)")
+	assert.NoError(t, err)
+	start := time.Now()
+	value, err := o.Evaluate(context.Background(), 1, node)
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, value)
+	assert.Less(t, time.Since(start), 290*time.Millisecond)
+}
+
+func TestOrchestratorSlowOperationTimesOut(t *testing.T) {
+	o := NewOrchestrator()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runLocalAgent(ctx, o, agent.OperationTimes{"*": time.Second})
+
+	node, err := calculate.Parse("2*3")
+	assert.NoError(t, err)
+	evalCtx, evalCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer evalCancel()
+	_, err = o.Evaluate(evalCtx, 1, node)
+	assert.EqualError(t, err, "timeout: calculation aborted")
+}
+
+func TestOrchestratorLeaseExpires(t *testing.T) {
+	o := NewOrchestrator()
+	o.leaseTimeout = 20 * time.Millisecond
+	o.maxAttempts = 2
+	node, err := calculate.Parse("1+2")
+	assert.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		_, err := o.Evaluate(context.Background(), 1, node)
+		done <- err
+	}()
+
+	// The agent never answers, so the task is handed out again
+	first := nextTask(t, o)
+	time.Sleep(30 * time.Millisecond)
+	second := nextTask(t, o)
+	assert.Equal(t, first.Id, second.Id)
+	assert.Equal(t, 2, second.Attempts)
+
+	// Out of attempts the calculation fails
+	time.Sleep(30 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, ok := o.NextTask(ctx, "test")
+	assert.False(t, ok)
+	assert.EqualError(t, <-done, "no agent finished an operation after 2 attempts")
+}
+
+func TestOrchestratorPersistsTasks(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	// Before the restart (1+2) was computed and 3+4 was given to an agent
+	assert.NoError(t, database.InsertTask(db, database.Task{Id: 1, ExpressionId: 7, Path: "0", Operation: "+", Left: 1, Right: 2}))
+	assert.NoError(t, database.CompleteTask(db, 1, 3, ""))
+	assert.NoError(t, database.InsertTask(db, database.Task{Id: 2, ExpressionId: 7, Path: "1", Operation: "+", Left: 3, Right: 4}))
+
+	o := NewOrchestrator()
+	assert.NoError(t, o.Persist(db))
+	node, err := calculate.Parse("(1+2)*(3+4)")
+	assert.NoError(t, err)
+
+	result := make(chan float64)
+	go func() {
+		value, err := o.Evaluate(context.Background(), 7, node)
+		assert.NoError(t, err)
+		result <- value
+	}()
+
+	task := nextTask(t, o)
+	assert.Equal(t, int64(3), task.Id)
+	assert.Equal(t, []float64{3, 4}, []float64{task.Left, task.Right})
+	tasks, err := database.GetTasks(db, 7)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, database.TaskLeased, tasks[1].Status)
+	assert.Equal(t, "test", tasks[1].AgentId)
+
+	assert.NoError(t, o.Complete(task.Id, "test", 7, ""))
+	task = nextTask(t, o)
+	assert.Equal(t, "*", task.Op)
+	assert.NoError(t, o.Complete(task.Id, "test", 21, ""))
+	assert.Equal(t, 21.0, <-result)
+
+	// A finished calculation leaves no tasks behind
+	tasks, err = database.GetTasks(db, 7)
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
+func TestOrchestratorCancel(t *testing.T) {
+	o := NewOrchestrator()
+	node, err := calculate.Parse("(1+2)*(3+4)")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := o.Evaluate(ctx, 1, node)
+		done <- err
+	}()
+	task := nextTask(t, o)
+	cancel()
+	assert.EqualError(t, <-done, "calculation cancelled")
+
+	// The queued task is dropped and a late result is ignored
+	counts := func() []int {
+		queued, running := o.TaskCounts()
+		return []int{queued, running}
+	}
+	assert.Equal(t, []int{0, 1}, counts())
+	assert.NoError(t, o.Complete(task.Id, "test", 3, ""))
+	assert.Equal(t, []int{0, 0}, counts())
+}
+
+func TestOrchestratorReportsProgress(t *testing.T) {
+	o := NewOrchestrator()
+	reported := make(chan CalculationEvent, 10)
+	o.progress = func(event CalculationEvent) { reported <- event }
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runLocalAgent(ctx, o, nil)
+
+	node, err := calculate.Parse("(1+2)*3")
+	assert.NoError(t, err)
+	value, err := o.Evaluate(context.Background(), 3, node)
+	assert.NoError(t, err)
+	assert.Equal(t, 9.0, value)
+
+	first, second := <-reported, <-reported
+	assert.Equal(t, CalculationEvent{Id: 3, Type: EventProgress, Status: "running", Path: "0", Value: 3, CompletedTasks: 1, TotalTasks: 2}, first)
+	assert.Equal(t, "", second.Path)
+	assert.Equal(t, 2, second.CompletedTasks)
+}
+
+func TestOrchestratorSharedSubExpressions(t *testing.T) {
+	o := NewOrchestrator()
+	node, err := calculate.Parse("(1+2)*(1+2)-(1+2)")
+	assert.NoError(t, err)
+
+	result := make(chan float64)
+	go func() {
+		value, err := o.Evaluate(context.Background(), 1, node)
+		assert.NoError(t, err)
+		result <- value
+	}()
+
+	// (1+2) is computed once although it is used three times
+	shared := nextTask(t, o)
+	assert.Equal(t, "+", shared.Op)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, ok := o.NextTask(ctx, "test")
+	assert.False(t, ok)
+
+	plan, ok := o.Plan(1)
+	assert.True(t, ok)
+	assert.True(t, plan.Live)
+	assert.Equal(t, 3, plan.TotalTasks)
+	// -, *, +, 1 and 2
+	assert.Len(t, plan.Nodes, 5)
+	assert.Equal(t, 3, plan.Nodes[shared.node.id].Uses)
+	assert.Equal(t, NodeRunning, plan.Nodes[shared.node.id].State)
+	assert.Equal(t, "test", plan.Nodes[shared.node.id].AgentId)
+
+	assert.NoError(t, o.Complete(shared.Id, "test", 3, ""))
+	square := nextTask(t, o)
+	assert.Equal(t, "*", square.Op)
+	assert.Equal(t, []float64{3, 3}, []float64{square.Left, square.Right})
+	assert.NoError(t, o.Complete(square.Id, "test", 9, ""))
+	last := nextTask(t, o)
+	assert.Equal(t, "-", last.Op)
+	assert.NoError(t, o.Complete(last.Id, "test", 6, ""))
+	assert.Equal(t, 6.0, <-result)
+
+	// The plan is kept after the calculation is finished
+	plan, ok = o.Plan(1)
+	assert.True(t, ok)
+	assert.Equal(t, 3, plan.CompletedTasks)
+	root := plan.Nodes[0]
+	assert.Equal(t, "-", root.Op)
+	assert.Equal(t, NodeDone, root.State)
+	assert.Equal(t, 6.0, root.Value)
+	assert.Equal(t, []int{square.node.id, shared.node.id}, root.DependsOn)
+	assert.False(t, root.FinishedAt.Before(root.ReadyAt))
+
+	_, ok = o.Plan(2)
+	assert.False(t, ok)
+}
+
+func TestExpressionPlan(t *testing.T) {
+	node, err := calculate.Parse("2*2+2")
+	assert.NoError(t, err)
+
+	plan := ExpressionPlan(node)
+	assert.False(t, plan.Live)
+	assert.Equal(t, 2, plan.TotalTasks)
+	// +, * and the shared 2
+	assert.Len(t, plan.Nodes, 3)
+	assert.Equal(t, []int{1, 2}, plan.Nodes[0].DependsOn)
+	assert.Equal(t, []int{2, 2}, plan.Nodes[1].DependsOn)
+	assert.Equal(t, 3, plan.Nodes[2].Uses)
+	assert.Equal(t, "", plan.Nodes[2].State)
+}
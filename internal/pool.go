@@ -0,0 +1,237 @@
+package internal
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	config "github.com/ArteShow/Calculator/pkg/Config"
+)
+
+// ErrQueueFull is returned when no more calculations can be queued
+var ErrQueueFull = errors.New("Too many calculations are waiting, try again later")
+
+// Priority classes of calculations
+const (
+	PriorityInteractive = "interactive" // Someone waits for the single calculation
+	PriorityBatch       = "batch"       // Batches and calculations resumed after a restart
+)
+
+// WorkerPool runs calculations on a fixed number of workers. Calculations wait in a
+// bounded queue, a slot in it is reserved before the calculation is saved so a full
+// queue is noticed before anything is written.
+// Waiting calculations are taken round-robin from the users, so one user can not keep
+// all workers busy, and no user runs more than userLimit at the same time (0 for no limit).
+// Interactive calculations go first, but after interactiveWeight of them in a row a
+// waiting batch calculation gets its turn
+type WorkerPool struct {
+	workers   int
+	slots     chan struct{}
+	busy      atomic.Int64
+	processed atomic.Int64
+	rejected  atomic.Int64
+
+	mu                sync.Mutex
+	cond              *sync.Cond
+	queues            map[string]*fairQueue
+	running           map[int]int
+	userLimit         int
+	interactiveWeight int
+	interactiveStreak int
+}
+
+// PoolMetrics is a snapshot of the pool and the task queue of the orchestrator
+type PoolMetrics struct {
+	Workers               int
+	BusyWorkers           int
+	Utilisation           float64
+	QueueDepth            int
+	QueueCapacity         int
+	InteractiveQueueDepth int
+	BatchQueueDepth       int
+	Processed             int64
+	Rejected              int64
+	TaskQueueDepth        int
+	TasksRunning          int
+}
+
+// fairQueue holds the waiting jobs of one priority class by user, users
+// lists the users with waiting jobs in the order they get their next turn
+type fairQueue struct {
+	users []int
+	jobs  map[int][]func()
+	size  int
+}
+
+func newFairQueue() *fairQueue {
+	return &fairQueue{jobs: map[int][]func(){}}
+}
+
+func (q *fairQueue) push(userId int, job func()) {
+	if len(q.jobs[userId]) == 0 {
+		q.users = append(q.users, userId)
+	}
+	q.jobs[userId] = append(q.jobs[userId], job)
+	q.size++
+}
+
+// pop takes the oldest job of the first user that may start one more,
+// that user moves to the end of the line
+func (q *fairQueue) pop(canStart func(userId int) bool) (int, func(), bool) {
+	for i, userId := range q.users {
+		if !canStart(userId) {
+			continue
+		}
+		jobs := q.jobs[userId]
+		q.users = append(q.users[:i], q.users[i+1:]...)
+		if len(jobs) > 1 {
+			q.jobs[userId] = jobs[1:]
+			q.users = append(q.users, userId)
+		} else {
+			delete(q.jobs, userId)
+		}
+		q.size--
+		return userId, jobs[0], true
+	}
+	return 0, nil, false
+}
+
+func NewWorkerPool(workers int, queueSize int, userLimit int, interactiveWeight int) *WorkerPool {
+	p := &WorkerPool{
+		workers:           workers,
+		slots:             make(chan struct{}, queueSize),
+		queues:            map[string]*fairQueue{PriorityInteractive: newFairQueue(), PriorityBatch: newFairQueue()},
+		running:           map[int]int{},
+		userLimit:         userLimit,
+		interactiveWeight: interactiveWeight,
+	}
+	p.cond = sync.NewCond(&p.mu)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *WorkerPool) work() {
+	for {
+		userId, job := p.next()
+		<-p.slots
+		p.busy.Add(1)
+		job()
+		p.busy.Add(-1)
+		p.processed.Add(1)
+
+		p.mu.Lock()
+		p.running[userId]--
+		if p.running[userId] == 0 {
+			delete(p.running, userId)
+		}
+		p.mu.Unlock()
+		// The user may be below the limit again and have jobs waiting
+		p.cond.Broadcast()
+	}
+}
+
+// next waits for a job that may start now
+func (p *WorkerPool) next() (int, func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	canStart := func(userId int) bool {
+		return p.userLimit == 0 || p.running[userId] < p.userLimit
+	}
+	for {
+		order := []string{PriorityInteractive, PriorityBatch}
+		if p.interactiveStreak >= p.interactiveWeight {
+			order = []string{PriorityBatch, PriorityInteractive}
+		}
+		for _, priority := range order {
+			userId, job, ok := p.queues[priority].pop(canStart)
+			if !ok {
+				continue
+			}
+			if priority == PriorityInteractive {
+				p.interactiveStreak++
+			} else {
+				p.interactiveStreak = 0
+			}
+			p.running[userId]++
+			return userId, job
+		}
+		p.cond.Wait()
+	}
+}
+
+// Reserve takes a place in the queue, it returns ErrQueueFull if there is none
+func (p *WorkerPool) Reserve() error {
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	default:
+		p.rejected.Add(1)
+		return ErrQueueFull
+	}
+}
+
+// ReserveWait waits for a place in the queue
+func (p *WorkerPool) ReserveWait() {
+	p.slots <- struct{}{}
+}
+
+// Release gives back a reserved place that is not used
+func (p *WorkerPool) Release() {
+	<-p.slots
+}
+
+// Run queues the job of the user on a place taken with Reserve,
+// an unknown priority counts as interactive
+func (p *WorkerPool) Run(userId int, priority string, job func()) {
+	p.mu.Lock()
+	queue, ok := p.queues[priority]
+	if !ok {
+		queue = p.queues[PriorityInteractive]
+	}
+	queue.push(userId, job)
+	p.mu.Unlock()
+	p.cond.Signal()
+}
+
+func (p *WorkerPool) Metrics() PoolMetrics {
+	busy := int(p.busy.Load())
+	metrics := PoolMetrics{
+		Workers:       p.workers,
+		BusyWorkers:   busy,
+		QueueDepth:    len(p.slots),
+		QueueCapacity: cap(p.slots),
+		Processed:     p.processed.Load(),
+		Rejected:      p.rejected.Load(),
+	}
+	p.mu.Lock()
+	metrics.InteractiveQueueDepth = p.queues[PriorityInteractive].size
+	metrics.BatchQueueDepth = p.queues[PriorityBatch].size
+	p.mu.Unlock()
+	if p.workers > 0 {
+		metrics.Utilisation = float64(busy) / float64(p.workers)
+	}
+	return metrics
+}
+
+var (
+	pool     *WorkerPool
+	poolOnce sync.Once
+)
+
+// calculationPool is sized by computingPower, queueSize, userConcurrency and
+// interactiveWeight of the calculator config
+func calculationPool() *WorkerPool {
+	poolOnce.Do(func() {
+		calculatorConfig, err := config.LoadCalculatorConfig()
+		if err != nil {
+			log.Printf("❌ Failed to load calculator config, using the defaults: %v", err)
+			calculatorConfig = config.DefaultCalculatorConfig()
+		}
+		pool = NewWorkerPool(calculatorConfig.ComputingPower, calculatorConfig.QueueSize,
+			calculatorConfig.UserConcurrency, calculatorConfig.InteractiveWeight)
+	})
+	return pool
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWorkerPool(t *testing.T) {
+	p := NewWorkerPool(1, 1, 0, 1)
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	// The worker is busy with the first job, the second waits in the queue
+	assert.NoError(t, p.Reserve())
+	p.Run(1, PriorityInteractive, func() {
+		close(started)
+		<-release
+	})
+	<-started
+	assert.NoError(t, p.Reserve())
+	p.Run(1, PriorityInteractive, func() {})
+
+	assert.ErrorIs(t, p.Reserve(), ErrQueueFull)
+
+	metrics := p.Metrics()
+	assert.Equal(t, 1, metrics.Workers)
+	assert.Equal(t, 1, metrics.BusyWorkers)
+	assert.Equal(t, 1.0, metrics.Utilisation)
+	assert.Equal(t, 1, metrics.QueueDepth)
+	assert.Equal(t, 1, metrics.QueueCapacity)
+	assert.Equal(t, int64(1), metrics.Rejected)
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return p.Metrics().Processed == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0, p.Metrics().QueueDepth)
+
+	// A released place can be reserved again
+	assert.NoError(t, p.Reserve())
+	p.Release()
+	assert.NoError(t, p.Reserve())
+}
+
+// runOrder queues the jobs on a pool with one worker that is busy until all are queued
+// and returns the names of the jobs in the order they ran
+func runOrder(t *testing.T, interactiveWeight int, jobs []struct {
+	name     string
+	userId   int
+	priority string
+}) []string {
+	p := NewWorkerPool(1, len(jobs)+1, 0, interactiveWeight)
+	release := make(chan struct{})
+	assert.NoError(t, p.Reserve())
+	p.Run(0, PriorityBatch, func() { <-release })
+	assert.Eventually(t, func() bool {
+		return p.Metrics().BusyWorkers == 1
+	}, time.Second, time.Millisecond)
+
+	var mu sync.Mutex
+	order := []string{}
+	for _, job := range jobs {
+		name := job.name
+		assert.NoError(t, p.Reserve())
+		p.Run(job.userId, job.priority, func() {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+		})
+	}
+	close(release)
+	assert.Eventually(t, func() bool {
+		return p.Metrics().Processed == int64(len(jobs)+1)
+	}, time.Second, 10*time.Millisecond)
+	return order
+}
+
+func TestWorkerPool_RoundRobin(t *testing.T) {
+	order := runOrder(t, 1, []struct {
+		name     string
+		userId   int
+		priority string
+	}{
+		{"a1", 1, PriorityInteractive},
+		{"a2", 1, PriorityInteractive},
+		{"a3", 1, PriorityInteractive},
+		{"b1", 2, PriorityInteractive},
+		{"c1", 3, PriorityInteractive},
+	})
+	assert.Equal(t, []string{"a1", "b1", "c1", "a2", "a3"}, order)
+}
+
+func TestWorkerPool_Priority(t *testing.T) {
+	order := runOrder(t, 2, []struct {
+		name     string
+		userId   int
+		priority string
+	}{
+		{"b1", 1, PriorityBatch},
+		{"b2", 1, PriorityBatch},
+		{"i1", 2, PriorityInteractive},
+		{"i2", 2, PriorityInteractive},
+		{"i3", 2, PriorityInteractive},
+	})
+	// Batch calculations still get every third turn
+	assert.Equal(t, []string{"i1", "i2", "b1", "i3", "b2"}, order)
+}
+
+func TestWorkerPool_UserLimit(t *testing.T) {
+	p := NewWorkerPool(2, 10, 1, 1)
+	release := make(chan struct{})
+	defer close(release)
+
+	// The second calculation of user 1 waits although a worker is free
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, p.Reserve())
+		p.Run(1, PriorityInteractive, func() { <-release })
+	}
+	assert.Eventually(t, func() bool {
+		return p.Metrics().BusyWorkers == 1
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, p.Metrics().BusyWorkers)
+	assert.Equal(t, 1, p.Metrics().InteractiveQueueDepth)
+
+	// Another user gets the free worker
+	done := make(chan struct{})
+	assert.NoError(t, p.Reserve())
+	p.Run(2, PriorityBatch, func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the calculation of user 2 did not start")
+	}
+}
+
+func TestSubmitError(t *testing.T) {
+	assert.Equal(t, codes.ResourceExhausted, status.Code(submitError(ErrQueueFull)))
+	assert.Equal(t, codes.Internal, status.Code(submitError(errors.New("disk full"))))
+}
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"log"
+	"time"
+
+	database "github.com/ArteShow/Calculator/pkg/Database"
+)
+
+// Attempts of a database statement that fails because the database is busy
+const busyAttempts = 5
+
+// Longest wait between two attempts
+const maxRetryBackoff = 30 * time.Second
+
+// retryBackoff is the first wait before a transient failure is retried, from retryBackoffMs
+var retryBackoff = 200 * time.Millisecond
+
+// backoffAfter returns the wait after the given failed attempt: base after the first,
+// twice as long after every further one
+func backoffAfter(base time.Duration, attempt int) time.Duration {
+	backoff := base
+	for i := 1; i < attempt && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return backoff
+}
+
+// retryBusy runs fn again while it fails because the database is busy, up to busyAttempts
+// times. Other errors are returned right away
+func retryBusy(what string, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if !database.IsBusy(err) || attempt >= busyAttempts {
+			return err
+		}
+		backoff := backoffAfter(retryBackoff, attempt)
+		log.Printf("⚠️ Database busy while trying to %s, retrying in %v", what, backoff)
+		time.Sleep(backoff)
+	}
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	calculate "github.com/ArteShow/Calculator/pkg/Calculation"
+	config "github.com/ArteShow/Calculator/pkg/Config"
+	cron "github.com/ArteShow/Calculator/pkg/Cron"
+	database "github.com/ArteShow/Calculator/pkg/Database"
+	user "github.com/ArteShow/Calculator/proto"
+	"google.golang.org/grpc/status"
+)
+
+// How often the scheduler looks for schedules that are due
+const schedulerInterval = time.Second
+
+// RunScheduler starts the calculations of due schedules until the context is done
+func RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := runDueSchedules(now); err != nil {
+				log.Printf("❌ Failed to run schedules: %v", err)
+			}
+		}
+	}
+}
+
+func runDueSchedules(now time.Time) error {
+	db, err := database.OpenDatabase(config.GetDatabasePath())
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	schedules, err := database.GetDueSchedules(db, now)
+	if err != nil {
+		return err
+	}
+	for _, schedule := range schedules {
+		runSchedule(db, schedule, now)
+	}
+	return nil
+}
+
+// runSchedule saves a new calculation of the schedule and moves it to its next run.
+// A schedule that was due several times while the server was down runs only once.
+// If the queue is full the schedule stays due and is tried again
+func runSchedule(db *sql.DB, schedule database.Schedule, now time.Time) {
+	nextRunAt, err := nextScheduleRun(schedule.Cron, now)
+	if err != nil {
+		log.Printf("❌ Schedule %d stops: %v", schedule.Id, err)
+	}
+
+	expressionID, err := startScheduledCalculation(schedule, now)
+	if errors.Is(err, ErrQueueFull) {
+		log.Printf("⚠️ Schedule %d waits for room in the queue", schedule.Id)
+		return
+	}
+	errorText := ""
+	if err != nil {
+		log.Printf("❌ Schedule %d could not start a calculation: %v", schedule.Id, err)
+		errorText = err.Error()
+	} else {
+		log.Printf("⏰ Schedule %d started calculation %d", schedule.Id, expressionID)
+	}
+	err = database.RecordScheduleRun(db, schedule.Id, database.ScheduleTime(now), nextRunAt, expressionID, errorText)
+	if err != nil {
+		log.Printf("❌ Failed to save the run of schedule %d: %v", schedule.Id, err)
+	}
+}
+
+func startScheduledCalculation(schedule database.Schedule, now time.Time) (int, error) {
+	expanded, err := calculate.Expand(schedule.Expression, schedule.Variables, now)
+	if err != nil {
+		return 0, err
+	}
+	expression, node, err := parseCalculation(&user.Calculation{Expression: expanded, CallbackUrl: schedule.CallbackUrl})
+	if err != nil {
+		return 0, errors.New(status.Convert(err).Message())
+	}
+	return submitCalculation(schedule.UserId, expression, node, schedule.CallbackUrl, PriorityBatch)
+}
+
+// nextScheduleRun returns when a schedule with the cron expression runs after now,
+// as saved in nextRunAt
+func nextScheduleRun(spec string, now time.Time) (string, error) {
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		return "", err
+	}
+	next, err := schedule.Next(now.UTC())
+	if err != nil {
+		return "", err
+	}
+	return database.ScheduleTime(next), nil
+}
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	database "github.com/ArteShow/Calculator/pkg/Database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunDueSchedules(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	os.Setenv("DB_PATH", testDBPath)
+
+	now := time.Date(2025, 5, 1, 12, 0, 30, 0, time.UTC)
+	due := database.ScheduleTime(now.Add(-30 * time.Second))
+	recurring, err := database.InsertSchedule(db, database.Schedule{
+		UserId: 9, Expression: "now()-$start", Variables: map[string]float64{"start": 1746100800},
+		Cron: "*/5 * * * *", Enabled: true, NextRunAt: due,
+	})
+	assert.NoError(t, err)
+	broken, err := database.InsertSchedule(db, database.Schedule{
+		UserId: 9, Expression: "$missing+1", Cron: "@hourly", Enabled: true, NextRunAt: due,
+	})
+	assert.NoError(t, err)
+	later, err := database.InsertSchedule(db, database.Schedule{
+		UserId: 9, Expression: "1+1", Cron: "@daily", Enabled: true, NextRunAt: database.ScheduleTime(now.Add(time.Hour)),
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, runDueSchedules(now))
+
+	// The run is a new calculation of the user with now() filled in
+	schedule, err := database.GetSchedule(db, 9, recurring)
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-05-01T12:05:00Z", schedule.NextRunAt)
+	assert.Equal(t, "2025-05-01T12:00:30Z", schedule.LastRunAt)
+	assert.NotZero(t, schedule.LastCalculationId)
+	_, result, _ := waitForCalculation(t, db, schedule.LastCalculationId)
+	assert.Equal(t, 30.0, result)
+
+	schedule, err = database.GetSchedule(db, 9, broken)
+	assert.NoError(t, err)
+	assert.Equal(t, "Unknown variable $missing", schedule.LastError)
+	assert.Equal(t, "2025-05-01T13:00:00Z", schedule.NextRunAt)
+
+	schedule, err = database.GetSchedule(db, 9, later)
+	assert.NoError(t, err)
+	assert.Empty(t, schedule.LastRunAt)
+
+	// Nothing is due any more
+	assert.NoError(t, runDueSchedules(now))
+	calculations, err := database.GetCalculationsByUserId(db, 9)
+	assert.NoError(t, err)
+	assert.Len(t, calculations, 1)
+}
@@ -0,0 +1,209 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	config "github.com/ArteShow/Calculator/pkg/Config"
+	database "github.com/ArteShow/Calculator/pkg/Database"
+)
+
+// Header with the HMAC-SHA256 of the body, signed with the webhook secret of the user
+const SignatureHeader = "X-Calculator-Signature"
+
+// webhookPayload is posted to the callbackUrl when a calculation is finished
+type webhookPayload struct {
+	Id         int     `json:"id"`
+	Status     string  `json:"status"`
+	Result     float64 `json:"result"`
+	Error      string  `json:"error,omitempty"`
+	Expression string  `json:"expression"`
+	UpdatedAt  string  `json:"updatedAt"`
+}
+
+// errForbiddenAddress is returned for a webhook to a loopback, private or link-local
+// address, so users can not make the server call its own network
+var errForbiddenAddress = errors.New("callbackUrl points to a loopback, private or link-local address")
+
+// The client checks the address it connects to, so a host that resolves to
+// another address than when the URL was checked, or a redirect, can not get around it
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         dialWebhook,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+// Looks up the addresses of a host, replaced in tests
+var lookupIP = net.DefaultResolver.LookupIPAddr
+
+// isPublicIP tells if a webhook may go to the address
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// isAllowedWebhookHost tells if the host is in webhookAllowedHosts
+func isAllowedWebhookHost(host string) bool {
+	calculatorConfig, err := config.LoadCalculatorConfig()
+	if err != nil {
+		return false
+	}
+	for _, allowed := range calculatorConfig.WebhookAllowedHosts {
+		if strings.EqualFold(allowed, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCallbackUrl accepts http and https URLs whose host resolves to public addresses only,
+// or is in webhookAllowedHosts
+func checkCallbackUrl(ctx context.Context, callbackUrl string) error {
+	parsed, err := url.Parse(callbackUrl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return errors.New("use an http or https URL")
+	}
+	host := parsed.Hostname()
+	if isAllowedWebhookHost(host) {
+		return nil
+	}
+	addresses, err := lookupIP(ctx, host)
+	if err != nil {
+		return fmt.Errorf("can not resolve %s", host)
+	}
+	for _, address := range addresses {
+		if !isPublicIP(address.IP) {
+			return errForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// dialWebhook connects to the receiver, refusing addresses that are not public
+// unless the host is in webhookAllowedHosts
+func dialWebhook(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if !isAllowedWebhookHost(host) {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return errForbiddenAddress
+			}
+			return nil
+		}
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// Sign returns the value of the signature header for the body: "sha256=<hex>"
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// notifyCallback posts the finished calculation to its callbackUrl in the background
+func notifyCallback(db *sql.DB, expressionID int) {
+	calculation, err := database.GetCalculationById(db, expressionID)
+	if err != nil {
+		log.Printf("❌ Failed to load calculation %d for its webhook: %v", expressionID, err)
+		return
+	}
+	if calculation.CallbackUrl == "" {
+		return
+	}
+	secret, err := database.GetWebhookSecret(config.GetDatabasePath(), calculation.UserId)
+	if err != nil {
+		log.Printf("❌ Failed to get the webhook secret of user %d: %v", calculation.UserId, err)
+		return
+	}
+	calculatorConfig, err := config.LoadCalculatorConfig()
+	if err != nil {
+		calculatorConfig = config.DefaultCalculatorConfig()
+	}
+	body, err := json.Marshal(webhookPayload{
+		Id:         calculation.Id,
+		Status:     calculation.Status,
+		Result:     calculation.Result,
+		Error:      calculation.Error,
+		Expression: calculation.Expression,
+		UpdatedAt:  calculation.UpdatedAt,
+	})
+	if err != nil {
+		return
+	}
+
+	go func() {
+		backoff := time.Duration(calculatorConfig.WebhookBackoffMs) * time.Millisecond
+		err := deliverWebhook(calculation.CallbackUrl, secret, body, calculatorConfig.WebhookMaxAttempts, backoff)
+		if err != nil {
+			log.Printf("❌ Webhook of calculation %d failed: %v", expressionID, err)
+			return
+		}
+		log.Printf("✅ Webhook of calculation %d delivered", expressionID)
+	}()
+}
+
+// deliverWebhook posts the body until the receiver accepts it, waiting backoff before the
+// second attempt and twice as long before every further one. Client errors other than
+// 408 and 429 are not retried
+func deliverWebhook(callbackUrl string, secret string, body []byte, maxAttempts int, backoff time.Duration) error {
+	for attempt := 1; ; attempt++ {
+		retry, err := postWebhook(callbackUrl, secret, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= maxAttempts {
+			return fmt.Errorf("attempt %d: %w", attempt, err)
+		}
+		log.Printf("⚠️ Webhook attempt %d to %s failed, retrying in %v: %v", attempt, callbackUrl, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func postWebhook(callbackUrl string, secret string, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, callbackUrl, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(secret, body))
+
+	res, err := webhookClient.Do(req)
+	if errors.Is(err, errForbiddenAddress) {
+		return false, err
+	}
+	if err != nil {
+		return true, err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return false, nil
+	}
+	retry := res.StatusCode >= 500 || res.StatusCode == http.StatusRequestTimeout || res.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("receiver answered %s", res.Status)
+}
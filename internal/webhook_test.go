@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	database "github.com/ArteShow/Calculator/pkg/Database"
+	proto "github.com/ArteShow/Calculator/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+func TestSign(t *testing.T) {
+	// echo -n '{"id":1}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=03def589620c813f198fd03d7967e292b163ef0435ebf43071ce0e9519763cb7", Sign("secret", []byte(`{"id":1}`)))
+	assert.NotEqual(t, Sign("secret", []byte("a")), Sign("other", []byte("a")))
+}
+
+func TestCheckCallbackUrl(t *testing.T) {
+	defer func(original func(context.Context, string) ([]net.IPAddr, error)) { lookupIP = original }(lookupIP)
+	lookupIP = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.215.14")}}, nil
+		case "internal.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.215.14")}, {IP: net.ParseIP("10.0.0.5")}}, nil
+		}
+		return net.DefaultResolver.LookupIPAddr(ctx, host)
+	}
+
+	assert.NoError(t, checkCallbackUrl(context.Background(), "https://example.com/hook"))
+	assert.NoError(t, checkCallbackUrl(context.Background(), "http://93.184.215.14:8080/hook"))
+	assert.Error(t, checkCallbackUrl(context.Background(), "ftp://example.com"))
+	assert.Error(t, checkCallbackUrl(context.Background(), "example.com/hook"))
+
+	// The server's own network is off limits
+	for _, callbackUrl := range []string{
+		"http://localhost:8080",
+		"http://127.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://10.1.2.3/hook",
+		"http://192.168.0.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/hook",
+		"http://internal.example.com/hook",
+	} {
+		assert.ErrorIs(t, checkCallbackUrl(context.Background(), callbackUrl), errForbiddenAddress, callbackUrl)
+	}
+
+	// Unless the host is allowed
+	t.Setenv("WEBHOOK_ALLOWED_HOSTS", "localhost, internal.example.com")
+	assert.NoError(t, checkCallbackUrl(context.Background(), "http://localhost:8080"))
+	assert.NoError(t, checkCallbackUrl(context.Background(), "http://internal.example.com/hook"))
+	assert.Error(t, checkCallbackUrl(context.Background(), "http://127.0.0.1/hook"))
+}
+
+func TestDeliverWebhook_ForbiddenAddress(t *testing.T) {
+	var attempts atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+	}))
+	defer receiver.Close()
+
+	// The address is checked again when connecting, and not retried
+	err := deliverWebhook(receiver.URL, "secret", []byte(`{}`), 5, time.Second)
+	assert.ErrorIs(t, err, errForbiddenAddress)
+	assert.Equal(t, int32(0), attempts.Load())
+}
+
+func TestDeliverWebhook_Retries(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOWED_HOSTS", "127.0.0.1")
+	var attempts atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, Sign("secret", body), r.Header.Get(SignatureHeader))
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	err := deliverWebhook(receiver.URL, "secret", []byte(`{"id":1}`), 5, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), attempts.Load())
+
+	// Out of attempts
+	attempts.Store(0)
+	err = deliverWebhook(receiver.URL, "secret", []byte(`{"id":1}`), 2, time.Millisecond)
+	assert.Error(t, err)
+	assert.Equal(t, int32(2), attempts.Load())
+}
+
+func TestDeliverWebhook_ClientError(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOWED_HOSTS", "127.0.0.1")
+	var attempts atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer receiver.Close()
+
+	err := deliverWebhook(receiver.URL, "secret", []byte(`{}`), 5, time.Millisecond)
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestSendUserData_Callback(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(testDBPath)
+
+	os.Setenv("DB_PATH", testDBPath)
+	t.Setenv("WEBHOOK_ALLOWED_HOSTS", "127.0.0.1")
+
+	type delivery struct {
+		payload   webhookPayload
+		signature string
+		body      []byte
+	}
+	delivered := make(chan delivery, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var d delivery
+		d.body, _ = io.ReadAll(r.Body)
+		d.signature = r.Header.Get(SignatureHeader)
+		json.Unmarshal(d.body, &d.payload)
+		delivered <- d
+	}))
+	defer receiver.Close()
+
+	server := &Server{}
+	req := &proto.UserDataRequest{UserId: 8, Calculation: &proto.Calculation{Expression: "6*7", CallbackUrl: receiver.URL}}
+	resp, err := server.SendUserData(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", resp.Status)
+
+	select {
+	case d := <-delivered:
+		assert.Equal(t, int(resp.Id), d.payload.Id)
+		assert.Equal(t, "done", d.payload.Status)
+		assert.Equal(t, 42.0, d.payload.Result)
+		secret, err := database.GetWebhookSecret(testDBPath, 8)
+		assert.NoError(t, err)
+		assert.Equal(t, Sign(secret, d.body), d.signature)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Webhook was not delivered")
+	}
+
+	req.Calculation.CallbackUrl = "not a url"
+	_, err = server.SendUserData(context.Background(), req)
+	assert.Equal(t, codes.InvalidArgument, grpcstatus.Code(err))
+	assert.Contains(t, grpcstatus.Convert(err).Message(), "Invalid callbackUrl")
+}
@@ -0,0 +1,225 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	calculate "github.com/ArteShow/Calculator/pkg/Calculation"
+	user "github.com/ArteShow/Calculator/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// TokenMetadataKey is the metadata the agent token is sent in
+const TokenMetadataKey = "x-agent-token"
+
+// How long one GetTask call waits for the orchestrator to come up with a task
+const pollTimeout = 10 * time.Second
+
+// OperationTimes is how long each operator takes, to simulate expensive operations
+type OperationTimes map[string]time.Duration
+
+// Compute calculates a single task after waiting the time of its operation
+func Compute(ctx context.Context, task *user.Task, times OperationTimes) (float64, error) {
+	if wait := times[task.Operation]; wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	result, err, _ := calculate.Apply(task.Operation, task.Left, task.Right)
+	return result, err
+}
+
+// Worker pulls tasks from the orchestrator and posts the results back until ctx is done
+func Worker(ctx context.Context, client user.AgentServiceClient, agentId string, times OperationTimes) {
+	work(ctx, client, agentId, times, newTaskTracker())
+}
+
+func work(ctx context.Context, client user.AgentServiceClient, agentId string, times OperationTimes, tracker *taskTracker) {
+	for ctx.Err() == nil {
+		pollCtx, cancel := context.WithTimeout(ctx, pollTimeout)
+		task, err := client.GetTask(pollCtx, &user.GetTaskRequest{AgentId: agentId})
+		cancel()
+		if err != nil {
+			code := status.Code(err)
+			if code != codes.NotFound && code != codes.DeadlineExceeded && code != codes.Canceled {
+				log.Printf("❌ Failed to get a task: %v", err)
+				sleep(ctx, time.Second)
+			}
+			continue
+		}
+
+		taskCtx, done := tracker.start(ctx, task.Id)
+		result, err := Compute(taskCtx, task, times)
+		cancelled := taskCtx.Err() != nil
+		done()
+		if ctx.Err() != nil {
+			return
+		}
+		if cancelled {
+			log.Printf("⚠️ Task %d is not needed any more", task.Id)
+			continue
+		}
+		taskResult := &user.TaskResult{Id: task.Id, AgentId: agentId, Result: result}
+		if err != nil {
+			taskResult.Error = err.Error()
+		}
+		log.Printf("Task %d: %v %s %v = %v", task.Id, task.Left, task.Operation, task.Right, result)
+		if _, err := client.SubmitResult(ctx, taskResult); err != nil {
+			log.Printf("❌ Failed to submit the result of task %d: %v", task.Id, err)
+		}
+	}
+}
+
+// taskTracker knows the tasks the workers are computing, so they can be
+// reported in heartbeats and stopped when the orchestrator drops them
+type taskTracker struct {
+	mu      sync.Mutex
+	cancels map[int64]context.CancelFunc
+}
+
+func newTaskTracker() *taskTracker {
+	return &taskTracker{cancels: map[int64]context.CancelFunc{}}
+}
+
+// start returns the context to compute the task in and the function to call when it is done
+func (t *taskTracker) start(ctx context.Context, id int64) (context.Context, func()) {
+	taskCtx, cancel := context.WithCancel(ctx)
+	t.mu.Lock()
+	t.cancels[id] = cancel
+	t.mu.Unlock()
+	return taskCtx, func() {
+		t.mu.Lock()
+		delete(t.cancels, id)
+		t.mu.Unlock()
+		cancel()
+	}
+}
+
+func (t *taskTracker) ids() []int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ids := make([]int64, 0, len(t.cancels))
+	for id := range t.cancels {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (t *taskTracker) cancel(ids []int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, id := range ids {
+		if cancel, ok := t.cancels[id]; ok {
+			cancel()
+		}
+	}
+}
+
+// register announces the agent, retrying until it works or ctx is done.
+// It returns false if the orchestrator does not know about agent registration
+func register(ctx context.Context, client user.AgentServiceClient, agentId string, capacity int) (time.Duration, bool) {
+	for ctx.Err() == nil {
+		response, err := client.RegisterAgent(ctx, &user.RegisterAgentRequest{AgentId: agentId, Capacity: int32(capacity)})
+		if err == nil {
+			return time.Duration(response.HeartbeatIntervalMs) * time.Millisecond, true
+		}
+		if status.Code(err) == codes.Unimplemented {
+			log.Printf("⚠️ Orchestrator does not track agents, running without heartbeats")
+			return 0, false
+		}
+		log.Printf("❌ Failed to register agent: %v", err)
+		sleep(ctx, time.Second)
+	}
+	return 0, false
+}
+
+// heartbeats registers the agent and keeps telling the orchestrator it is alive and
+// which tasks it computes, stopping the tasks the orchestrator does not need any more
+func heartbeats(ctx context.Context, client user.AgentServiceClient, agentId string, capacity int, tracker *taskTracker) {
+	interval, ok := register(ctx, client, agentId, capacity)
+	for ok {
+		sleep(ctx, interval)
+		if ctx.Err() != nil {
+			return
+		}
+		response, err := client.Heartbeat(ctx, &user.HeartbeatRequest{AgentId: agentId, TaskIds: tracker.ids()})
+		if status.Code(err) == codes.NotFound {
+			// The orchestrator restarted or forgot about the agent
+			interval, ok = register(ctx, client, agentId, capacity)
+			continue
+		}
+		if err != nil {
+			log.Printf("❌ Failed to send heartbeat: %v", err)
+			continue
+		}
+		tracker.cancel(response.CancelledTaskIds)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+}
+
+// tokenCredentials sends the agent token with every call
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{TokenMetadataKey: string(t)}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// WithToken makes the agent authenticate with the agent token of the orchestrator
+func WithToken(token string) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(tokenCredentials(token))
+}
+
+// Run connects to the orchestrator, registers the agent with a capacity of
+// computingPower and starts that many workers. Without dial options the
+// connection is plaintext
+func Run(ctx context.Context, address string, computingPower int, times OperationTimes, options ...grpc.DialOption) error {
+	if len(options) == 0 {
+		options = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	conn, err := grpc.Dial(address, options...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to orchestrator: %v", err)
+	}
+	defer conn.Close()
+
+	client := user.NewAgentServiceClient(conn)
+	hostname, _ := os.Hostname()
+	agentId := fmt.Sprintf("%s-%d", hostname, os.Getpid())
+
+	log.Printf("Agent %s started %d workers for %s", agentId, computingPower, address)
+	tracker := newTaskTracker()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		heartbeats(ctx, client, agentId, computingPower, tracker)
+	}()
+	for i := 0; i < computingPower; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			work(ctx, client, agentId, times, tracker)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,216 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	user "github.com/ArteShow/Calculator/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeOrchestrator hands out the given tasks once and records the results
+type fakeOrchestrator struct {
+	user.UnimplementedAgentServiceServer
+	mu      sync.Mutex
+	tasks   []*user.Task
+	results chan *user.TaskResult
+}
+
+func (f *fakeOrchestrator) GetTask(ctx context.Context, req *user.GetTaskRequest) (*user.Task, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.tasks) == 0 {
+		return nil, status.Error(codes.NotFound, "no task")
+	}
+	task := f.tasks[0]
+	f.tasks = f.tasks[1:]
+	return task, nil
+}
+
+func (f *fakeOrchestrator) SubmitResult(ctx context.Context, req *user.TaskResult) (*user.SubmitResultResponse, error) {
+	f.results <- req
+	return &user.SubmitResultResponse{}, nil
+}
+
+func TestCompute(t *testing.T) {
+	result, err := Compute(context.Background(), &user.Task{Operation: "*", Left: 3, Right: 4}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 12.0, result)
+
+	_, err = Compute(context.Background(), &user.Task{Operation: "/", Left: 1, Right: 0}, nil)
+	assert.EqualError(t, err, "Division by zero")
+}
+
+func TestCompute_OperationTimes(t *testing.T) {
+	times := OperationTimes{"+": 50 * time.Millisecond}
+
+	start := time.Now()
+	result, err := Compute(context.Background(), &user.Task{Operation: "+", Left: 1, Right: 2}, times)
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, result)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	start = time.Now()
+	_, err = Compute(context.Background(), &user.Task{Operation: "*", Left: 1, Right: 2}, times)
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Compute(ctx, &user.Task{Operation: "+", Left: 1, Right: 2}, times)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRun(t *testing.T) {
+	fake := &fakeOrchestrator{
+		tasks: []*user.Task{
+			{Id: 1, Operation: "+", Left: 2, Right: 3},
+			{Id: 2, Operation: "/", Left: 2, Right: 0},
+		},
+		results: make(chan *user.TaskResult, 2),
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := grpc.NewServer()
+	user.RegisterAgentServiceServer(server, fake)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- Run(ctx, listener.Addr().String(), 2, nil) }()
+
+	results := map[int64]*user.TaskResult{}
+	for len(results) < 2 {
+		select {
+		case result := <-fake.results:
+			results[result.Id] = result
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the results")
+		}
+	}
+	assert.Equal(t, 5.0, results[1].Result)
+	assert.Empty(t, results[1].Error)
+	assert.NotEmpty(t, results[1].AgentId)
+	assert.Equal(t, "Division by zero", results[2].Error)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+// trackingOrchestrator also takes registrations and drops task 1 in the first
+// heartbeat that reports it
+type trackingOrchestrator struct {
+	fakeOrchestrator
+	registered chan *user.RegisterAgentRequest
+	dropped    chan int64
+}
+
+func (f *trackingOrchestrator) RegisterAgent(ctx context.Context, req *user.RegisterAgentRequest) (*user.RegisterAgentResponse, error) {
+	f.registered <- req
+	return &user.RegisterAgentResponse{HeartbeatIntervalMs: 10}, nil
+}
+
+func (f *trackingOrchestrator) Heartbeat(ctx context.Context, req *user.HeartbeatRequest) (*user.HeartbeatResponse, error) {
+	for _, id := range req.TaskIds {
+		if id == 1 {
+			select {
+			case f.dropped <- id:
+			default:
+			}
+			return &user.HeartbeatResponse{CancelledTaskIds: []int64{id}}, nil
+		}
+	}
+	return &user.HeartbeatResponse{}, nil
+}
+
+func TestRun_Heartbeats(t *testing.T) {
+	fake := &trackingOrchestrator{
+		fakeOrchestrator: fakeOrchestrator{
+			tasks: []*user.Task{
+				{Id: 1, Operation: "*", Left: 2, Right: 3},
+				{Id: 2, Operation: "+", Left: 2, Right: 3},
+			},
+			results: make(chan *user.TaskResult, 2),
+		},
+		registered: make(chan *user.RegisterAgentRequest, 1),
+		dropped:    make(chan int64, 1),
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := grpc.NewServer()
+	user.RegisterAgentServiceServer(server, fake)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	// Multiplications take long enough to be reported in a heartbeat
+	times := OperationTimes{"*": 5 * time.Second}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- Run(ctx, listener.Addr().String(), 1, times) }()
+
+	select {
+	case req := <-fake.registered:
+		assert.Equal(t, int32(1), req.Capacity)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the registration")
+	}
+
+	// The dropped task is not submitted, the worker goes on with the next one
+	select {
+	case result := <-fake.results:
+		assert.Equal(t, int64(2), result.Id)
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timed out waiting for the result")
+	}
+	assert.Equal(t, int64(1), <-fake.dropped)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestRun_WithToken(t *testing.T) {
+	fake := &fakeOrchestrator{
+		tasks:   []*user.Task{{Id: 1, Operation: "+", Left: 2, Right: 3}},
+		results: make(chan *user.TaskResult, 1),
+	}
+	tokens := make(chan []string, 10)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		select {
+		case tokens <- md.Get(TokenMetadataKey):
+		default:
+		}
+		return handler(ctx, req)
+	}))
+	user.RegisterAgentServiceServer(server, fake)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	insecureOption := grpc.WithTransportCredentials(insecure.NewCredentials())
+	go func() { done <- Run(ctx, listener.Addr().String(), 1, nil, insecureOption, WithToken("agent-secret")) }()
+
+	select {
+	case <-fake.results:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the result")
+	}
+	cancel()
+	assert.NoError(t, <-done)
+	close(tokens)
+	for sent := range tokens {
+		assert.Equal(t, []string{"agent-secret"}, sent)
+	}
+}
@@ -0,0 +1,316 @@
+package calculate
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// OpNumber marks a leaf node holding a number
+const OpNumber = "num"
+
+// Node is one element of a parsed expression: a number or an operator with its operands.
+// Binary operators have two args, the unary minus "-" has one.
+type Node struct {
+	Op    string
+	Value float64
+	Args  []*Node
+}
+
+// nodeJSON is the wire format of a Node: {"op":"+","args":[...]} for operators
+// and {"op":"num","value":2} for numbers
+type nodeJSON struct {
+	Op    string   `json:"op"`
+	Value *float64 `json:"value,omitempty"`
+	Args  []*Node  `json:"args,omitempty"`
+}
+
+func (n *Node) MarshalJSON() ([]byte, error) {
+	out := nodeJSON{Op: n.Op, Args: n.Args}
+	if n.Op == OpNumber {
+		out.Value = &n.Value
+	}
+	return json.Marshal(out)
+}
+
+func (n *Node) UnmarshalJSON(data []byte) error {
+	var in nodeJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in.Op == OpNumber && in.Value == nil {
+		return errors.New("A number needs a value")
+	}
+	*n = Node{Op: in.Op, Args: in.Args}
+	if in.Value != nil {
+		n.Value = *in.Value
+	}
+	return nil
+}
+
+func NewNumber(value float64) *Node {
+	return &Node{Op: OpNumber, Value: value}
+}
+
+func NewOperation(op string, args ...*Node) *Node {
+	return &Node{Op: op, Args: args}
+}
+
+func isOperator(op string) bool {
+	return op == "+" || op == "-" || op == "*" || op == "/"
+}
+
+// Check that every node has a known operator and the right number of operands
+func (n *Node) Validate() error {
+	if n == nil {
+		return errors.New("Empty expression")
+	}
+	if n.Op == OpNumber {
+		if len(n.Args) != 0 {
+			return errors.New("A number can not have operands")
+		}
+		return nil
+	}
+	if !isOperator(n.Op) {
+		return errors.New("Unknown operator " + n.Op)
+	}
+	if len(n.Args) != 2 && !(n.Op == "-" && len(n.Args) == 1) {
+		return errors.New("Wrong number of operands for " + n.Op)
+	}
+	for _, arg := range n.Args {
+		if err := arg.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String prints the node as a fully bracketed infix expression without spaces,
+// so it can be stored and fed back into Calc
+func (n *Node) String() string {
+	if n == nil {
+		return ""
+	}
+	if n.Op == OpNumber && n.Value < 0 {
+		return "(" + formatNumber(n.Value) + ")"
+	}
+	if n.Op == OpNumber {
+		return formatNumber(n.Value)
+	}
+	if len(n.Args) == 1 {
+		return "(" + n.Op + n.Args[0].String() + ")"
+	}
+	return "(" + n.Args[0].String() + n.Op + n.Args[1].String() + ")"
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// Evaluate the tree, returning the same status codes as Calc
+func Evaluate(n *Node) (float64, error, int) {
+	if err := n.Validate(); err != nil {
+		return 0.0, err, 422
+	}
+	return evaluate(n)
+}
+
+func evaluate(n *Node) (float64, error, int) {
+	if n.Op == OpNumber {
+		return n.Value, nil, 200
+	}
+	left, err, code := evaluate(n.Args[0])
+	if err != nil {
+		return 0.0, err, code
+	}
+	if len(n.Args) == 1 {
+		return -left, nil, 200
+	}
+	right, err, code := evaluate(n.Args[1])
+	if err != nil {
+		return 0.0, err, code
+	}
+	return Apply(n.Op, left, right)
+}
+
+// Apply one binary operator
+func Apply(op string, left, right float64) (float64, error, int) {
+	switch op {
+	case "+":
+		return left + right, nil, 200
+	case "-":
+		return left - right, nil, 200
+	case "*":
+		return left * right, nil, 200
+	case "/":
+		if right == 0 {
+			return 0.0, errors.New("Division by zero"), 422
+		}
+		return left / right, nil, 200
+	}
+	return 0.0, errors.New("Unknown operator " + op), 422
+}
+
+// Infix parser: + and - bind weaker than * and /, brackets group,
+// a bracket right after a number or another bracket multiplies: (2+2)(2+2)
+type infixParser struct {
+	tokens []string
+	// 1-based character position of every token, end is the one after the last character
+	positions []int
+	end       int
+	pos       int
+}
+
+// Parse an infix expression into a tree
+func Parse(expression string) (*Node, error) {
+	return parseInfix(expression, DefaultLocale)
+}
+
+func parseInfix(expression string, locale Locale) (*Node, error) {
+	tokens, positions, err := tokenize(expression, locale)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, syntaxError(0, "Empty expression")
+	}
+	p := &infixParser{tokens: tokens, positions: positions, end: len([]rune(expression)) + 1}
+	node, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.errorf("Unexpected " + p.tokens[p.pos])
+	}
+	return node, nil
+}
+
+// Split the expression into numbers, operators and brackets and return the position
+// of each. Numbers are read in the given locale and stored in plain form
+func tokenize(expression string, locale Locale) ([]string, []int, error) {
+	tokens := []string{}
+	positions := []int{}
+	letters := []rune(expression)
+	for i := 0; i < len(letters); i++ {
+		letter := letters[i]
+		if unicode.IsDigit(letter) || letter == locale.Decimal {
+			number, next := locale.scanNumber(letters, i)
+			value, err := locale.ParseNumber(number)
+			if err != nil {
+				return nil, nil, syntaxError(i+1, err.Error())
+			}
+			tokens = append(tokens, formatNumber(value))
+			positions = append(positions, i+1)
+			i = next - 1
+			continue
+		}
+		switch {
+		case letter == ' ':
+		case strings.ContainsRune("+-*/()", letter):
+			tokens = append(tokens, string(letter))
+			positions = append(positions, i+1)
+		case unicode.IsLetter(letter):
+			return nil, nil, syntaxError(i+1, "There is a letter in the expression")
+		default:
+			return nil, nil, syntaxError(i+1, "Unexpected "+string(letter))
+		}
+	}
+	return tokens, positions, nil
+}
+
+func (p *infixParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// errorf is a SyntaxError at the current token
+func (p *infixParser) errorf(message string) error {
+	if p.pos < len(p.positions) {
+		return syntaxError(p.positions[p.pos], message)
+	}
+	return syntaxError(p.end, message)
+}
+
+func (p *infixParser) parseSum() (*Node, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "+" || p.peek() == "-" {
+		op := p.tokens[p.pos]
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = NewOperation(op, left, right)
+	}
+	return left, nil
+}
+
+func (p *infixParser) parseProduct() (*Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "*" || p.peek() == "/" || p.peek() == "(" {
+		op := "*"
+		if p.peek() != "(" {
+			op = p.tokens[p.pos]
+			p.pos++
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = NewOperation(op, left, right)
+	}
+	return left, nil
+}
+
+func (p *infixParser) parseUnary() (*Node, error) {
+	switch p.peek() {
+	case "-":
+		p.pos++
+		arg, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return NewOperation("-", arg), nil
+	case "+":
+		p.pos++
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+func (p *infixParser) parsePrimary() (*Node, error) {
+	token := p.peek()
+	switch token {
+	case "":
+		return nil, p.errorf("Unexpected end of expression")
+	case "(":
+		p.pos++
+		node, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, p.errorf("Error by counting the brackets")
+		}
+		p.pos++
+		return node, nil
+	case ")", "+", "-", "*", "/":
+		return nil, p.errorf("Unexpected " + token)
+	}
+	value, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return nil, p.errorf("Invalid number " + token)
+	}
+	p.pos++
+	return NewNumber(value), nil
+}
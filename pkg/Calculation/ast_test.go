@@ -0,0 +1,90 @@
+package calculate
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_Precedence(t *testing.T) {
+	node, err := Parse("2+3*4")
+	assert.NoError(t, err)
+	assert.Equal(t, NewOperation("+", NewNumber(2), NewOperation("*", NewNumber(3), NewNumber(4))), node)
+}
+
+func TestParse_ImplicitMultiplication(t *testing.T) {
+	node, err := Parse("(2+2)(2+2)")
+	assert.NoError(t, err)
+	result, err, code := Evaluate(node)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, 16.0, result)
+}
+
+func TestParse_UnaryMinus(t *testing.T) {
+	node, err := Parse("2*-3")
+	assert.NoError(t, err)
+	assert.Equal(t, "(2*(-3))", node.String())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expression := range []string{"", "2+", "(2+3", "2+3)", "3 + a"} {
+		_, err := Parse(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestEvaluate_DivideByZero(t *testing.T) {
+	_, err, code := Evaluate(NewOperation("/", NewNumber(1), NewNumber(0)))
+	assert.Error(t, err)
+	assert.Equal(t, 422, code)
+}
+
+func TestEvaluate_InvalidNode(t *testing.T) {
+	_, err, code := Evaluate(NewOperation("+", NewNumber(1)))
+	assert.Error(t, err)
+	assert.Equal(t, 422, code)
+}
+
+func TestNodeString_RoundTrip(t *testing.T) {
+	node, err := Parse("1.5-(2-3)/4")
+	assert.NoError(t, err)
+	again, err := Parse(node.String())
+	assert.NoError(t, err)
+	assert.Equal(t, node, again)
+}
+
+// Trees sent as JSON are stored and computed in their String form, which must read back
+// as the same expression with the same result. A negative number comes back as a negation
+func TestNodeString_RoundTripBuilt(t *testing.T) {
+	for _, node := range []*Node{
+		NewOperation("+", NewNumber(1.234), NewNumber(2.5)),
+		NewOperation("*", NewNumber(-0.5), NewOperation("-", NewNumber(1e-7), NewNumber(1e21))),
+		NewOperation("/", NewNumber(1234567.125), NewNumber(-3)),
+	} {
+		again, err := Parse(node.String())
+		assert.NoError(t, err, node.String())
+		assert.Equal(t, node.String(), again.String())
+		expected, _, _ := Evaluate(node)
+		result, _, _ := Evaluate(again)
+		assert.Equal(t, expected, result, node.String())
+	}
+}
+
+func TestNodeJSON(t *testing.T) {
+	node, err := Parse("-(0+2)*3")
+	assert.NoError(t, err)
+	data, err := json.Marshal(node)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"op":"*","args":[{"op":"-","args":[{"op":"+","args":[{"op":"num","value":0},{"op":"num","value":2}]}]},{"op":"num","value":3}]}`, string(data))
+
+	var decoded Node
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, node, &decoded)
+}
+
+func TestNodeJSON_MissingValue(t *testing.T) {
+	var decoded Node
+	assert.Error(t, json.Unmarshal([]byte(`{"op":"+","args":[{"op":"num"},{"op":"num","value":1}]}`), &decoded))
+}
@@ -50,14 +50,18 @@ func CalcBasic(expression string) (float64, error, int) {
 	MathOperators := make([]string, 0)
 	Numbers := make([]float64, 0)
 	var num string
-	expression = strings.ReplaceAll(expression, ",", ".")
+	expression, err = DefaultLocale.Normalize(expression)
+	if err != nil {
+		Logger.Println("[ERROR]:", err)
+		return 0.0, err, 422
+	}
 	for _, letter := range expression {
 		if letter == ' ' {
 			continue
 		}
 		RuneWithout += string(letter)
 	}
-	Logger.Println("Removed all spaces and replaced all commas with periods")
+	Logger.Println("Removed all spaces and normalized the numbers")
 
 	String := string(RuneWithout)
 	if len(String) == 0 {
@@ -169,7 +173,11 @@ func Calc(expression string) (float64, error, int) {
 	Logger.Println("/////////////Check for Brackets///////////////")
 
 	// Preparing the expression
-	expression = strings.ReplaceAll(expression, ",", ".")
+	expression, err = DefaultLocale.Normalize(expression)
+	if err != nil {
+		Logger.Println("[ERROR]:", err)
+		return 0.0, err, 422
+	}
 	var ResultString string
 	stack := []string{}
 
@@ -180,7 +188,7 @@ func Calc(expression string) (float64, error, int) {
 		}
 		ResultString += string(letter)
 	}
-	Logger.Println("Replaced all commas with periods")
+	Logger.Println("Removed all spaces and normalized the numbers")
 
 	// Checking the brackets
 	ResultString = "(" + ResultString + ")"
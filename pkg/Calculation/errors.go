@@ -0,0 +1,29 @@
+package calculate
+
+import "errors"
+
+// SyntaxError is returned for an expression that can not be parsed. Position is
+// the 1-based character where the problem was found, 0 if it is not one place,
+// e.g. for an empty expression
+type SyntaxError struct {
+	Position int
+	Message  string
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Message
+}
+
+func syntaxError(position int, message string) error {
+	return &SyntaxError{Position: position, Message: message}
+}
+
+// ErrorPosition returns where the expression is wrong, 0 if err is not a SyntaxError
+// or does not point at one place
+func ErrorPosition(err error) int {
+	var syntax *SyntaxError
+	if errors.As(err, &syntax) {
+		return syntax.Position
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+package calculate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorPosition_Infix(t *testing.T) {
+	cases := map[string]int{
+		"":        0,
+		"1+":      3,
+		"2*(3+4":  7,
+		"1 + x":   5,
+		"12 $ 3":  4,
+		"(1+2))":  6,
+		"1,5+2":   1,
+		"4 * * 2": 5,
+	}
+	for expression, position := range cases {
+		_, err := Parse(expression)
+		assert.Error(t, err, expression)
+		assert.Equal(t, position, ErrorPosition(err), expression)
+	}
+}
+
+func TestErrorPosition_Notations(t *testing.T) {
+	_, err := ParseRPN("1 2 + x")
+	assert.Equal(t, 7, ErrorPosition(err))
+	_, err = ParseRPN("1  +")
+	assert.Equal(t, 4, ErrorPosition(err))
+	_, err = ParsePrefix("+ 1")
+	assert.Equal(t, 4, ErrorPosition(err))
+	_, err = ParsePrefix("+ 1 2 3")
+	assert.Equal(t, 7, ErrorPosition(err))
+
+	locale, _ := GetLocale("de-DE")
+	_, err = locale.Normalize("2 + 1,5,5")
+	assert.Equal(t, 5, ErrorPosition(err))
+}
+
+func TestErrorPosition_NotSyntaxError(t *testing.T) {
+	assert.Equal(t, 0, ErrorPosition(errors.New("Division by zero")))
+	_, err := Parse("1+")
+	assert.EqualError(t, err, "Unexpected end of expression")
+}
@@ -0,0 +1,137 @@
+package calculate
+
+import (
+	"errors"
+	"strings"
+)
+
+// Output formats for Format
+const (
+	FormatLatex        = "latex"
+	FormatMathML       = "mathml"
+	FormatUnicode      = "unicode"
+	FormatInfixMinimal = "infix-minimal"
+)
+
+// Binding strength used to decide where brackets are needed
+const (
+	precedenceSum = iota + 1
+	precedenceProduct
+	precedenceUnary
+	precedenceNumber
+)
+
+// printer holds the pieces that differ between the output formats
+type printer struct {
+	number   func(string) string
+	operator func(string) string
+	minus    string
+	brackets func(string) string
+	join     func(...string) string
+	// fraction is set when division is typeset as a fraction, its operands then need no brackets
+	fraction func(top, bottom string) string
+	wrap     func(string) string
+}
+
+var printers = map[string]printer{
+	FormatInfixMinimal: {
+		number:   func(s string) string { return s },
+		operator: func(op string) string { return op },
+		minus:    "-",
+		brackets: func(s string) string { return "(" + s + ")" },
+		join:     func(parts ...string) string { return strings.Join(parts, "") },
+		wrap:     func(s string) string { return s },
+	},
+	FormatUnicode: {
+		number: func(s string) string { return strings.ReplaceAll(s, "-", "−") },
+		operator: func(op string) string {
+			return map[string]string{"+": " + ", "-": " − ", "*": " × ", "/": " ÷ "}[op]
+		},
+		minus:    "−",
+		brackets: func(s string) string { return "(" + s + ")" },
+		join:     func(parts ...string) string { return strings.Join(parts, "") },
+		wrap:     func(s string) string { return s },
+	},
+	FormatLatex: {
+		number: func(s string) string { return s },
+		operator: func(op string) string {
+			return map[string]string{"+": " + ", "-": " - ", "*": " \\cdot "}[op]
+		},
+		minus:    "-",
+		brackets: func(s string) string { return "\\left(" + s + "\\right)" },
+		join:     func(parts ...string) string { return strings.Join(parts, "") },
+		fraction: func(top, bottom string) string { return "\\frac{" + top + "}{" + bottom + "}" },
+		wrap:     func(s string) string { return s },
+	},
+	FormatMathML: {
+		number: func(s string) string { return "<mn>" + s + "</mn>" },
+		operator: func(op string) string {
+			return "<mo>" + map[string]string{"+": "+", "-": "&#x2212;", "*": "&#xD7;"}[op] + "</mo>"
+		},
+		minus:    "<mo>&#x2212;</mo>",
+		brackets: func(s string) string { return "<mo>(</mo>" + s + "<mo>)</mo>" },
+		join:     func(parts ...string) string { return "<mrow>" + strings.Join(parts, "") + "</mrow>" },
+		fraction: func(top, bottom string) string { return "<mfrac>" + top + bottom + "</mfrac>" },
+		wrap: func(s string) string {
+			return `<math xmlns="http://www.w3.org/1998/Math/MathML">` + s + "</math>"
+		},
+	},
+}
+
+func IsFormat(format string) bool {
+	_, ok := printers[format]
+	return ok
+}
+
+// Format prints the tree in the given format with as few brackets as possible
+func Format(node *Node, format string) (string, error) {
+	p, ok := printers[format]
+	if !ok {
+		return "", errors.New("Unknown format " + format)
+	}
+	if err := node.Validate(); err != nil {
+		return "", err
+	}
+	return p.wrap(p.print(node)), nil
+}
+
+func precedence(n *Node) int {
+	switch {
+	case n.Op == OpNumber && n.Value < 0:
+		return precedenceUnary
+	case n.Op == OpNumber:
+		return precedenceNumber
+	case len(n.Args) == 1:
+		return precedenceUnary
+	case n.Op == "+" || n.Op == "-":
+		return precedenceSum
+	}
+	return precedenceProduct
+}
+
+func (p printer) print(n *Node) string {
+	if n.Op == OpNumber {
+		return p.number(formatNumber(n.Value))
+	}
+	if len(n.Args) == 1 {
+		return p.join(p.minus, p.operand(n.Args[0], precedenceUnary, true, false))
+	}
+	if n.Op == "/" && p.fraction != nil {
+		return p.fraction(p.print(n.Args[0]), p.print(n.Args[1]))
+	}
+	left := p.operand(n.Args[0], precedence(n), false, false)
+	// a-(b-c) and a/(b/c) keep their brackets, a+(b+c) does not need them
+	right := p.operand(n.Args[1], precedence(n), true, n.Op == "-" || n.Op == "/")
+	return p.join(left, p.operator(n.Op), right)
+}
+
+// Print an operand, bracketing it when it binds weaker than its parent.
+// A negative operand on the right is bracketed too, so 2*-3 prints as 2*(-3)
+func (p printer) operand(n *Node, parent int, right bool, strict bool) string {
+	own := precedence(n)
+	text := p.print(n)
+	if own < parent || (strict && own == parent) || (right && own == precedenceUnary) {
+		return p.brackets(text)
+	}
+	return text
+}
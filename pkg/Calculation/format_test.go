@@ -0,0 +1,52 @@
+package calculate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func formatExpression(t *testing.T, expression string, format string) string {
+	node, err := Parse(expression)
+	assert.NoError(t, err)
+	result, err := Format(node, format)
+	assert.NoError(t, err)
+	return result
+}
+
+func TestFormat_InfixMinimal(t *testing.T) {
+	cases := map[string]string{
+		"((1+2))+(3*4)": "1+2+3*4",
+		"(1+2)*3":       "(1+2)*3",
+		"1-(2-3)":       "1-(2-3)",
+		"1+(2+3)":       "1+2+3",
+		"8/(4/2)":       "8/(4/2)",
+		"(8/4)/2":       "8/4/2",
+		"2*-3":          "2*(-3)",
+		"-(1+2)":        "-(1+2)",
+		"--2":           "-(-2)",
+	}
+	for expression, expected := range cases {
+		assert.Equal(t, expected, formatExpression(t, expression, FormatInfixMinimal), expression)
+	}
+}
+
+func TestFormat_Unicode(t *testing.T) {
+	assert.Equal(t, "(1 + 2) × 3 ÷ (−4)", formatExpression(t, "(1+2)*3/-4", FormatUnicode))
+}
+
+func TestFormat_Latex(t *testing.T) {
+	assert.Equal(t, `\left(1 + 2\right) \cdot \frac{3}{4 - 1}`, formatExpression(t, "(1+2)*(3/(4-1))", FormatLatex))
+}
+
+func TestFormat_MathML(t *testing.T) {
+	assert.Equal(t,
+		`<math xmlns="http://www.w3.org/1998/Math/MathML"><mrow><mn>1</mn><mo>+</mo><mfrac><mn>2</mn><mn>3</mn></mfrac></mrow></math>`,
+		formatExpression(t, "1+2/3", FormatMathML))
+}
+
+func TestFormat_Unknown(t *testing.T) {
+	_, err := Format(NewNumber(1), "roman")
+	assert.Error(t, err)
+	assert.False(t, IsFormat("roman"))
+}
@@ -0,0 +1,192 @@
+package calculate
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Locale describes how numbers are written: 1,000.5 in en-US is 1.000,5 in de-DE
+type Locale struct {
+	Name    string
+	Decimal rune
+	Group   rune
+}
+
+// DefaultLocale is used when neither the request nor the user picked one
+var DefaultLocale = Locale{Name: "en-US", Decimal: '.', Group: ','}
+
+var locales = []Locale{
+	DefaultLocale,
+	{Name: "en-GB", Decimal: '.', Group: ','},
+	{Name: "de-DE", Decimal: ',', Group: '.'},
+	{Name: "de-CH", Decimal: '.', Group: '\''},
+	{Name: "fr-FR", Decimal: ',', Group: ' '},
+	{Name: "ru-RU", Decimal: ',', Group: ' '},
+}
+
+// GetLocale finds a supported locale by its name ("de-DE") or only its language ("de")
+func GetLocale(name string) (Locale, bool) {
+	name = strings.TrimSpace(strings.ReplaceAll(name, "_", "-"))
+	if name == "" {
+		return Locale{}, false
+	}
+	for _, locale := range locales {
+		if strings.EqualFold(locale.Name, name) {
+			return locale, true
+		}
+	}
+	language := strings.SplitN(name, "-", 2)[0]
+	for _, locale := range locales {
+		if strings.EqualFold(strings.SplitN(locale.Name, "-", 2)[0], language) {
+			return locale, true
+		}
+	}
+	return Locale{}, false
+}
+
+// LocaleFromHeader picks the best supported locale from an Accept-Language header,
+// e.g. "de-DE,de;q=0.9,en;q=0.8"
+func LocaleFromHeader(header string) (Locale, bool) {
+	type candidate struct {
+		name    string
+		quality float64
+	}
+	candidates := []candidate{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		quality := 1.0
+		for _, field := range fields[1:] {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "q=") {
+				if q, err := strconv.ParseFloat(field[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if fields[0] != "" && fields[0] != "*" && quality > 0 {
+			candidates = append(candidates, candidate{fields[0], quality})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+	for _, c := range candidates {
+		if locale, ok := GetLocale(c.name); ok {
+			return locale, true
+		}
+	}
+	return Locale{}, false
+}
+
+func (l Locale) isNumberRune(letter rune) bool {
+	return unicode.IsDigit(letter) || letter == l.Decimal || letter == l.Group
+}
+
+// ParseNumber reads a number written in this locale. Group separators are optional
+// but must split the integer part into groups of three digits, so "1,5" is rejected
+// in en-US instead of being read as 15
+func (l Locale) ParseNumber(number string) (float64, error) {
+	if number == "" {
+		return 0.0, errors.New("Invalid number")
+	}
+	for _, letter := range number {
+		if !l.isNumberRune(letter) {
+			return 0.0, errors.New("Invalid number " + number)
+		}
+	}
+	integer, fraction, hasFraction := strings.Cut(number, string(l.Decimal))
+	if strings.ContainsRune(fraction, l.Group) {
+		return 0.0, errors.New("Invalid number " + number)
+	}
+	if strings.ContainsRune(integer, l.Group) {
+		groups := strings.Split(integer, string(l.Group))
+		if len(groups[0]) == 0 || len(groups[0]) > 3 {
+			return 0.0, errors.New("Invalid number " + number)
+		}
+		for _, group := range groups[1:] {
+			if len(group) != 3 {
+				return 0.0, errors.New("Invalid number " + number)
+			}
+		}
+		integer = strings.Join(groups, "")
+	}
+	plain := integer
+	if hasFraction {
+		plain += "." + fraction
+	}
+	value, err := strconv.ParseFloat(plain, 64)
+	if err != nil {
+		return 0.0, errors.New("Invalid number " + number)
+	}
+	return value, nil
+}
+
+// FormatNumber writes the value with this locale's separators, e.g. 1234.5 as 1.234,5 in de-DE
+func (l Locale) FormatNumber(value float64) string {
+	return l.localize(formatNumber(value))
+}
+
+// localize adds group separators to a plain number like "-1234.5"
+func (l Locale) localize(plain string) string {
+	sign := ""
+	if strings.HasPrefix(plain, "-") {
+		sign, plain = "-", plain[1:]
+	}
+	integer, fraction, hasFraction := strings.Cut(plain, ".")
+	// Leave NaN, Inf and exponents alone
+	for _, letter := range integer {
+		if !unicode.IsDigit(letter) {
+			return sign + plain
+		}
+	}
+
+	var grouped strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			grouped.WriteRune(l.Group)
+		}
+		grouped.WriteRune(digit)
+	}
+	if hasFraction {
+		grouped.WriteRune(l.Decimal)
+		grouped.WriteString(fraction)
+	}
+	return sign + grouped.String()
+}
+
+// Normalize rewrites every number in the expression from this locale into plain
+// form ("1.000,5" becomes "1000.5" in de-DE) so Calc can read it
+func (l Locale) Normalize(expression string) (string, error) {
+	var result strings.Builder
+	letters := []rune(expression)
+	for i := 0; i < len(letters); i++ {
+		if !l.isNumberRune(letters[i]) || letters[i] == l.Group {
+			result.WriteRune(letters[i])
+			continue
+		}
+		number, next := l.scanNumber(letters, i)
+		value, err := l.ParseNumber(number)
+		if err != nil {
+			return "", syntaxError(i+1, err.Error())
+		}
+		result.WriteString(formatNumber(value))
+		i = next - 1
+	}
+	return result.String(), nil
+}
+
+// scanNumber reads a number starting at index start and returns it with the index after it.
+// A group separator only belongs to the number when a digit follows it
+func (l Locale) scanNumber(letters []rune, start int) (string, int) {
+	i := start
+	for i < len(letters) && l.isNumberRune(letters[i]) {
+		if letters[i] == l.Group && (i+1 == len(letters) || !unicode.IsDigit(letters[i+1])) {
+			break
+		}
+		i++
+	}
+	return string(letters[start:i]), i
+}
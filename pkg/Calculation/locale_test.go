@@ -0,0 +1,90 @@
+package calculate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLocale(t *testing.T) {
+	locale, ok := GetLocale("de_de")
+	assert.True(t, ok)
+	assert.Equal(t, "de-DE", locale.Name)
+
+	locale, ok = GetLocale("fr")
+	assert.True(t, ok)
+	assert.Equal(t, "fr-FR", locale.Name)
+
+	_, ok = GetLocale("xx-YY")
+	assert.False(t, ok)
+}
+
+func TestLocaleFromHeader(t *testing.T) {
+	locale, ok := LocaleFromHeader("xx;q=1, en-US;q=0.5, de-DE;q=0.8")
+	assert.True(t, ok)
+	assert.Equal(t, "de-DE", locale.Name)
+
+	_, ok = LocaleFromHeader("*")
+	assert.False(t, ok)
+}
+
+func TestParseNumber(t *testing.T) {
+	german, _ := GetLocale("de-DE")
+	cases := []struct {
+		locale Locale
+		input  string
+		value  float64
+	}{
+		{DefaultLocale, "1,000.5", 1000.5},
+		{DefaultLocale, "1234567.25", 1234567.25},
+		{german, "1.000,5", 1000.5},
+		{german, "2,5", 2.5},
+	}
+	for _, c := range cases {
+		value, err := c.locale.ParseNumber(c.input)
+		assert.NoError(t, err, c.input)
+		assert.Equal(t, c.value, value, c.input)
+	}
+
+	for _, input := range []string{"1,5", "1,0000", "1.2.3", "inf", ""} {
+		_, err := DefaultLocale.ParseNumber(input)
+		assert.Error(t, err, input)
+	}
+	_, err := german.ParseNumber("1.5")
+	assert.Error(t, err)
+}
+
+func TestFormatNumber(t *testing.T) {
+	german, _ := GetLocale("de-DE")
+	assert.Equal(t, "1,234,567.5", DefaultLocale.FormatNumber(1234567.5))
+	assert.Equal(t, "-1.234,5", german.FormatNumber(-1234.5))
+	assert.Equal(t, "999", german.FormatNumber(999))
+}
+
+func TestParseWithLocale(t *testing.T) {
+	german, _ := GetLocale("de-DE")
+	node, err := ParseWithLocale("1.000,5 * 2", NotationInfix, german)
+	assert.NoError(t, err)
+	assert.Equal(t, "(1000.5*2)", node.String())
+
+	node, err = ParseWithLocale("2,5 -1,5 +", NotationRPN, german)
+	assert.NoError(t, err)
+	assert.Equal(t, "(2.5+(-1.5))", node.String())
+}
+
+func TestNormalize(t *testing.T) {
+	normalized, err := DefaultLocale.Normalize("1,000.5+(2, 3)")
+	assert.NoError(t, err)
+	assert.Equal(t, "1000.5+(2, 3)", normalized)
+
+	_, err = DefaultLocale.Normalize("1,5+1")
+	assert.Error(t, err)
+}
+
+func TestCalc_GroupSeparator(t *testing.T) {
+	disableLogOutput()
+	result, err, code := Calc("1,000.5*2")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, 2001.0, result)
+}
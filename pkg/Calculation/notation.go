@@ -0,0 +1,185 @@
+package calculate
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Supported input notations
+const (
+	NotationInfix  = "infix"
+	NotationRPN    = "rpn"
+	NotationPrefix = "prefix"
+)
+
+// Tokens accepted as unary minus in RPN and prefix input
+func isNegation(token string) bool {
+	return token == "neg" || token == "~"
+}
+
+func IsNotation(notation string) bool {
+	return notation == "" || notation == NotationInfix || notation == NotationRPN || notation == NotationPrefix
+}
+
+// Parse the expression in the given notation, an empty notation means infix
+func ParseNotation(expression string, notation string) (*Node, error) {
+	return ParseWithLocale(expression, notation, DefaultLocale)
+}
+
+// ParseWithLocale is ParseNotation with numbers written in the given locale
+func ParseWithLocale(expression string, notation string, locale Locale) (*Node, error) {
+	switch notation {
+	case "", NotationInfix:
+		return parseInfix(expression, locale)
+	case NotationRPN:
+		return parseRPN(expression, locale)
+	case NotationPrefix:
+		return parsePrefix(expression, locale)
+	}
+	return nil, errors.New("Unknown notation " + notation)
+}
+
+// RPN prints the node in Reverse Polish Notation, the inverse of ParseRPN
+func (n *Node) RPN() string {
+	if n.Op == OpNumber {
+		return formatNumber(n.Value)
+	}
+	tokens := []string{}
+	for _, arg := range n.Args {
+		tokens = append(tokens, arg.RPN())
+	}
+	if len(n.Args) == 1 {
+		return strings.Join(append(tokens, "neg"), " ")
+	}
+	return strings.Join(append(tokens, n.Op), " ")
+}
+
+// Parse Reverse Polish Notation, e.g. "2 3 4 * +" is 2+3*4
+func ParseRPN(expression string) (*Node, error) {
+	return parseRPN(expression, DefaultLocale)
+}
+
+func parseRPN(expression string, locale Locale) (*Node, error) {
+	tokens, positions := fields(expression)
+	if len(tokens) == 0 {
+		return nil, syntaxError(0, "Empty expression")
+	}
+
+	stack := []*Node{}
+	for i, token := range tokens {
+		switch {
+		case isOperator(token):
+			if len(stack) < 2 {
+				return nil, syntaxError(positions[i], "Not enough operands for "+token)
+			}
+			left, right := stack[len(stack)-2], stack[len(stack)-1]
+			stack = append(stack[:len(stack)-2], NewOperation(token, left, right))
+		case isNegation(token):
+			if len(stack) < 1 {
+				return nil, syntaxError(positions[i], "Not enough operands for "+token)
+			}
+			stack[len(stack)-1] = NewOperation("-", stack[len(stack)-1])
+		default:
+			value, err := parseToken(token, locale)
+			if err != nil {
+				return nil, syntaxError(positions[i], err.Error())
+			}
+			stack = append(stack, NewNumber(value))
+		}
+	}
+
+	// The operator is missing at the end
+	if len(stack) != 1 {
+		return nil, syntaxError(len([]rune(expression))+1, "Too many operands")
+	}
+	return stack[0], nil
+}
+
+// Parse prefix (Polish) notation, e.g. "+ 2 * 3 4" is 2+3*4
+func ParsePrefix(expression string) (*Node, error) {
+	return parsePrefix(expression, DefaultLocale)
+}
+
+func parsePrefix(expression string, locale Locale) (*Node, error) {
+	tokens, positions := fields(expression)
+	if len(tokens) == 0 {
+		return nil, syntaxError(0, "Empty expression")
+	}
+
+	pos := 0
+	var parse func() (*Node, error)
+	parse = func() (*Node, error) {
+		if pos >= len(tokens) {
+			return nil, syntaxError(len([]rune(expression))+1, "Unexpected end of expression")
+		}
+		token := tokens[pos]
+		pos++
+		switch {
+		case isOperator(token):
+			left, err := parse()
+			if err != nil {
+				return nil, err
+			}
+			right, err := parse()
+			if err != nil {
+				return nil, err
+			}
+			return NewOperation(token, left, right), nil
+		case isNegation(token):
+			arg, err := parse()
+			if err != nil {
+				return nil, err
+			}
+			return NewOperation("-", arg), nil
+		}
+		value, err := parseToken(token, locale)
+		if err != nil {
+			return nil, syntaxError(positions[pos-1], err.Error())
+		}
+		return NewNumber(value), nil
+	}
+
+	node, err := parse()
+	if err != nil {
+		return nil, err
+	}
+	if pos != len(tokens) {
+		return nil, syntaxError(positions[pos], "Too many operands")
+	}
+	return node, nil
+}
+
+// fields splits RPN or prefix input at white space like strings.Fields and
+// also returns the 1-based character position of every token
+func fields(expression string) ([]string, []int) {
+	tokens, positions := []string{}, []int{}
+	start := -1
+	letters := []rune(expression)
+	for i, letter := range letters {
+		if !unicode.IsSpace(letter) && start < 0 {
+			start = i
+		}
+		if unicode.IsSpace(letter) && start >= 0 {
+			tokens, positions = append(tokens, string(letters[start:i])), append(positions, start+1)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens, positions = append(tokens, string(letters[start:])), append(positions, start+1)
+	}
+	return tokens, positions
+}
+
+// A number token of RPN or prefix input, which may carry a sign
+func parseToken(token string, locale Locale) (float64, error) {
+	sign := 1.0
+	if strings.HasPrefix(token, "-") {
+		sign, token = -1.0, token[1:]
+	}
+	value, err := locale.ParseNumber(token)
+	if err != nil {
+		return 0.0, err
+	}
+	return sign * value, nil
+}
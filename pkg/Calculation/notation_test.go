@@ -0,0 +1,65 @@
+package calculate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRPN_SameAsInfix(t *testing.T) {
+	infix, err := Parse("(1+2)*3-4/2")
+	assert.NoError(t, err)
+	rpn, err := ParseRPN("1 2 + 3 * 4 2 / -")
+	assert.NoError(t, err)
+	assert.Equal(t, infix, rpn)
+}
+
+func TestParsePrefix_SameAsInfix(t *testing.T) {
+	infix, err := Parse("(1+2)*3-4/2")
+	assert.NoError(t, err)
+	prefix, err := ParsePrefix("- * + 1 2 3 / 4 2")
+	assert.NoError(t, err)
+	assert.Equal(t, infix, prefix)
+}
+
+func TestParseRPN_Negation(t *testing.T) {
+	node, err := ParseRPN("2 3 neg *")
+	assert.NoError(t, err)
+	result, err, _ := Evaluate(node)
+	assert.NoError(t, err)
+	assert.Equal(t, -6.0, result)
+}
+
+func TestParseRPN_Invalid(t *testing.T) {
+	for _, expression := range []string{"", "1 +", "1 2", "1 x +"} {
+		_, err := ParseRPN(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestParsePrefix_Invalid(t *testing.T) {
+	for _, expression := range []string{"", "+ 1", "+ 1 2 3", "* x 2"} {
+		_, err := ParsePrefix(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestParseNotation(t *testing.T) {
+	node, err := ParseNotation("3 4 +", NotationRPN)
+	assert.NoError(t, err)
+	assert.Equal(t, "(3+4)", node.String())
+
+	_, err = ParseNotation("3 4 +", "roman")
+	assert.Error(t, err)
+	assert.False(t, IsNotation("roman"))
+	assert.True(t, IsNotation(""))
+}
+
+func TestNodeRPN_RoundTrip(t *testing.T) {
+	node, err := Parse("-(1.5+2)*3/4")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.5 2 + neg 3 * 4 /", node.RPN())
+	again, err := ParseRPN(node.RPN())
+	assert.NoError(t, err)
+	assert.Equal(t, node, again)
+}
@@ -0,0 +1,161 @@
+package calculate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// How a result is printed
+const (
+	ResultFixed       = "fixed"       // Digits decimals: 3.14
+	ResultSignificant = "significant" // Digits significant figures: 3.14159
+	ResultScientific  = "scientific"  // Digits decimals in the mantissa: 3.14e0
+	ResultEngineering = "engineering" // like scientific, exponent is a multiple of 3: 31.4e-3
+	ResultFraction    = "fraction"    // nearest fraction with a denominator up to Digits: 1/3
+)
+
+// Defaults used when Digits is negative
+var defaultDigits = map[string]int{
+	ResultFixed:       2,
+	ResultSignificant: 6,
+	ResultScientific:  6,
+	ResultEngineering: 6,
+	ResultFraction:    1000,
+}
+
+// Largest Digits: a float64 has no more than 17 significant digits, and fractions
+// with larger denominators are no longer readable
+const (
+	MaxDigits      = 17
+	MaxDenominator = 1000000
+)
+
+// ResultFormat picks the output mode. An empty Mode prints the number as it is,
+// a negative Digits uses the default of the mode
+type ResultFormat struct {
+	Mode   string `json:"mode"`
+	Digits int    `json:"digits"`
+}
+
+func IsResultMode(mode string) bool {
+	_, ok := defaultDigits[mode]
+	return mode == "" || ok
+}
+
+// Validate checks the mode and that Digits is at most MaxDigits, or MaxDenominator for fractions
+func (f ResultFormat) Validate() error {
+	if !IsResultMode(f.Mode) {
+		return errors.New("Unknown result format " + f.Mode)
+	}
+	limit := MaxDigits
+	if f.Mode == ResultFraction {
+		limit = MaxDenominator
+	}
+	if f.Digits > limit {
+		return fmt.Errorf("digits can be at most %d for this result format", limit)
+	}
+	return nil
+}
+
+// Format prints the value in this mode with the separators of the locale
+func (f ResultFormat) Format(value float64, locale Locale) (string, error) {
+	if err := f.Validate(); err != nil {
+		return "", err
+	}
+	if f.Mode == "" || math.IsNaN(value) || math.IsInf(value, 0) {
+		return locale.FormatNumber(value), nil
+	}
+	digits := defaultDigits[f.Mode]
+	if f.Digits >= 0 {
+		digits = f.Digits
+	}
+
+	switch f.Mode {
+	case ResultFixed:
+		return locale.localize(strconv.FormatFloat(value, 'f', digits, 64)), nil
+	case ResultSignificant:
+		return locale.localize(formatSignificant(value, digits)), nil
+	case ResultScientific:
+		return formatExponent(value, digits, 1, locale), nil
+	case ResultEngineering:
+		return formatExponent(value, digits, 3, locale), nil
+	}
+	return formatFraction(value, digits, locale), nil
+}
+
+func formatSignificant(value float64, digits int) string {
+	if digits < 1 {
+		digits = 1
+	}
+	if value == 0 {
+		return "0"
+	}
+	// Round with 'e' first, then print without an exponent
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(value, 'e', digits-1, 64), 64)
+	exponent := int(math.Floor(math.Log10(math.Abs(rounded))))
+	decimals := digits - 1 - exponent
+	if decimals < 0 {
+		decimals = 0
+	}
+	return strconv.FormatFloat(rounded, 'f', decimals, 64)
+}
+
+// Print mantissa and exponent, the exponent is a multiple of step
+func formatExponent(value float64, digits int, step int, locale Locale) string {
+	if value == 0 {
+		return locale.localize(strconv.FormatFloat(0, 'f', digits, 64)) + "e0"
+	}
+	exponent := int(math.Floor(math.Log10(math.Abs(value))))
+	exponent -= ((exponent % step) + step) % step
+	mantissa := value / math.Pow(10, float64(exponent))
+
+	// Rounding can carry the mantissa over, e.g. 9.9999 with 2 decimals
+	text := strconv.FormatFloat(mantissa, 'f', digits, 64)
+	if rounded, _ := strconv.ParseFloat(text, 64); math.Abs(rounded) >= math.Pow(10, float64(step)) {
+		exponent += step
+		text = strconv.FormatFloat(value/math.Pow(10, float64(exponent)), 'f', digits, 64)
+	}
+	return locale.localize(text) + "e" + strconv.Itoa(exponent)
+}
+
+// Find the closest fraction with a denominator up to maxDenominator using continued fractions.
+// If none is close enough, the plain number is printed
+func formatFraction(value float64, maxDenominator int, locale Locale) string {
+	if maxDenominator < 1 {
+		maxDenominator = 1
+	}
+	original := value
+	sign := ""
+	if value < 0 {
+		sign, value = "-", -value
+	}
+
+	// Convergents h/k of the continued fraction of value
+	h, hPrev := 1.0, 0.0
+	k, kPrev := 0.0, 1.0
+	rest := value
+	for i := 0; i < 64; i++ {
+		whole := math.Floor(rest)
+		hNext, kNext := whole*h+hPrev, whole*k+kPrev
+		if kNext > float64(maxDenominator) {
+			break
+		}
+		h, hPrev, k, kPrev = hNext, h, kNext, k
+		if rest-whole < 1e-12 {
+			break
+		}
+		rest = 1 / (rest - whole)
+	}
+
+	// Results travel as float32 in places, so allow that much error
+	if k == 0 || math.Abs(value-h/k) > 1e-6*math.Max(1, value) {
+		return locale.FormatNumber(original)
+	}
+	numerator := locale.localize(strconv.FormatFloat(h, 'f', 0, 64))
+	if k == 1 {
+		return sign + numerator
+	}
+	return sign + numerator + "/" + locale.localize(strconv.FormatFloat(k, 'f', 0, 64))
+}
@@ -0,0 +1,57 @@
+package calculate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultFormat(t *testing.T) {
+	german, _ := GetLocale("de-DE")
+	cases := []struct {
+		format   ResultFormat
+		value    float64
+		locale   Locale
+		expected string
+	}{
+		{ResultFormat{Mode: "", Digits: -1}, 1234.5, DefaultLocale, "1,234.5"},
+		{ResultFormat{Mode: ResultFixed, Digits: -1}, 3.14159, DefaultLocale, "3.14"},
+		{ResultFormat{Mode: ResultFixed, Digits: 0}, 2.5e6, german, "2.500.000"},
+		{ResultFormat{Mode: ResultSignificant, Digits: 3}, 3.14159, DefaultLocale, "3.14"},
+		{ResultFormat{Mode: ResultSignificant, Digits: 2}, 123456, DefaultLocale, "120,000"},
+		{ResultFormat{Mode: ResultSignificant, Digits: 2}, 0.0012345, german, "0,0012"},
+		{ResultFormat{Mode: ResultScientific, Digits: 2}, 12345, DefaultLocale, "1.23e4"},
+		{ResultFormat{Mode: ResultScientific, Digits: 2}, -0.0009999, DefaultLocale, "-1.00e-3"},
+		{ResultFormat{Mode: ResultEngineering, Digits: 1}, 0.0314, DefaultLocale, "31.4e-3"},
+		{ResultFormat{Mode: ResultEngineering, Digits: 2}, 999999, german, "1,00e6"},
+		{ResultFormat{Mode: ResultFraction, Digits: -1}, 1.0 / 3, DefaultLocale, "1/3"},
+		{ResultFormat{Mode: ResultFraction, Digits: -1}, float64(float32(-2.0 / 7)), DefaultLocale, "-2/7"},
+		{ResultFormat{Mode: ResultFraction, Digits: -1}, 4, DefaultLocale, "4"},
+		{ResultFormat{Mode: ResultFraction, Digits: 10}, 3.14159, DefaultLocale, "3.14159"},
+	}
+	for _, c := range cases {
+		result, err := c.format.Format(c.value, c.locale)
+		assert.NoError(t, err)
+		assert.Equal(t, c.expected, result, "%+v %v", c.format, c.value)
+	}
+}
+
+func TestResultFormat_Unknown(t *testing.T) {
+	_, err := ResultFormat{Mode: "roman"}.Format(1, DefaultLocale)
+	assert.Error(t, err)
+	assert.False(t, IsResultMode("roman"))
+	assert.True(t, IsResultMode(ResultFraction))
+}
+
+func TestResultFormat_DigitsLimit(t *testing.T) {
+	_, err := ResultFormat{Mode: ResultFixed, Digits: MaxDigits}.Format(1, DefaultLocale)
+	assert.NoError(t, err)
+	for _, mode := range []string{ResultFixed, ResultSignificant, ResultScientific, ResultEngineering} {
+		_, err := ResultFormat{Mode: mode, Digits: MaxDigits + 1}.Format(1, DefaultLocale)
+		assert.Error(t, err, mode)
+	}
+	_, err = ResultFormat{Mode: ResultFraction, Digits: MaxDenominator}.Format(0.5, DefaultLocale)
+	assert.NoError(t, err)
+	_, err = ResultFormat{Mode: ResultFraction, Digits: MaxDenominator + 1}.Format(0.5, DefaultLocale)
+	assert.Error(t, err)
+}
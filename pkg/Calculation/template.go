@@ -0,0 +1,40 @@
+package calculate
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Variables of a template are written as $name, now() is the current Unix time in seconds
+var templateVariable = regexp.MustCompile(`\$[A-Za-z_][A-Za-z0-9_]*`)
+
+// Expand replaces now() and the variables in the expression with their values,
+// so the result can be parsed. Every value is put in brackets, so it stays one
+// operand: 2now() must not turn into a larger number
+func Expand(expression string, variables map[string]float64, now time.Time) (string, error) {
+	expression = strings.ReplaceAll(expression, "now()", "("+strconv.FormatInt(now.Unix(), 10)+")")
+
+	var missing error
+	expanded := templateVariable.ReplaceAllStringFunc(expression, func(name string) string {
+		value, ok := variables[name[1:]]
+		if !ok {
+			if missing == nil {
+				missing = errors.New("Unknown variable " + name)
+			}
+			return name
+		}
+		return "(" + formatNumber(value) + ")"
+	})
+	if missing != nil {
+		return "", missing
+	}
+	return expanded, nil
+}
+
+// IsTemplate reports whether the expression uses now() or variables
+func IsTemplate(expression string) bool {
+	return strings.Contains(expression, "now()") || templateVariable.MatchString(expression)
+}
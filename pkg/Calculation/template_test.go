@@ -0,0 +1,42 @@
+package calculate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpand(t *testing.T) {
+	now := time.Unix(1746100800, 0)
+
+	expanded, err := Expand("now()/60+$offset*$rate", map[string]float64{"offset": -2, "rate": 1.5}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "(1746100800)/60+(-2)*(1.5)", expanded)
+
+	_, err = Parse(expanded)
+	assert.NoError(t, err)
+
+	// A value next to a number is multiplied with it instead of adding digits to it
+	cases := map[string]float64{"2now()": 2 * 1746100800, "2$rate": 6}
+	for expression, expected := range cases {
+		expanded, err = Expand(expression, map[string]float64{"rate": 3}, now)
+		assert.NoError(t, err)
+		node, err := Parse(expanded)
+		assert.NoError(t, err)
+		result, err, _ := Evaluate(node)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result, expression)
+	}
+	expanded, err = Expand("now()3", nil, now)
+	assert.NoError(t, err)
+	_, err = Parse(expanded)
+	assert.Error(t, err)
+
+	_, err = Expand("$missing+1", nil, now)
+	assert.EqualError(t, err, "Unknown variable $missing")
+
+	assert.True(t, IsTemplate("now()-1"))
+	assert.True(t, IsTemplate("$a+1"))
+	assert.False(t, IsTemplate("1+2"))
+}
@@ -0,0 +1,75 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	config "github.com/ArteShow/Calculator/pkg/Config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// ServerOptions returns the options that make the gRPC server use TLS, and ask clients
+// for a certificate if grpcClientCa is set. Without grpcTlsCert the server stays
+// plaintext and there are no options
+func ServerOptions(c *config.CalculatorConfig) ([]grpc.ServerOption, error) {
+	if c.GrpcTLSCert == "" {
+		return nil, nil
+	}
+	certificate, err := tls.LoadX509KeyPair(c.GrpcTLSCert, c.GrpcTLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the gRPC server certificate: %v", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.GrpcClientCA != "" {
+		pool, err := loadPool(c.GrpcClientCA)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(tlsConfig))}, nil
+}
+
+// DialOption returns the transport credentials of a gRPC client: TLS trusting grpcCa,
+// with the client certificate if one is set, or plaintext without grpcCa
+func DialOption(c *config.CalculatorConfig) (grpc.DialOption, error) {
+	if c.GrpcCA == "" {
+		return grpc.WithTransportCredentials(insecure.NewCredentials()), nil
+	}
+	pool, err := loadPool(c.GrpcCA)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+	if c.GrpcClientCert != "" {
+		certificate, err := tls.LoadX509KeyPair(c.GrpcClientCert, c.GrpcClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the gRPC client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)), nil
+}
+
+func loadPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA %s: %v", path, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificate found in CA %s", path)
+	}
+	return pool, nil
+}
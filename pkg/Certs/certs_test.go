@@ -0,0 +1,81 @@
+package certs
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	config "github.com/ArteShow/Calculator/pkg/Config"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// serve starts a gRPC server with the health service and returns its address
+func serve(t *testing.T, c *config.CalculatorConfig) string {
+	options, err := ServerOptions(c)
+	assert.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := grpc.NewServer(options...)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+// check calls the server with the client side of the config
+func check(t *testing.T, address string, c *config.CalculatorConfig) error {
+	option, err := DialOption(c)
+	assert.NoError(t, err)
+	conn, err := grpc.Dial(address, option)
+	assert.NoError(t, err)
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca, err := GenerateDevCA(t.TempDir())
+	assert.NoError(t, err)
+	address := serve(t, &config.CalculatorConfig{GrpcTLSCert: ca.ServerCert, GrpcTLSKey: ca.ServerKey, GrpcClientCA: ca.CA})
+
+	assert.NoError(t, check(t, address, &config.CalculatorConfig{GrpcCA: ca.CA, GrpcClientCert: ca.ClientCert, GrpcClientKey: ca.ClientKey}))
+	// Without a client certificate, or without TLS at all, the server does not answer
+	assert.Error(t, check(t, address, &config.CalculatorConfig{GrpcCA: ca.CA}))
+	assert.Error(t, check(t, address, &config.CalculatorConfig{}))
+
+	// A client certificate of another CA is not accepted
+	other, err := GenerateDevCA(t.TempDir())
+	assert.NoError(t, err)
+	assert.Error(t, check(t, address, &config.CalculatorConfig{GrpcCA: ca.CA, GrpcClientCert: other.ClientCert, GrpcClientKey: other.ClientKey}))
+}
+
+func TestTLS(t *testing.T) {
+	ca, err := GenerateDevCA(t.TempDir())
+	assert.NoError(t, err)
+	address := serve(t, &config.CalculatorConfig{GrpcTLSCert: ca.ServerCert, GrpcTLSKey: ca.ServerKey})
+
+	assert.NoError(t, check(t, address, &config.CalculatorConfig{GrpcCA: ca.CA}))
+	// The client does not trust a server whose certificate another CA signed
+	other, err := GenerateDevCA(t.TempDir())
+	assert.NoError(t, err)
+	assert.Error(t, check(t, address, &config.CalculatorConfig{GrpcCA: other.CA}))
+}
+
+func TestPlaintext(t *testing.T) {
+	options, err := ServerOptions(&config.CalculatorConfig{})
+	assert.NoError(t, err)
+	assert.Empty(t, options)
+	address := serve(t, &config.CalculatorConfig{})
+	assert.NoError(t, check(t, address, &config.CalculatorConfig{}))
+
+	_, err = ServerOptions(&config.CalculatorConfig{GrpcTLSCert: "missing.pem", GrpcTLSKey: "missing-key.pem"})
+	assert.Error(t, err)
+	_, err = DialOption(&config.CalculatorConfig{GrpcCA: "missing.pem"})
+	assert.Error(t, err)
+}
@@ -2,7 +2,11 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type DatabaseConfig struct {
@@ -26,6 +30,10 @@ func LoadDatabaseConfig() (*DatabaseConfig, error) {
 }
 
 func GetDatabasePath() string {
+	// DB_PATH overrides the configured path (used by tests)
+	if path := os.Getenv("DB_PATH"); path != "" {
+		return path
+	}
 	config, err := LoadDatabaseConfig()
 	if err != nil {
 		panic(err)
@@ -33,3 +41,278 @@ func GetDatabasePath() string {
 	return config.Path
 }
 
+// CalculatorConfig holds the settings of the orchestrator and the agents
+type CalculatorConfig struct {
+	OrchestratorAddress  string `json:"orchestratorAddress"`
+	ComputingPower       int    `json:"computingPower"`
+	QueueSize            int    `json:"queueSize"`
+	CalculationTimeoutMs int    `json:"calculationTimeoutMs"`
+	TaskLeaseMs          int    `json:"taskLeaseMs"`
+	TaskMaxAttempts      int    `json:"taskMaxAttempts"`
+	WebhookMaxAttempts   int    `json:"webhookMaxAttempts"`
+	WebhookBackoffMs     int    `json:"webhookBackoffMs"`
+	// Hosts webhooks may be posted to although they resolve to a loopback,
+	// private or link-local address
+	WebhookAllowedHosts []string `json:"webhookAllowedHosts"`
+	// First wait before a transient failure, a busy database or a lost agent, is
+	// retried. It doubles with every attempt
+	RetryBackoffMs int `json:"retryBackoffMs"`
+
+	// Calculations one user may have evaluated at the same time, 0 for no limit
+	UserConcurrency int `json:"userConcurrency"`
+	// Interactive calculations started for every batch calculation while both wait
+	InteractiveWeight int `json:"interactiveWeight"`
+
+	// Results of expressions computed before are reused, the cacheSize most recently
+	// used are kept, in the database too if cachePersist is set
+	CacheEnabled bool `json:"cacheEnabled"`
+	CacheSize    int  `json:"cacheSize"`
+	CachePersist bool `json:"cachePersist"`
+
+	// Registered agents send a heartbeat every agentHeartbeatMs, one that is quiet for
+	// agentTimeoutMs counts as dead and its tasks are handed out again
+	AgentHeartbeatMs int `json:"agentHeartbeatMs"`
+	AgentTimeoutMs   int `json:"agentTimeoutMs"`
+
+	// Users that may call the /api/v1/admin endpoints
+	AdminUserIds []int `json:"adminUserIds"`
+
+	// TLS of the gRPC channel, PEM files. The server shows grpcTlsCert and grpcTlsKey and,
+	// with grpcClientCa, only lets in clients with a certificate signed by that CA (mutual TLS).
+	// Clients trust the server if grpcCa signed its certificate and show grpcClientCert
+	// and grpcClientKey. Without grpcTlsCert and grpcCa the channel is plaintext
+	GrpcTLSCert    string `json:"grpcTlsCert"`
+	GrpcTLSKey     string `json:"grpcTlsKey"`
+	GrpcClientCA   string `json:"grpcClientCa"`
+	GrpcCA         string `json:"grpcCa"`
+	GrpcClientCert string `json:"grpcClientCert"`
+	GrpcClientKey  string `json:"grpcClientKey"`
+
+	// Secret the agents authenticate with, besides a client certificate under mutual TLS
+	AgentToken string `json:"agentToken"`
+
+	// Simulated time each operation takes in the agents
+	TimeAdditionMs       int `json:"timeAdditionMs"`
+	TimeSubtractionMs    int `json:"timeSubtractionMs"`
+	TimeMultiplicationMs int `json:"timeMultiplicationMs"`
+	TimeDivisionMs       int `json:"timeDivisionMs"`
+}
+
+// DevAgentToken is the agent token of a fresh install, so the orchestrator and the agents
+// started on one machine find each other. Anything but local development sets its own
+const DevAgentToken = "dev-agent-token"
+
+// DefaultCalculatorConfig is used for everything configs/calculator.json leaves out
+func DefaultCalculatorConfig() *CalculatorConfig {
+	return &CalculatorConfig{
+		OrchestratorAddress:  "localhost:50051",
+		ComputingPower:       4,
+		QueueSize:            100,
+		CalculationTimeoutMs: 60000,
+		TaskLeaseMs:          30000,
+		TaskMaxAttempts:      3,
+		WebhookMaxAttempts:   5,
+		WebhookBackoffMs:     500,
+		RetryBackoffMs:       200,
+		UserConcurrency:      2,
+		InteractiveWeight:    3,
+		CacheEnabled:         true,
+		CacheSize:            1000,
+		AgentHeartbeatMs:     5000,
+		AgentTimeoutMs:       15000,
+		AgentToken:           DevAgentToken,
+	}
+}
+
+// LoadCalculatorConfig reads configs/calculator.json if it exists and applies the
+// ORCHESTRATOR_ADDRESS, COMPUTING_POWER, QUEUE_SIZE, CALCULATION_TIMEOUT_MS, TASK_LEASE_MS,
+// TASK_MAX_ATTEMPTS, WEBHOOK_MAX_ATTEMPTS, WEBHOOK_BACKOFF_MS, WEBHOOK_ALLOWED_HOSTS, RETRY_BACKOFF_MS, USER_CONCURRENCY, INTERACTIVE_WEIGHT,
+// CACHE_ENABLED, CACHE_SIZE, CACHE_PERSIST, AGENT_HEARTBEAT_MS, AGENT_TIMEOUT_MS, ADMIN_USER_IDS,
+// GRPC_TLS_CERT, GRPC_TLS_KEY, GRPC_CLIENT_CA, GRPC_CA, GRPC_CLIENT_CERT, GRPC_CLIENT_KEY,
+// AGENT_TOKEN and TIME_*_MS environment variables on top
+func LoadCalculatorConfig() (*CalculatorConfig, error) {
+	calculatorConfig := DefaultCalculatorConfig()
+	file, err := os.Open("configs/calculator.json")
+	if err == nil {
+		defer file.Close()
+		if err := json.NewDecoder(file).Decode(calculatorConfig); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if address := os.Getenv("ORCHESTRATOR_ADDRESS"); address != "" {
+		calculatorConfig.OrchestratorAddress = address
+	}
+	if err := intFromEnv("COMPUTING_POWER", &calculatorConfig.ComputingPower); err != nil {
+		return nil, err
+	}
+	if err := intFromEnv("QUEUE_SIZE", &calculatorConfig.QueueSize); err != nil {
+		return nil, err
+	}
+	if err := intFromEnv("CALCULATION_TIMEOUT_MS", &calculatorConfig.CalculationTimeoutMs); err != nil {
+		return nil, err
+	}
+	if err := intFromEnv("TASK_LEASE_MS", &calculatorConfig.TaskLeaseMs); err != nil {
+		return nil, err
+	}
+	if err := intFromEnv("TASK_MAX_ATTEMPTS", &calculatorConfig.TaskMaxAttempts); err != nil {
+		return nil, err
+	}
+	if err := intFromEnv("WEBHOOK_MAX_ATTEMPTS", &calculatorConfig.WebhookMaxAttempts); err != nil {
+		return nil, err
+	}
+	if err := intFromEnv("WEBHOOK_BACKOFF_MS", &calculatorConfig.WebhookBackoffMs); err != nil {
+		return nil, err
+	}
+	if value := os.Getenv("WEBHOOK_ALLOWED_HOSTS"); value != "" {
+		calculatorConfig.WebhookAllowedHosts = nil
+		for _, host := range strings.Split(value, ",") {
+			calculatorConfig.WebhookAllowedHosts = append(calculatorConfig.WebhookAllowedHosts, strings.TrimSpace(host))
+		}
+	}
+	if err := intFromEnv("RETRY_BACKOFF_MS", &calculatorConfig.RetryBackoffMs); err != nil {
+		return nil, err
+	}
+	if err := intFromEnv("USER_CONCURRENCY", &calculatorConfig.UserConcurrency); err != nil {
+		return nil, err
+	}
+	if err := intFromEnv("INTERACTIVE_WEIGHT", &calculatorConfig.InteractiveWeight); err != nil {
+		return nil, err
+	}
+	if err := boolFromEnv("CACHE_ENABLED", &calculatorConfig.CacheEnabled); err != nil {
+		return nil, err
+	}
+	if err := intFromEnv("CACHE_SIZE", &calculatorConfig.CacheSize); err != nil {
+		return nil, err
+	}
+	if err := boolFromEnv("CACHE_PERSIST", &calculatorConfig.CachePersist); err != nil {
+		return nil, err
+	}
+	if err := intFromEnv("AGENT_HEARTBEAT_MS", &calculatorConfig.AgentHeartbeatMs); err != nil {
+		return nil, err
+	}
+	if err := intFromEnv("AGENT_TIMEOUT_MS", &calculatorConfig.AgentTimeoutMs); err != nil {
+		return nil, err
+	}
+	if value := os.Getenv("ADMIN_USER_IDS"); value != "" {
+		calculatorConfig.AdminUserIds = nil
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return nil, fmt.Errorf("ADMIN_USER_IDS must be a comma separated list of user ids: %v", err)
+			}
+			calculatorConfig.AdminUserIds = append(calculatorConfig.AdminUserIds, id)
+		}
+	}
+	for name, target := range map[string]*string{
+		"GRPC_TLS_CERT":    &calculatorConfig.GrpcTLSCert,
+		"GRPC_TLS_KEY":     &calculatorConfig.GrpcTLSKey,
+		"GRPC_CLIENT_CA":   &calculatorConfig.GrpcClientCA,
+		"GRPC_CA":          &calculatorConfig.GrpcCA,
+		"GRPC_CLIENT_CERT": &calculatorConfig.GrpcClientCert,
+		"GRPC_CLIENT_KEY":  &calculatorConfig.GrpcClientKey,
+		"AGENT_TOKEN":      &calculatorConfig.AgentToken,
+	} {
+		if value := os.Getenv(name); value != "" {
+			*target = value
+		}
+	}
+	for name, target := range map[string]*int{
+		"TIME_ADDITION_MS":        &calculatorConfig.TimeAdditionMs,
+		"TIME_SUBTRACTION_MS":     &calculatorConfig.TimeSubtractionMs,
+		"TIME_MULTIPLICATIONS_MS": &calculatorConfig.TimeMultiplicationMs,
+		"TIME_DIVISIONS_MS":       &calculatorConfig.TimeDivisionMs,
+	} {
+		if err := intFromEnv(name, target); err != nil {
+			return nil, err
+		}
+		if *target < 0 {
+			return nil, fmt.Errorf("%s can not be negative", name)
+		}
+	}
+	if calculatorConfig.ComputingPower < 1 {
+		return nil, fmt.Errorf("computingPower must be at least 1, got %d", calculatorConfig.ComputingPower)
+	}
+	if calculatorConfig.TaskLeaseMs < 1 || calculatorConfig.TaskMaxAttempts < 1 {
+		return nil, fmt.Errorf("taskLeaseMs and taskMaxAttempts must be at least 1")
+	}
+	if calculatorConfig.WebhookMaxAttempts < 1 || calculatorConfig.WebhookBackoffMs < 0 {
+		return nil, fmt.Errorf("webhookMaxAttempts must be at least 1 and webhookBackoffMs not negative")
+	}
+	if calculatorConfig.RetryBackoffMs < 0 {
+		return nil, fmt.Errorf("retryBackoffMs can not be negative, got %d", calculatorConfig.RetryBackoffMs)
+	}
+	if calculatorConfig.UserConcurrency < 0 || calculatorConfig.InteractiveWeight < 1 {
+		return nil, fmt.Errorf("userConcurrency can not be negative and interactiveWeight must be at least 1")
+	}
+	if calculatorConfig.CacheEnabled && calculatorConfig.CacheSize < 1 {
+		return nil, fmt.Errorf("cacheSize must be at least 1, got %d", calculatorConfig.CacheSize)
+	}
+	if calculatorConfig.AgentHeartbeatMs < 1 || calculatorConfig.AgentTimeoutMs <= calculatorConfig.AgentHeartbeatMs {
+		return nil, fmt.Errorf("agentHeartbeatMs must be at least 1 and agentTimeoutMs longer than it")
+	}
+	if (calculatorConfig.GrpcTLSCert == "") != (calculatorConfig.GrpcTLSKey == "") {
+		return nil, fmt.Errorf("grpcTlsCert and grpcTlsKey must be set together")
+	}
+	if calculatorConfig.GrpcClientCA != "" && calculatorConfig.GrpcTLSCert == "" {
+		return nil, fmt.Errorf("grpcClientCa needs grpcTlsCert, mutual TLS works over TLS only")
+	}
+	if (calculatorConfig.GrpcClientCert == "") != (calculatorConfig.GrpcClientKey == "") {
+		return nil, fmt.Errorf("grpcClientCert and grpcClientKey must be set together")
+	}
+	if calculatorConfig.GrpcClientCert != "" && calculatorConfig.GrpcCA == "" {
+		return nil, fmt.Errorf("grpcClientCert needs grpcCa, client certificates are sent over TLS only")
+	}
+	if calculatorConfig.QueueSize < 1 {
+		return nil, fmt.Errorf("queueSize must be at least 1, got %d", calculatorConfig.QueueSize)
+	}
+	return calculatorConfig, nil
+}
+
+// IsAdmin tells if the user may call the admin endpoints
+func (c *CalculatorConfig) IsAdmin(userId int) bool {
+	for _, id := range c.AdminUserIds {
+		if id == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// OperationTimes maps every operator to the time it takes
+func (c *CalculatorConfig) OperationTimes() map[string]time.Duration {
+	return map[string]time.Duration{
+		"+": time.Duration(c.TimeAdditionMs) * time.Millisecond,
+		"-": time.Duration(c.TimeSubtractionMs) * time.Millisecond,
+		"*": time.Duration(c.TimeMultiplicationMs) * time.Millisecond,
+		"/": time.Duration(c.TimeDivisionMs) * time.Millisecond,
+	}
+}
+
+func intFromEnv(name string, target *int) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%s must be a number: %v", name, err)
+	}
+	*target = number
+	return nil
+}
+
+func boolFromEnv(name string, target *bool) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%s must be true or false: %v", name, err)
+	}
+	*target = parsed
+	return nil
+}
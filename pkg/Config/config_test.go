@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -55,3 +56,151 @@ func TestGetDatabasePath(t *testing.T) {
 	path := GetDatabasePath()
 	assert.Equal(t, "test.db", path)
 }
+
+func TestLoadCalculatorConfig_Defaults(t *testing.T) {
+	cfg, err := LoadCalculatorConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost:50051", cfg.OrchestratorAddress)
+	assert.Equal(t, 4, cfg.ComputingPower)
+	assert.Equal(t, 100, cfg.QueueSize)
+	assert.Equal(t, 2, cfg.UserConcurrency)
+	assert.Equal(t, 3, cfg.InteractiveWeight)
+	assert.True(t, cfg.CacheEnabled)
+	assert.Equal(t, 1000, cfg.CacheSize)
+	assert.False(t, cfg.CachePersist)
+	assert.Equal(t, 5000, cfg.AgentHeartbeatMs)
+	assert.Equal(t, 15000, cfg.AgentTimeoutMs)
+	assert.Equal(t, 200, cfg.RetryBackoffMs)
+	assert.False(t, cfg.IsAdmin(1))
+}
+
+func TestLoadCalculatorConfig_Env(t *testing.T) {
+	t.Setenv("COMPUTING_POWER", "8")
+	t.Setenv("ORCHESTRATOR_ADDRESS", "orchestrator:50051")
+
+	cfg, err := LoadCalculatorConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "orchestrator:50051", cfg.OrchestratorAddress)
+	assert.Equal(t, 8, cfg.ComputingPower)
+
+	t.Setenv("QUEUE_SIZE", "-1")
+	_, err = LoadCalculatorConfig()
+	assert.Error(t, err)
+
+	t.Setenv("QUEUE_SIZE", "10")
+	t.Setenv("COMPUTING_POWER", "many")
+	_, err = LoadCalculatorConfig()
+	assert.Error(t, err)
+
+	t.Setenv("COMPUTING_POWER", "8")
+	t.Setenv("RETRY_BACKOFF_MS", "-5")
+	_, err = LoadCalculatorConfig()
+	assert.Error(t, err)
+}
+
+func TestLoadCalculatorConfig_Fairness(t *testing.T) {
+	t.Setenv("USER_CONCURRENCY", "0")
+	t.Setenv("INTERACTIVE_WEIGHT", "5")
+
+	cfg, err := LoadCalculatorConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cfg.UserConcurrency)
+	assert.Equal(t, 5, cfg.InteractiveWeight)
+
+	t.Setenv("INTERACTIVE_WEIGHT", "0")
+	_, err = LoadCalculatorConfig()
+	assert.Error(t, err)
+}
+
+func TestLoadCalculatorConfig_Cache(t *testing.T) {
+	t.Setenv("CACHE_PERSIST", "true")
+	t.Setenv("CACHE_SIZE", "50")
+
+	cfg, err := LoadCalculatorConfig()
+	assert.NoError(t, err)
+	assert.True(t, cfg.CachePersist)
+	assert.Equal(t, 50, cfg.CacheSize)
+
+	t.Setenv("CACHE_ENABLED", "maybe")
+	_, err = LoadCalculatorConfig()
+	assert.Error(t, err)
+
+	// The size does not matter without the cache
+	t.Setenv("CACHE_ENABLED", "false")
+	t.Setenv("CACHE_SIZE", "0")
+	cfg, err = LoadCalculatorConfig()
+	assert.NoError(t, err)
+	assert.False(t, cfg.CacheEnabled)
+}
+
+func TestLoadCalculatorConfig_Agents(t *testing.T) {
+	t.Setenv("AGENT_HEARTBEAT_MS", "1000")
+	t.Setenv("AGENT_TIMEOUT_MS", "3000")
+	t.Setenv("ADMIN_USER_IDS", "1, 7")
+
+	cfg, err := LoadCalculatorConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, cfg.AgentHeartbeatMs)
+	assert.Equal(t, 3000, cfg.AgentTimeoutMs)
+	assert.True(t, cfg.IsAdmin(7))
+	assert.False(t, cfg.IsAdmin(2))
+
+	// An agent has to get the chance to send a heartbeat before it counts as dead
+	t.Setenv("AGENT_TIMEOUT_MS", "1000")
+	_, err = LoadCalculatorConfig()
+	assert.Error(t, err)
+
+	t.Setenv("AGENT_TIMEOUT_MS", "3000")
+	t.Setenv("ADMIN_USER_IDS", "1,admin")
+	_, err = LoadCalculatorConfig()
+	assert.Error(t, err)
+}
+
+func TestLoadCalculatorConfig_TLS(t *testing.T) {
+	cfg, err := LoadCalculatorConfig()
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.GrpcTLSCert)
+	assert.Empty(t, cfg.GrpcCA)
+	assert.Equal(t, DevAgentToken, cfg.AgentToken)
+
+	t.Setenv("AGENT_TOKEN", "agent-secret")
+	t.Setenv("GRPC_TLS_CERT", "certs/server.pem")
+	t.Setenv("GRPC_TLS_KEY", "certs/server-key.pem")
+	t.Setenv("GRPC_CLIENT_CA", "certs/ca.pem")
+	t.Setenv("GRPC_CA", "certs/ca.pem")
+	t.Setenv("GRPC_CLIENT_CERT", "certs/client.pem")
+	t.Setenv("GRPC_CLIENT_KEY", "certs/client-key.pem")
+	cfg, err = LoadCalculatorConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "certs/server.pem", cfg.GrpcTLSCert)
+	assert.Equal(t, "certs/client-key.pem", cfg.GrpcClientKey)
+	assert.Equal(t, "agent-secret", cfg.AgentToken)
+
+	// A certificate is useless without its key
+	t.Setenv("GRPC_CLIENT_KEY", "")
+	_, err = LoadCalculatorConfig()
+	assert.Error(t, err)
+	t.Setenv("GRPC_CLIENT_KEY", "certs/client-key.pem")
+
+	// Mutual TLS needs TLS
+	t.Setenv("GRPC_TLS_CERT", "")
+	t.Setenv("GRPC_TLS_KEY", "")
+	_, err = LoadCalculatorConfig()
+	assert.Error(t, err)
+}
+
+func TestLoadCalculatorConfig_OperationTimes(t *testing.T) {
+	t.Setenv("TIME_ADDITION_MS", "100")
+	t.Setenv("TIME_DIVISIONS_MS", "250")
+
+	cfg, err := LoadCalculatorConfig()
+	assert.NoError(t, err)
+	times := cfg.OperationTimes()
+	assert.Equal(t, 100*time.Millisecond, times["+"])
+	assert.Equal(t, time.Duration(0), times["*"])
+	assert.Equal(t, 250*time.Millisecond, times["/"])
+
+	t.Setenv("TIME_ADDITION_MS", "-1")
+	_, err = LoadCalculatorConfig()
+	assert.Error(t, err)
+}
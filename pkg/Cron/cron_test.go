@@ -0,0 +1,50 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func next(t *testing.T, spec string, after string) string {
+	s, err := Parse(spec)
+	assert.NoError(t, err)
+	from, err := time.Parse(time.RFC3339, after)
+	assert.NoError(t, err)
+	at, err := s.Next(from)
+	assert.NoError(t, err)
+	return at.Format(time.RFC3339)
+}
+
+func TestNext(t *testing.T) {
+	// 2025-05-01 is a Thursday
+	assert.Equal(t, "2025-05-01T12:01:00Z", next(t, "* * * * *", "2025-05-01T12:00:30Z"))
+	assert.Equal(t, "2025-05-01T12:15:00Z", next(t, "*/15 * * * *", "2025-05-01T12:00:00Z"))
+	assert.Equal(t, "2025-05-02T00:00:00Z", next(t, "@daily", "2025-05-01T12:00:00Z"))
+	assert.Equal(t, "2025-05-05T09:00:00Z", next(t, "0 9 * * 1-5", "2025-05-02T09:00:00Z"))
+	assert.Equal(t, "2025-05-04T00:00:00Z", next(t, "0 0 * * 7", "2025-05-01T12:00:00Z"))
+	assert.Equal(t, "2026-01-01T00:00:00Z", next(t, "@yearly", "2025-05-01T12:00:00Z"))
+	assert.Equal(t, "2028-02-29T06:30:00Z", next(t, "30 6 29 2 *", "2025-05-01T12:00:00Z"))
+	assert.Equal(t, "2025-05-01T17:05:00Z", next(t, "5,35 9-17/4 * * *", "2025-05-01T13:35:00Z"))
+}
+
+func TestNext_DayFields(t *testing.T) {
+	// With both day fields restricted, either of them is enough: the 15th or a Monday
+	assert.Equal(t, "2025-05-05T00:00:00Z", next(t, "0 0 15 * 1", "2025-05-01T12:00:00Z"))
+	assert.Equal(t, "2025-05-15T00:00:00Z", next(t, "0 0 15 * 1", "2025-05-12T12:00:00Z"))
+}
+
+func TestNext_Never(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	assert.NoError(t, err)
+	_, err = s.Next(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.ErrorIs(t, err, ErrNever)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *", "@often"} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -1,19 +1,52 @@
 package database
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
 
+// Status of a calculation
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusDone      = "done"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+type Calculation struct {
+	Id          int     `json:"id"`
+	UserId      int     `json:"userId"`
+	Expression  string  `json:"expression"`
+	Result      float64 `json:"result"`
+	Status      string  `json:"status"`
+	Error       string  `json:"error"`
+	CreatedAt   string  `json:"createdAt"`
+	UpdatedAt   string  `json:"updatedAt"`
+	CallbackUrl string  `json:"callbackUrl,omitempty"`
+}
+
 type User struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 	UserId   int    `json:"userId"`
 }
 
+type Preferences struct {
+	UserId       int    `json:"userId"`
+	Locale       string `json:"locale"`
+	ResultFormat string `json:"resultFormat"`
+	Digits       int    `json:"digits"`
+}
+
 func CreateDB(path string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite", path)
 	if err != nil {
@@ -77,6 +110,10 @@ func DeleteData(db *sql.DB, tableName string, condition string) error {
 }
 
 func OpenDatabase(path string) (*sql.DB, error) {
+	// Background calculations write at the same time, wait for the lock instead of failing
+	if !strings.Contains(path, "?") {
+		path += "?_pragma=busy_timeout(5000)"
+	}
 	db, err := sql.Open("sqlite", path)
 	if err != nil {
 		log.Printf("❌ Failed to open database: %v", err)
@@ -192,4 +229,695 @@ func GetMaxExpressionIdByUserId(db *sql.DB, userId int) (int, error) {
 	return *maxId, nil
 }
 
+// GetPreferences returns the saved preferences of a user, empty ones if nothing was saved yet
+func GetPreferences(path string, userId int) (*Preferences, error) {
+	db, err := OpenDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	preferences := &Preferences{UserId: userId, Digits: -1}
+	query := "SELECT locale, resultFormat, digits FROM preferences WHERE userId = ?"
+	err = db.QueryRow(query, userId).Scan(&preferences.Locale, &preferences.ResultFormat, &preferences.Digits)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("❌ Failed to get preferences for user %d: %v", userId, err)
+		return nil, err
+	}
+	return preferences, nil
+}
+
+func SavePreferences(path string, preferences Preferences) error {
+	db, err := OpenDatabase(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return InsertData(db, "preferences", map[string]interface{}{
+		"userId":       preferences.UserId,
+		"locale":       preferences.Locale,
+		"resultFormat": preferences.ResultFormat,
+		"digits":       preferences.Digits,
+	})
+}
+
+// GetWebhookSecret returns the secret the webhooks of the user are signed with.
+// A user without one gets a new secret
+func GetWebhookSecret(path string, userId int) (string, error) {
+	db, err := OpenDatabase(path)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	var secret string
+	err = db.QueryRow("SELECT secret FROM webhook_secrets WHERE userId = ?", userId).Scan(&secret)
+	if err == nil {
+		return secret, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to get webhook secret: %w", err)
+	}
+
+	// Another request may create the secret at the same time, the first one wins
+	// and both return it
+	secret, err = newWebhookSecret()
+	if err != nil {
+		return "", err
+	}
+	_, err = db.Exec("INSERT OR IGNORE INTO webhook_secrets (userId, secret, createdAt) VALUES (?, ?, ?)",
+		userId, secret, now())
+	if err != nil {
+		return "", fmt.Errorf("failed to save webhook secret: %w", err)
+	}
+	err = db.QueryRow("SELECT secret FROM webhook_secrets WHERE userId = ?", userId).Scan(&secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to get webhook secret: %w", err)
+	}
+	return secret, nil
+}
+
+// RotateWebhookSecret replaces the webhook secret of the user with a new one
+func RotateWebhookSecret(path string, userId int) (string, error) {
+	db, err := OpenDatabase(path)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return "", err
+	}
+	// Not InsertData, that one logs the values
+	_, err = db.Exec("INSERT OR REPLACE INTO webhook_secrets (userId, secret, createdAt) VALUES (?, ?, ?)",
+		userId, secret, now())
+	if err != nil {
+		return "", fmt.Errorf("failed to save webhook secret: %w", err)
+	}
+	return secret, nil
+}
+
+func newWebhookSecret() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(key), nil
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+// InsertCalculation saves a new pending calculation, callbackUrl may be empty
+func InsertCalculation(db *sql.DB, userId int, expression string, callbackUrl string) (int, error) {
+	return insertCalculation(db, userId, expression, callbackUrl)
+}
+
+// NewCalculation is one calculation of InsertCalculations
+type NewCalculation struct {
+	Expression  string
+	CallbackUrl string
+}
+
+// InsertCalculations saves all calculations in one transaction, either all of them
+// are saved or none. The ids are in the same order as the calculations
+func InsertCalculations(db *sql.DB, userId int, calculations []NewCalculation) ([]int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	ids := []int{}
+	for _, calculation := range calculations {
+		id, err := insertCalculation(tx, userId, calculation.Expression, calculation.CallbackUrl)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit calculations: %w", err)
+	}
+	return ids, nil
+}
+
+func insertCalculation(db interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, userId int, expression string, callbackUrl string) (int, error) {
+	createdAt := now()
+	res, err := db.Exec(
+		"INSERT INTO calculations (userId, calculation, result, status, error, createdAt, updatedAt, callbackUrl) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		userId, expression, 0, StatusPending, "", createdAt, createdAt, callbackUrl,
+	)
+	if err != nil {
+		log.Printf("❌ Failed to insert calculation: %v", err)
+		return 0, fmt.Errorf("failed to insert calculation: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get calculation id: %w", err)
+	}
+	return int(id), nil
+}
+
+func UpdateCalculation(db *sql.DB, id int, status string, result float64, errorText string) error {
+	_, err := db.Exec(
+		"UPDATE calculations SET status = ?, result = ?, error = ?, updatedAt = ? WHERE id = ?",
+		status, result, errorText, now(), id,
+	)
+	if err != nil {
+		log.Printf("❌ Failed to update calculation %d: %v", id, err)
+		return fmt.Errorf("failed to update calculation: %w", err)
+	}
+	return nil
+}
+
+// UpdateCalculationFrom updates the calculation only while it has one of the statuses in from,
+// so a calculation that was cancelled or finished in the meantime is not overwritten.
+// It reports whether the calculation was updated
+func UpdateCalculationFrom(db *sql.DB, id int, from []string, status string, result float64, errorText string) (bool, error) {
+	query := "UPDATE calculations SET status = ?, result = ?, error = ?, updatedAt = ? WHERE id = ? AND status IN (?" +
+		strings.Repeat(", ?", len(from)-1) + ")"
+	args := []interface{}{status, result, errorText, now(), id}
+	for _, s := range from {
+		args = append(args, s)
+	}
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		log.Printf("❌ Failed to update calculation %d: %v", id, err)
+		return false, fmt.Errorf("failed to update calculation: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update calculation: %w", err)
+	}
+	return affected > 0, nil
+}
+
+const calculationColumns = "id, userId, calculation, result, status, error, createdAt, updatedAt, callbackUrl"
+
+func scanCalculation(row interface{ Scan(...any) error }) (*Calculation, error) {
+	var calculation Calculation
+	err := row.Scan(&calculation.Id, &calculation.UserId, &calculation.Expression, &calculation.Result,
+		&calculation.Status, &calculation.Error, &calculation.CreatedAt, &calculation.UpdatedAt, &calculation.CallbackUrl)
+	if err != nil {
+		return nil, err
+	}
+	return &calculation, nil
+}
+
+// GetCalculation returns sql.ErrNoRows if the user has no calculation with this ID
+func GetCalculation(db *sql.DB, userId int, id int) (*Calculation, error) {
+	query := "SELECT " + calculationColumns + " FROM calculations WHERE userId = ? AND id = ?"
+	return scanCalculation(db.QueryRow(query, userId, id))
+}
+
+// GetCalculationById returns a calculation of any user
+func GetCalculationById(db *sql.DB, id int) (*Calculation, error) {
+	return scanCalculation(db.QueryRow("SELECT "+calculationColumns+" FROM calculations WHERE id = ?", id))
+}
+
+func GetCalculationsByUserId(db *sql.DB, userId int) ([]Calculation, error) {
+	rows, err := db.Query("SELECT "+calculationColumns+" FROM calculations WHERE userId = ? ORDER BY id", userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calculations: %w", err)
+	}
+	defer rows.Close()
+
+	calculations := []Calculation{}
+	for rows.Next() {
+		calculation, err := scanCalculation(rows)
+		if err != nil {
+			return nil, err
+		}
+		calculations = append(calculations, *calculation)
+	}
+	return calculations, rows.Err()
+}
+
+// GetCalculationsPage returns up to limit calculations of the user with an id above afterId,
+// ordered by id. A non-empty status only returns calculations with that status
+func GetCalculationsPage(db *sql.DB, userId int, afterId int, status string, limit int) ([]Calculation, error) {
+	query := "SELECT " + calculationColumns + " FROM calculations WHERE userId = ? AND id > ?"
+	args := []any{userId, afterId}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	rows, err := db.Query(query+" ORDER BY id LIMIT ?", append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calculations: %w", err)
+	}
+	defer rows.Close()
+
+	calculations := []Calculation{}
+	for rows.Next() {
+		calculation, err := scanCalculation(rows)
+		if err != nil {
+			return nil, err
+		}
+		calculations = append(calculations, *calculation)
+	}
+	return calculations, rows.Err()
+}
+
+// DeleteCalculation removes a calculation of the user and its tasks, it reports
+// whether the calculation existed
+func DeleteCalculation(db *sql.DB, userId int, id int) (bool, error) {
+	res, err := db.Exec("DELETE FROM calculations WHERE id = ? AND userId = ?", id, userId)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete calculation: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	if err := DeleteTasks(db, id, false); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// GetUnfinishedCalculations returns the calculations of all users that are still pending or running
+func GetUnfinishedCalculations(db *sql.DB) ([]Calculation, error) {
+	rows, err := db.Query("SELECT "+calculationColumns+" FROM calculations WHERE status IN (?, ?) ORDER BY id",
+		StatusPending, StatusRunning)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calculations: %w", err)
+	}
+	defer rows.Close()
+
+	calculations := []Calculation{}
+	for rows.Next() {
+		calculation, err := scanCalculation(rows)
+		if err != nil {
+			return nil, err
+		}
+		calculations = append(calculations, *calculation)
+	}
+	return calculations, rows.Err()
+}
+
+// Status of a task, one operation of a calculation handed out to an agent
+const (
+	TaskQueued = "queued"
+	TaskLeased = "leased"
+	TaskDone   = "done"
+)
+
+// Task is one operation of a calculation. Path is the position of the operation
+// in the expression tree, e.g. "0.1" is the right operand of the left operand
+type Task struct {
+	Id           int64   `json:"id"`
+	ExpressionId int     `json:"expressionId"`
+	Path         string  `json:"path"`
+	Operation    string  `json:"operation"`
+	Left         float64 `json:"left"`
+	Right        float64 `json:"right"`
+	Status       string  `json:"status"`
+	Result       float64 `json:"result"`
+	Error        string  `json:"error"`
+	Attempts     int     `json:"attempts"`
+	AgentId      string  `json:"agentId"`
+	LeaseUntil   string  `json:"leaseUntil"`
+	CreatedAt    string  `json:"createdAt"`
+	UpdatedAt    string  `json:"updatedAt"`
+}
+
+const taskColumns = "id, expressionId, path, operation, leftValue, rightValue, status, result, error, attempts, agentId, leaseUntil, createdAt, updatedAt"
+
+func InsertTask(db *sql.DB, task Task) error {
+	createdAt := now()
+	_, err := db.Exec(
+		"INSERT INTO tasks ("+taskColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		task.Id, task.ExpressionId, task.Path, task.Operation, task.Left, task.Right, TaskQueued,
+		0, "", task.Attempts, "", "", createdAt, createdAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert task: %w", err)
+	}
+	return nil
+}
+
+// LeaseTask hands the task to an agent until leaseUntil
+func LeaseTask(db *sql.DB, id int64, agentId string, attempts int, leaseUntil time.Time) error {
+	_, err := db.Exec(
+		"UPDATE tasks SET status = ?, agentId = ?, attempts = ?, leaseUntil = ?, updatedAt = ? WHERE id = ?",
+		TaskLeased, agentId, attempts, leaseUntil.UTC().Format(time.RFC3339Nano), now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to lease task: %w", err)
+	}
+	return nil
+}
+
+// RequeueTask puts a task whose lease ran out back into the queue
+func RequeueTask(db *sql.DB, id int64) error {
+	_, err := db.Exec(
+		"UPDATE tasks SET status = ?, agentId = '', leaseUntil = '', updatedAt = ? WHERE id = ?",
+		TaskQueued, now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to requeue task: %w", err)
+	}
+	return nil
+}
+
+func CompleteTask(db *sql.DB, id int64, result float64, errorText string) error {
+	_, err := db.Exec(
+		"UPDATE tasks SET status = ?, result = ?, error = ?, leaseUntil = '', updatedAt = ? WHERE id = ?",
+		TaskDone, result, errorText, now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to complete task: %w", err)
+	}
+	return nil
+}
+
+// GetTasks returns the tasks of a calculation in the order they were created
+func GetTasks(db *sql.DB, expressionId int) ([]Task, error) {
+	rows, err := db.Query("SELECT "+taskColumns+" FROM tasks WHERE expressionId = ? ORDER BY id", expressionId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []Task{}
+	for rows.Next() {
+		var task Task
+		err := rows.Scan(&task.Id, &task.ExpressionId, &task.Path, &task.Operation, &task.Left, &task.Right,
+			&task.Status, &task.Result, &task.Error, &task.Attempts, &task.AgentId, &task.LeaseUntil,
+			&task.CreatedAt, &task.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// DeleteTasks removes the tasks of a calculation, all of them or only the unfinished ones
+func DeleteTasks(db *sql.DB, expressionId int, onlyUnfinished bool) error {
+	query := "DELETE FROM tasks WHERE expressionId = ?"
+	if onlyUnfinished {
+		query += " AND status != '" + TaskDone + "'"
+	}
+	if _, err := db.Exec(query, expressionId); err != nil {
+		return fmt.Errorf("failed to delete tasks: %w", err)
+	}
+	return nil
+}
+
+func GetMaxTaskId(db *sql.DB) (int64, error) {
+	var maxId sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(id) FROM tasks").Scan(&maxId); err != nil {
+		return 0, fmt.Errorf("failed to get max task id: %w", err)
+	}
+	return maxId.Int64, nil
+}
+
+// CachedResult is a result saved by the result cache, usedAt orders the entries
+// from the most to the least recently used
+type CachedResult struct {
+	Key    string
+	Result float64
+	UsedAt string
+}
+
+// SaveCachedResult saves the result or marks it as just used
+func SaveCachedResult(db *sql.DB, key string, result float64) error {
+	_, err := db.Exec("INSERT OR REPLACE INTO result_cache (key, result, usedAt) VALUES (?, ?, ?)", key, result, now())
+	if err != nil {
+		return fmt.Errorf("failed to save cached result: %w", err)
+	}
+	return nil
+}
+
+func DeleteCachedResult(db *sql.DB, key string) error {
+	if _, err := db.Exec("DELETE FROM result_cache WHERE key = ?", key); err != nil {
+		return fmt.Errorf("failed to delete cached result: %w", err)
+	}
+	return nil
+}
+
+// GetCachedResults returns up to limit results, the most recently used first
+func GetCachedResults(db *sql.DB, limit int) ([]CachedResult, error) {
+	rows, err := db.Query("SELECT key, result, usedAt FROM result_cache ORDER BY usedAt DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cached results: %w", err)
+	}
+	defer rows.Close()
+
+	results := []CachedResult{}
+	for rows.Next() {
+		var result CachedResult
+		if err := rows.Scan(&result.Key, &result.Result, &result.UsedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// Schedule runs an expression whenever its cron expression matches. Every run is
+// saved as a new calculation. The expression may use now() and the variables,
+// see calculate.Expand. Times are UTC in RFC 3339, nextRunAt is empty when it never runs again
+type Schedule struct {
+	Id                int                `json:"id"`
+	UserId            int                `json:"userId"`
+	Expression        string             `json:"expression"`
+	Variables         map[string]float64 `json:"variables"`
+	Cron              string             `json:"cron"`
+	CallbackUrl       string             `json:"callbackUrl,omitempty"`
+	Enabled           bool               `json:"enabled"`
+	NextRunAt         string             `json:"nextRunAt,omitempty"`
+	LastRunAt         string             `json:"lastRunAt,omitempty"`
+	LastCalculationId int                `json:"lastCalculationId,omitempty"`
+	LastError         string             `json:"lastError,omitempty"`
+	CreatedAt         string             `json:"createdAt"`
+	UpdatedAt         string             `json:"updatedAt"`
+}
+
+// ScheduleTime formats times of schedules, all of the same length so they compare as text
+func ScheduleTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+const scheduleColumns = "id, userId, expression, variables, cron, callbackUrl, enabled, nextRunAt, lastRunAt, lastCalculationId, lastError, createdAt, updatedAt"
+
+func InsertSchedule(db *sql.DB, schedule Schedule) (int, error) {
+	variables, err := json.Marshal(schedule.Variables)
+	if err != nil {
+		return 0, err
+	}
+	createdAt := now()
+	res, err := db.Exec(
+		"INSERT INTO schedules (userId, expression, variables, cron, callbackUrl, enabled, nextRunAt, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		schedule.UserId, schedule.Expression, string(variables), schedule.Cron, schedule.CallbackUrl,
+		schedule.Enabled, schedule.NextRunAt, createdAt, createdAt,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert schedule: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
 
+// UpdateSchedule saves the settings of a schedule of the user, it reports
+// whether the schedule exists
+func UpdateSchedule(db *sql.DB, schedule Schedule) (bool, error) {
+	variables, err := json.Marshal(schedule.Variables)
+	if err != nil {
+		return false, err
+	}
+	res, err := db.Exec(
+		"UPDATE schedules SET expression = ?, variables = ?, cron = ?, callbackUrl = ?, enabled = ?, nextRunAt = ?, updatedAt = ? WHERE id = ? AND userId = ?",
+		schedule.Expression, string(variables), schedule.Cron, schedule.CallbackUrl, schedule.Enabled,
+		schedule.NextRunAt, now(), schedule.Id, schedule.UserId,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update schedule: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// RecordScheduleRun saves when the schedule ran, the calculation it started
+// or why it could not, and when it runs next
+func RecordScheduleRun(db *sql.DB, id int, runAt string, nextRunAt string, calculationId int, errorText string) error {
+	_, err := db.Exec(
+		"UPDATE schedules SET lastRunAt = ?, nextRunAt = ?, lastCalculationId = ?, lastError = ?, updatedAt = ? WHERE id = ?",
+		runAt, nextRunAt, calculationId, errorText, now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record schedule run: %w", err)
+	}
+	return nil
+}
+
+// DeleteSchedule removes a schedule of the user, the calculations it started are kept
+func DeleteSchedule(db *sql.DB, userId int, id int) (bool, error) {
+	res, err := db.Exec("DELETE FROM schedules WHERE id = ? AND userId = ?", id, userId)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete schedule: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+func scanSchedule(row interface{ Scan(...any) error }) (*Schedule, error) {
+	var schedule Schedule
+	var variables string
+	err := row.Scan(&schedule.Id, &schedule.UserId, &schedule.Expression, &variables, &schedule.Cron,
+		&schedule.CallbackUrl, &schedule.Enabled, &schedule.NextRunAt, &schedule.LastRunAt,
+		&schedule.LastCalculationId, &schedule.LastError, &schedule.CreatedAt, &schedule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if variables != "" && variables != "null" {
+		if err := json.Unmarshal([]byte(variables), &schedule.Variables); err != nil {
+			return nil, fmt.Errorf("invalid variables of schedule %d: %w", schedule.Id, err)
+		}
+	}
+	return &schedule, nil
+}
+
+func querySchedules(db *sql.DB, query string, args ...any) ([]Schedule, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []Schedule{}
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *schedule)
+	}
+	return schedules, rows.Err()
+}
+
+// GetSchedule returns sql.ErrNoRows if the user has no such schedule
+func GetSchedule(db *sql.DB, userId int, id int) (*Schedule, error) {
+	row := db.QueryRow("SELECT "+scheduleColumns+" FROM schedules WHERE id = ? AND userId = ?", id, userId)
+	return scanSchedule(row)
+}
+
+func GetSchedulesByUserId(db *sql.DB, userId int) ([]Schedule, error) {
+	return querySchedules(db, "SELECT "+scheduleColumns+" FROM schedules WHERE userId = ? ORDER BY id", userId)
+}
+
+// GetDueSchedules returns the enabled schedules that should have run by now
+func GetDueSchedules(db *sql.DB, now time.Time) ([]Schedule, error) {
+	return querySchedules(db,
+		"SELECT "+scheduleColumns+" FROM schedules WHERE enabled = 1 AND nextRunAt != '' AND nextRunAt <= ? ORDER BY nextRunAt, id",
+		ScheduleTime(now))
+}
+
+// IsBusy tells if the error only came from another connection holding the lock,
+// so the same statement may work when it is tried again
+func IsBusy(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := err.Error()
+	return strings.Contains(message, "SQLITE_BUSY") || strings.Contains(message, "SQLITE_LOCKED")
+}
+
+// DeadLetter is an operation that failed for good, e.g. because no agent finished it
+// in all attempts. UserId and Expression are those of its calculation, requeuedAt
+// is set once the calculation was started again
+type DeadLetter struct {
+	Id           int     `json:"id"`
+	TaskId       int64   `json:"taskId"`
+	ExpressionId int     `json:"expressionId"`
+	UserId       int     `json:"userId"`
+	Expression   string  `json:"expression"`
+	Path         string  `json:"path"`
+	Operation    string  `json:"operation"`
+	Left         float64 `json:"left"`
+	Right        float64 `json:"right"`
+	Attempts     int     `json:"attempts"`
+	Error        string  `json:"error"`
+	CreatedAt    string  `json:"createdAt"`
+	RequeuedAt   string  `json:"requeuedAt,omitempty"`
+}
+
+const deadLetterColumns = "d.id, d.taskId, d.expressionId, COALESCE(c.userId, 0), COALESCE(c.calculation, ''), d.path, d.operation, d.leftValue, d.rightValue, d.attempts, d.error, d.createdAt, d.requeuedAt"
+
+func InsertDeadLetter(db *sql.DB, deadLetter DeadLetter) (int, error) {
+	res, err := db.Exec(
+		"INSERT INTO dead_letters (taskId, expressionId, path, operation, leftValue, rightValue, attempts, error, createdAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		deadLetter.TaskId, deadLetter.ExpressionId, deadLetter.Path, deadLetter.Operation,
+		deadLetter.Left, deadLetter.Right, deadLetter.Attempts, deadLetter.Error, now(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert dead letter: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func scanDeadLetter(row interface{ Scan(...any) error }) (*DeadLetter, error) {
+	var deadLetter DeadLetter
+	err := row.Scan(&deadLetter.Id, &deadLetter.TaskId, &deadLetter.ExpressionId, &deadLetter.UserId,
+		&deadLetter.Expression, &deadLetter.Path, &deadLetter.Operation, &deadLetter.Left, &deadLetter.Right,
+		&deadLetter.Attempts, &deadLetter.Error, &deadLetter.CreatedAt, &deadLetter.RequeuedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &deadLetter, nil
+}
+
+// GetDeadLetter returns sql.ErrNoRows if there is no such dead letter
+func GetDeadLetter(db *sql.DB, id int) (*DeadLetter, error) {
+	row := db.QueryRow("SELECT "+deadLetterColumns+" FROM dead_letters d LEFT JOIN calculations c ON c.id = d.expressionId WHERE d.id = ?", id)
+	return scanDeadLetter(row)
+}
+
+// GetDeadLetters returns the newest dead letters first, with pendingOnly
+// only those that were not requeued yet
+func GetDeadLetters(db *sql.DB, pendingOnly bool) ([]DeadLetter, error) {
+	query := "SELECT " + deadLetterColumns + " FROM dead_letters d LEFT JOIN calculations c ON c.id = d.expressionId"
+	if pendingOnly {
+		query += " WHERE d.requeuedAt = ''"
+	}
+	rows, err := db.Query(query + " ORDER BY d.id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	deadLetters := []DeadLetter{}
+	for rows.Next() {
+		deadLetter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		deadLetters = append(deadLetters, *deadLetter)
+	}
+	return deadLetters, rows.Err()
+}
+
+// MarkDeadLetterRequeued sets requeuedAt, it reports false if the dead letter
+// does not exist or was requeued before
+func MarkDeadLetterRequeued(db *sql.DB, id int) (bool, error) {
+	res, err := db.Exec("UPDATE dead_letters SET requeuedAt = ? WHERE id = ? AND requeuedAt = ''", now(), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue dead letter: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
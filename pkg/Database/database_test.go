@@ -3,7 +3,9 @@ package database
 import (
 	"database/sql"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
 func setupTestDB(t *testing.T) (*sql.DB, string) {
@@ -24,13 +26,104 @@ func setupTestDB(t *testing.T) (*sql.DB, string) {
 	}
 
 	err = CreateTable(db, "calculations", map[string]string{
-		"id":     "INTEGER PRIMARY KEY",
-		"userId": "INTEGER",
+		"id":          "INTEGER PRIMARY KEY",
+		"userId":      "INTEGER",
+		"calculation": "TEXT NOT NULL DEFAULT ''",
+		"result":      "REAL NOT NULL DEFAULT 0",
+		"status":      "TEXT NOT NULL DEFAULT 'done'",
+		"error":       "TEXT NOT NULL DEFAULT ''",
+		"createdAt":   "TEXT NOT NULL DEFAULT ''",
+		"updatedAt":   "TEXT NOT NULL DEFAULT ''",
+		"callbackUrl": "TEXT NOT NULL DEFAULT ''",
 	})
 	if err != nil {
 		t.Fatalf("Failed to create calculations table: %v", err)
 	}
 
+	err = CreateTable(db, "webhook_secrets", map[string]string{
+		"userId":    "INTEGER PRIMARY KEY",
+		"secret":    "TEXT NOT NULL",
+		"createdAt": "TEXT NOT NULL DEFAULT ''",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create webhook_secrets table: %v", err)
+	}
+
+	err = CreateTable(db, "schedules", map[string]string{
+		"id":                "INTEGER PRIMARY KEY AUTOINCREMENT",
+		"userId":            "INTEGER NOT NULL",
+		"expression":        "TEXT NOT NULL",
+		"variables":         "TEXT NOT NULL DEFAULT ''",
+		"cron":              "TEXT NOT NULL",
+		"callbackUrl":       "TEXT NOT NULL DEFAULT ''",
+		"enabled":           "INTEGER NOT NULL DEFAULT 1",
+		"nextRunAt":         "TEXT NOT NULL DEFAULT ''",
+		"lastRunAt":         "TEXT NOT NULL DEFAULT ''",
+		"lastCalculationId": "INTEGER NOT NULL DEFAULT 0",
+		"lastError":         "TEXT NOT NULL DEFAULT ''",
+		"createdAt":         "TEXT NOT NULL DEFAULT ''",
+		"updatedAt":         "TEXT NOT NULL DEFAULT ''",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create schedules table: %v", err)
+	}
+
+	err = CreateTable(db, "result_cache", map[string]string{
+		"key":    "TEXT PRIMARY KEY",
+		"result": "REAL NOT NULL",
+		"usedAt": "TEXT NOT NULL DEFAULT ''",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create result_cache table: %v", err)
+	}
+
+	err = CreateTable(db, "preferences", map[string]string{
+		"userId":       "INTEGER PRIMARY KEY",
+		"locale":       "TEXT NOT NULL DEFAULT ''",
+		"resultFormat": "TEXT NOT NULL DEFAULT ''",
+		"digits":       "INTEGER NOT NULL DEFAULT -1",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create preferences table: %v", err)
+	}
+
+	err = CreateTable(db, "tasks", map[string]string{
+		"id":           "INTEGER PRIMARY KEY",
+		"expressionId": "INTEGER NOT NULL",
+		"path":         "TEXT NOT NULL",
+		"operation":    "TEXT NOT NULL",
+		"leftValue":    "REAL NOT NULL",
+		"rightValue":   "REAL NOT NULL",
+		"status":       "TEXT NOT NULL DEFAULT 'queued'",
+		"result":       "REAL NOT NULL DEFAULT 0",
+		"error":        "TEXT NOT NULL DEFAULT ''",
+		"attempts":     "INTEGER NOT NULL DEFAULT 0",
+		"agentId":      "TEXT NOT NULL DEFAULT ''",
+		"leaseUntil":   "TEXT NOT NULL DEFAULT ''",
+		"createdAt":    "TEXT NOT NULL DEFAULT ''",
+		"updatedAt":    "TEXT NOT NULL DEFAULT ''",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tasks table: %v", err)
+	}
+
+	err = CreateTable(db, "dead_letters", map[string]string{
+		"id":           "INTEGER PRIMARY KEY AUTOINCREMENT",
+		"taskId":       "INTEGER NOT NULL",
+		"expressionId": "INTEGER NOT NULL",
+		"path":         "TEXT NOT NULL DEFAULT ''",
+		"operation":    "TEXT NOT NULL",
+		"leftValue":    "REAL NOT NULL",
+		"rightValue":   "REAL NOT NULL",
+		"attempts":     "INTEGER NOT NULL DEFAULT 0",
+		"error":        "TEXT NOT NULL DEFAULT ''",
+		"createdAt":    "TEXT NOT NULL DEFAULT ''",
+		"requeuedAt":   "TEXT NOT NULL DEFAULT ''",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create dead_letters table: %v", err)
+	}
+
 	return db, dbPath
 }
 
@@ -153,3 +246,461 @@ func TestGetMaxExpressionIdByUserId(t *testing.T) {
 		t.Fatalf("Expected max expression ID 10, got %d", maxID)
 	}
 }
+
+func TestPreferences(t *testing.T) {
+	db, dbPath := setupTestDB(t)
+	defer db.Close()
+
+	preferences, err := GetPreferences(dbPath, 4)
+	if err != nil {
+		t.Fatalf("GetPreferences failed: %v", err)
+	}
+	if preferences.Locale != "" {
+		t.Fatalf("Expected no locale, got %s", preferences.Locale)
+	}
+
+	if preferences.Digits != -1 {
+		t.Fatalf("Expected default digits -1, got %d", preferences.Digits)
+	}
+
+	err = SavePreferences(dbPath, Preferences{UserId: 4, Locale: "de-DE", ResultFormat: "fixed", Digits: 3})
+	if err != nil {
+		t.Fatalf("SavePreferences failed: %v", err)
+	}
+	preferences, err = GetPreferences(dbPath, 4)
+	if err != nil {
+		t.Fatalf("GetPreferences failed: %v", err)
+	}
+	if preferences.Locale != "de-DE" || preferences.ResultFormat != "fixed" || preferences.Digits != 3 {
+		t.Fatalf("Unexpected preferences: %+v", preferences)
+	}
+}
+
+func TestCalculationLifecycle(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	id, err := InsertCalculation(db, 8, "2+2", "")
+	if err != nil {
+		t.Fatalf("InsertCalculation failed: %v", err)
+	}
+	calculation, err := GetCalculation(db, 8, id)
+	if err != nil {
+		t.Fatalf("GetCalculation failed: %v", err)
+	}
+	if calculation.Status != StatusPending || calculation.CreatedAt == "" {
+		t.Fatalf("Unexpected new calculation: %+v", calculation)
+	}
+
+	err = UpdateCalculation(db, id, StatusDone, 4, "")
+	if err != nil {
+		t.Fatalf("UpdateCalculation failed: %v", err)
+	}
+	calculations, err := GetCalculationsByUserId(db, 8)
+	if err != nil {
+		t.Fatalf("GetCalculationsByUserId failed: %v", err)
+	}
+	if len(calculations) != 1 || calculations[0].Status != StatusDone || calculations[0].Result != 4 {
+		t.Fatalf("Unexpected calculations: %+v", calculations)
+	}
+
+	// Another user can not see it
+	_, err = GetCalculation(db, 9, id)
+	if err != sql.ErrNoRows {
+		t.Fatalf("Expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestCalculationPagesAndDelete(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ids := []int{}
+	for _, expression := range []string{"1+1", "2+2", "3+3"} {
+		id, err := InsertCalculation(db, 8, expression, "")
+		if err != nil {
+			t.Fatalf("InsertCalculation failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := UpdateCalculation(db, ids[1], StatusDone, 4, ""); err != nil {
+		t.Fatalf("UpdateCalculation failed: %v", err)
+	}
+
+	page, err := GetCalculationsPage(db, 8, 0, "", 2)
+	if err != nil || len(page) != 2 || page[0].Id != ids[0] || page[1].Id != ids[1] {
+		t.Fatalf("Unexpected first page: %+v (%v)", page, err)
+	}
+	page, err = GetCalculationsPage(db, 8, ids[1], "", 2)
+	if err != nil || len(page) != 1 || page[0].Id != ids[2] {
+		t.Fatalf("Unexpected second page: %+v (%v)", page, err)
+	}
+	page, err = GetCalculationsPage(db, 8, 0, StatusDone, 10)
+	if err != nil || len(page) != 1 || page[0].Id != ids[1] {
+		t.Fatalf("Unexpected done calculations: %+v (%v)", page, err)
+	}
+
+	// Only the owner can delete it, and only once
+	for _, c := range []struct {
+		userId int
+		want   bool
+	}{{9, false}, {8, true}, {8, false}} {
+		deleted, err := DeleteCalculation(db, c.userId, ids[0])
+		if err != nil || deleted != c.want {
+			t.Fatalf("DeleteCalculation by user %d: expected %v, got %v (%v)", c.userId, c.want, deleted, err)
+		}
+	}
+	if _, err := GetCalculation(db, 8, ids[0]); err != sql.ErrNoRows {
+		t.Fatalf("Expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestUpdateCalculationFrom(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	id, _ := InsertCalculation(db, 1, "1+1", "")
+	updated, err := UpdateCalculationFrom(db, id, []string{StatusPending, StatusRunning}, StatusCancelled, 0, "Cancelled")
+	if err != nil || !updated {
+		t.Fatalf("Expected the calculation to be cancelled, got %v %v", updated, err)
+	}
+
+	// A cancelled calculation is not finished afterwards
+	updated, err = UpdateCalculationFrom(db, id, []string{StatusRunning}, StatusDone, 2, "")
+	if err != nil || updated {
+		t.Fatalf("Expected no update, got %v %v", updated, err)
+	}
+	calculation, _ := GetCalculation(db, 1, id)
+	if calculation.Status != StatusCancelled || calculation.Error != "Cancelled" {
+		t.Fatalf("Unexpected calculation: %+v", calculation)
+	}
+}
+
+func TestGetUnfinishedCalculations(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	pending, _ := InsertCalculation(db, 1, "1+1", "")
+	done, _ := InsertCalculation(db, 2, "2+2", "")
+	running, _ := InsertCalculation(db, 3, "3+3", "")
+	UpdateCalculation(db, done, StatusDone, 4, "")
+	UpdateCalculation(db, running, StatusRunning, 0, "")
+
+	calculations, err := GetUnfinishedCalculations(db)
+	if err != nil {
+		t.Fatalf("GetUnfinishedCalculations failed: %v", err)
+	}
+	if len(calculations) != 2 || calculations[0].Id != pending || calculations[1].Id != running {
+		t.Fatalf("Unexpected calculations: %+v", calculations)
+	}
+}
+
+func TestTaskLifecycle(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	maxId, err := GetMaxTaskId(db)
+	if err != nil || maxId != 0 {
+		t.Fatalf("Expected no tasks, got %d %v", maxId, err)
+	}
+
+	if err := InsertTask(db, Task{Id: 1, ExpressionId: 5, Path: "0", Operation: "+", Left: 1, Right: 2}); err != nil {
+		t.Fatalf("InsertTask failed: %v", err)
+	}
+	if err := InsertTask(db, Task{Id: 2, ExpressionId: 5, Path: "1", Operation: "*", Left: 3, Right: 4}); err != nil {
+		t.Fatalf("InsertTask failed: %v", err)
+	}
+	if err := LeaseTask(db, 1, "agent-1", 1, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("LeaseTask failed: %v", err)
+	}
+	if err := CompleteTask(db, 1, 3, ""); err != nil {
+		t.Fatalf("CompleteTask failed: %v", err)
+	}
+	if err := LeaseTask(db, 2, "agent-1", 1, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("LeaseTask failed: %v", err)
+	}
+	if err := RequeueTask(db, 2); err != nil {
+		t.Fatalf("RequeueTask failed: %v", err)
+	}
+
+	tasks, err := GetTasks(db, 5)
+	if err != nil {
+		t.Fatalf("GetTasks failed: %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].Status != TaskDone || tasks[0].Result != 3 || tasks[0].AgentId != "agent-1" {
+		t.Fatalf("Unexpected tasks: %+v", tasks)
+	}
+	if tasks[1].Status != TaskQueued || tasks[1].Attempts != 1 || tasks[1].LeaseUntil != "" {
+		t.Fatalf("Unexpected requeued task: %+v", tasks[1])
+	}
+
+	maxId, _ = GetMaxTaskId(db)
+	if maxId != 2 {
+		t.Fatalf("Expected max id 2, got %d", maxId)
+	}
+
+	// Only the finished task survives
+	if err := DeleteTasks(db, 5, true); err != nil {
+		t.Fatalf("DeleteTasks failed: %v", err)
+	}
+	tasks, _ = GetTasks(db, 5)
+	if len(tasks) != 1 || tasks[0].Id != 1 {
+		t.Fatalf("Unexpected tasks: %+v", tasks)
+	}
+	DeleteTasks(db, 5, false)
+	tasks, _ = GetTasks(db, 5)
+	if len(tasks) != 0 {
+		t.Fatalf("Expected no tasks, got %+v", tasks)
+	}
+}
+
+func TestWebhookSecret(t *testing.T) {
+	db, dbPath := setupTestDB(t)
+	defer db.Close()
+
+	secret, err := GetWebhookSecret(dbPath, 3)
+	if err != nil || len(secret) != 64 {
+		t.Fatalf("Expected a new secret, got %q %v", secret, err)
+	}
+	again, _ := GetWebhookSecret(dbPath, 3)
+	if again != secret {
+		t.Fatalf("Expected the same secret, got %q", again)
+	}
+	rotated, err := RotateWebhookSecret(dbPath, 3)
+	if err != nil || rotated == secret {
+		t.Fatalf("Expected a new secret, got %q %v", rotated, err)
+	}
+	again, _ = GetWebhookSecret(dbPath, 3)
+	if again != rotated {
+		t.Fatalf("Expected the rotated secret, got %q", again)
+	}
+}
+
+func TestWebhookSecret_Concurrent(t *testing.T) {
+	db, dbPath := setupTestDB(t)
+	defer db.Close()
+
+	// Requests racing to create the secret all get the one that was saved
+	secrets := make(chan string, 8)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			secret, err := GetWebhookSecret(dbPath, 4)
+			if err != nil {
+				t.Errorf("Failed to get secret: %v", err)
+			}
+			secrets <- secret
+		}()
+	}
+	wg.Wait()
+	close(secrets)
+	saved, _ := GetWebhookSecret(dbPath, 4)
+	for secret := range secrets {
+		if secret != saved {
+			t.Fatalf("Expected %q, got %q", saved, secret)
+		}
+	}
+}
+
+func TestCalculationCallbackUrl(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	id, err := InsertCalculation(db, 1, "1+1", "http://example.com/hook")
+	if err != nil {
+		t.Fatalf("InsertCalculation failed: %v", err)
+	}
+	calculation, err := GetCalculationById(db, id)
+	if err != nil || calculation.CallbackUrl != "http://example.com/hook" || calculation.UserId != 1 {
+		t.Fatalf("Unexpected calculation: %+v %v", calculation, err)
+	}
+}
+
+func TestInsertCalculations(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	ids, err := InsertCalculations(db, 2, []NewCalculation{{Expression: "1+1"}, {Expression: "2+2", CallbackUrl: "http://example.com"}})
+	if err != nil {
+		t.Fatalf("InsertCalculations failed: %v", err)
+	}
+	calculations, _ := GetCalculationsByUserId(db, 2)
+	if len(ids) != 2 || len(calculations) != 2 || calculations[1].Id != ids[1] || calculations[1].CallbackUrl != "http://example.com" {
+		t.Fatalf("Unexpected calculations: %v %+v", ids, calculations)
+	}
+
+	// Nothing is saved if one insert fails
+	db.Exec("CREATE TRIGGER no_fail BEFORE INSERT ON calculations WHEN NEW.calculation = 'fail' BEGIN SELECT RAISE(ABORT, 'fail'); END")
+	_, err = InsertCalculations(db, 3, []NewCalculation{{Expression: "3+3"}, {Expression: "fail"}})
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+	calculations, _ = GetCalculationsByUserId(db, 3)
+	if len(calculations) != 0 {
+		t.Fatalf("Expected no calculations, got %+v", calculations)
+	}
+}
+
+func TestCachedResults(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	for _, key := range []string{"(1+2)", "(2*3)", "(4-1)"} {
+		if err := SaveCachedResult(db, key, float64(len(key))); err != nil {
+			t.Fatalf("SaveCachedResult failed: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// Using a result again makes it the most recent
+	if err := SaveCachedResult(db, "(1+2)", 3); err != nil {
+		t.Fatalf("SaveCachedResult failed: %v", err)
+	}
+	if err := DeleteCachedResult(db, "(2*3)"); err != nil {
+		t.Fatalf("DeleteCachedResult failed: %v", err)
+	}
+
+	results, err := GetCachedResults(db, 10)
+	if err != nil {
+		t.Fatalf("GetCachedResults failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Key != "(1+2)" || results[0].Result != 3 || results[1].Key != "(4-1)" {
+		t.Fatalf("Unexpected cached results: %+v", results)
+	}
+
+	results, err = GetCachedResults(db, 1)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Expected 1 cached result, got %+v (%v)", results, err)
+	}
+}
+
+func TestScheduleLifecycle(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	start := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	id, err := InsertSchedule(db, Schedule{
+		UserId:     3,
+		Expression: "now()+$x",
+		Variables:  map[string]float64{"x": 2},
+		Cron:       "* * * * *",
+		Enabled:    true,
+		NextRunAt:  ScheduleTime(start.Add(time.Minute)),
+	})
+	if err != nil {
+		t.Fatalf("InsertSchedule failed: %v", err)
+	}
+
+	due, err := GetDueSchedules(db, start)
+	if err != nil || len(due) != 0 {
+		t.Fatalf("Expected no due schedules, got %+v (%v)", due, err)
+	}
+	due, err = GetDueSchedules(db, start.Add(90*time.Second))
+	if err != nil || len(due) != 1 || due[0].Id != id || due[0].Variables["x"] != 2 {
+		t.Fatalf("Expected schedule %d to be due, got %+v (%v)", id, due, err)
+	}
+
+	err = RecordScheduleRun(db, id, ScheduleTime(start.Add(time.Minute)), ScheduleTime(start.Add(2*time.Minute)), 7, "")
+	if err != nil {
+		t.Fatalf("RecordScheduleRun failed: %v", err)
+	}
+	schedule, err := GetSchedule(db, 3, id)
+	if err != nil || schedule.LastCalculationId != 7 || schedule.NextRunAt != "2025-05-01T12:02:00Z" {
+		t.Fatalf("Unexpected schedule: %+v (%v)", schedule, err)
+	}
+
+	// Disabled schedules are never due, other users can not change them
+	schedule.Enabled = false
+	if updated, err := UpdateSchedule(db, *schedule); err != nil || !updated {
+		t.Fatalf("UpdateSchedule failed: %v", err)
+	}
+	due, _ = GetDueSchedules(db, start.Add(time.Hour))
+	if len(due) != 0 {
+		t.Fatalf("Expected no due schedules, got %+v", due)
+	}
+	schedule.UserId = 4
+	if updated, _ := UpdateSchedule(db, *schedule); updated {
+		t.Fatal("Updated the schedule of another user")
+	}
+
+	schedules, err := GetSchedulesByUserId(db, 3)
+	if err != nil || len(schedules) != 1 {
+		t.Fatalf("Expected 1 schedule, got %+v (%v)", schedules, err)
+	}
+	if deleted, err := DeleteSchedule(db, 3, id); err != nil || !deleted {
+		t.Fatalf("DeleteSchedule failed: %v", err)
+	}
+	if _, err := GetSchedule(db, 3, id); err != sql.ErrNoRows {
+		t.Fatalf("Expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	expressionId, err := InsertCalculation(db, 7, "1+2", "")
+	if err != nil {
+		t.Fatalf("InsertCalculation failed: %v", err)
+	}
+	id, err := InsertDeadLetter(db, DeadLetter{TaskId: 3, ExpressionId: expressionId, Path: "", Operation: "+", Left: 1, Right: 2, Attempts: 3, Error: "no agent"})
+	if err != nil {
+		t.Fatalf("InsertDeadLetter failed: %v", err)
+	}
+
+	deadLetter, err := GetDeadLetter(db, id)
+	if err != nil {
+		t.Fatalf("GetDeadLetter failed: %v", err)
+	}
+	if deadLetter.UserId != 7 || deadLetter.Expression != "1+2" || deadLetter.TaskId != 3 || deadLetter.Error != "no agent" {
+		t.Fatalf("Unexpected dead letter: %+v", deadLetter)
+	}
+	if _, err := GetDeadLetter(db, id+1); err != sql.ErrNoRows {
+		t.Fatalf("Expected sql.ErrNoRows, got %v", err)
+	}
+
+	// A dead letter is requeued only once
+	for i, want := range []bool{true, false} {
+		requeued, err := MarkDeadLetterRequeued(db, id)
+		if err != nil || requeued != want {
+			t.Fatalf("Requeue %d: expected %v, got %v (%v)", i, want, requeued, err)
+		}
+	}
+	all, err := GetDeadLetters(db, false)
+	if err != nil || len(all) != 1 || all[0].RequeuedAt == "" {
+		t.Fatalf("Unexpected dead letters: %+v (%v)", all, err)
+	}
+	pending, err := GetDeadLetters(db, true)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("Expected no pending dead letters, got %+v (%v)", pending, err)
+	}
+}
+
+func TestIsBusy(t *testing.T) {
+	db, path := setupTestDB(t)
+	defer db.Close()
+
+	// An open write transaction holds the lock, the other connection does not wait for it
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("INSERT INTO users (username, password) VALUES ('locker', '')"); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	other, err := OpenDatabase(path + "?_pragma=busy_timeout(0)")
+	if err != nil {
+		t.Fatalf("OpenDatabase failed: %v", err)
+	}
+	defer other.Close()
+
+	_, err = InsertCalculation(other, 1, "1+2", "")
+	if !IsBusy(err) {
+		t.Fatalf("Expected a busy error, got %v", err)
+	}
+	if IsBusy(nil) || IsBusy(sql.ErrNoRows) {
+		t.Fatal("Only lock errors are busy")
+	}
+}
@@ -19,7 +19,7 @@ func CreateJWT(userID int, role string, key string) (string, error) {
     token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
         "user_id": userID,
         "iat":     now.Unix(),
-		"nbf": now.Add(time.Second * 1).Unix(),
+		"nbf": now.Unix(),
 		"exp": now.Add(time.Minute * 120).Unix(),
 		"role":  role,
     })
@@ -2,14 +2,24 @@ package jwt
 
 import (
 	"database/sql"
+	"path/filepath"
 	"testing"
 
-	_ "github.com/ArteShow/Calculator/pkg/database"
+	database "github.com/ArteShow/Calculator/pkg/Database"
 	"github.com/stretchr/testify/assert"
 )
 
 func setupTestDB(t *testing.T) (*sql.DB, string) {
-	db, dbPath := setupTestDB(t)
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	t.Setenv("DB_PATH", dbPath)
+	db, err := database.OpenDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	_, err = db.Exec("CREATE TABLE jwt (id INTEGER PRIMARY KEY AUTOINCREMENT, key TEXT NOT NULL)")
+	if err != nil {
+		t.Fatalf("Failed to create jwt table: %v", err)
+	}
 	return db, dbPath
 }
 
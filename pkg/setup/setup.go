@@ -38,6 +38,71 @@ func Setup() {
 			"userId":      "INTEGER NOT NULL",
 			"calculation": "TEXT NOT NULL",
 			"result":      "TEXT NOT NULL",
+			"status":      "TEXT NOT NULL DEFAULT 'done'",
+			"error":       "TEXT NOT NULL DEFAULT ''",
+			"createdAt":   "TEXT NOT NULL DEFAULT ''",
+			"updatedAt":   "TEXT NOT NULL DEFAULT ''",
+			"callbackUrl": "TEXT NOT NULL DEFAULT ''",
+		},
+		"preferences": {
+			"userId":       "INTEGER PRIMARY KEY",
+			"locale":       "TEXT NOT NULL DEFAULT ''",
+			"resultFormat": "TEXT NOT NULL DEFAULT ''",
+			"digits":       "INTEGER NOT NULL DEFAULT -1",
+		},
+		"webhook_secrets": {
+			"userId":    "INTEGER PRIMARY KEY",
+			"secret":    "TEXT NOT NULL",
+			"createdAt": "TEXT NOT NULL DEFAULT ''",
+		},
+		"tasks": {
+			"id":           "INTEGER PRIMARY KEY",
+			"expressionId": "INTEGER NOT NULL",
+			"path":         "TEXT NOT NULL",
+			"operation":    "TEXT NOT NULL",
+			"leftValue":    "REAL NOT NULL",
+			"rightValue":   "REAL NOT NULL",
+			"status":       "TEXT NOT NULL DEFAULT 'queued'",
+			"result":       "REAL NOT NULL DEFAULT 0",
+			"error":        "TEXT NOT NULL DEFAULT ''",
+			"attempts":     "INTEGER NOT NULL DEFAULT 0",
+			"agentId":      "TEXT NOT NULL DEFAULT ''",
+			"leaseUntil":   "TEXT NOT NULL DEFAULT ''",
+			"createdAt":    "TEXT NOT NULL DEFAULT ''",
+			"updatedAt":    "TEXT NOT NULL DEFAULT ''",
+		},
+		"schedules": {
+			"id":                "INTEGER PRIMARY KEY AUTOINCREMENT",
+			"userId":            "INTEGER NOT NULL",
+			"expression":        "TEXT NOT NULL",
+			"variables":         "TEXT NOT NULL DEFAULT ''",
+			"cron":              "TEXT NOT NULL",
+			"callbackUrl":       "TEXT NOT NULL DEFAULT ''",
+			"enabled":           "INTEGER NOT NULL DEFAULT 1",
+			"nextRunAt":         "TEXT NOT NULL DEFAULT ''",
+			"lastRunAt":         "TEXT NOT NULL DEFAULT ''",
+			"lastCalculationId": "INTEGER NOT NULL DEFAULT 0",
+			"lastError":         "TEXT NOT NULL DEFAULT ''",
+			"createdAt":         "TEXT NOT NULL DEFAULT ''",
+			"updatedAt":         "TEXT NOT NULL DEFAULT ''",
+		},
+		"result_cache": {
+			"key":    "TEXT PRIMARY KEY",
+			"result": "REAL NOT NULL",
+			"usedAt": "TEXT NOT NULL DEFAULT ''",
+		},
+		"dead_letters": {
+			"id":           "INTEGER PRIMARY KEY AUTOINCREMENT",
+			"taskId":       "INTEGER NOT NULL",
+			"expressionId": "INTEGER NOT NULL",
+			"path":         "TEXT NOT NULL DEFAULT ''",
+			"operation":    "TEXT NOT NULL",
+			"leftValue":    "REAL NOT NULL",
+			"rightValue":   "REAL NOT NULL",
+			"attempts":     "INTEGER NOT NULL DEFAULT 0",
+			"error":        "TEXT NOT NULL DEFAULT ''",
+			"createdAt":    "TEXT NOT NULL DEFAULT ''",
+			"requeuedAt":   "TEXT NOT NULL DEFAULT ''",
 		},
 	}
 
@@ -47,6 +112,13 @@ func Setup() {
 		if err != nil {
 			log.Fatalf("❌ Failed to create table %s: %v", tableName, err)
 		}
+		// Tables from an older version miss the newer columns
+		for column, definition := range columns {
+			err = AddColumnIfNotExists(db, tableName, column, definition)
+			if err != nil {
+				log.Fatalf("❌ Failed to add column %s to table %s: %v", column, tableName, err)
+			}
+		}
 	}
 
 	// Generate the JWT key using the function
@@ -108,3 +180,33 @@ func CreateTable(db *sql.DB, tableName string, columns map[string]string) error
 	_, err := db.Exec(query)
 	return err
 }
+
+// AddColumnIfNotExists adds a column to an existing table. SQLite can not add
+// primary keys or unique columns this way, those have to be in the first version of the table
+func AddColumnIfNotExists(db *sql.DB, tableName string, column string, definition string) error {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", tableName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = db.Exec("ALTER TABLE " + tableName + " ADD COLUMN " + column + " " + definition)
+	if err != nil {
+		return fmt.Errorf("❌ failed to add column %s to %s: %w", column, tableName, err)
+	}
+	log.Printf("✅ Column %s added to table %s 😎", column, tableName)
+	return nil
+}
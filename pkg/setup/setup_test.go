@@ -5,7 +5,7 @@ import (
 	"os"
 	"testing"
 
-	database "github.com/ArteShow/Calculator/pkg/database"
+	database "github.com/ArteShow/Calculator/pkg/Database"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -73,9 +73,28 @@ func TestInsertIfNotExists(t *testing.T) {
 	assert.Equal(t, 1, count)
 }
 
+func TestAddColumnIfNotExists(t *testing.T) {
+	db, dbPath := setupTestDB(t)
+	defer db.Close()
+	defer os.Remove(dbPath)
+
+	err := CreateTable(db, "old_table", map[string]string{"id": "INTEGER PRIMARY KEY"})
+	assert.NoError(t, err)
+
+	err = AddColumnIfNotExists(db, "old_table", "name", "TEXT NOT NULL DEFAULT ''")
+	assert.NoError(t, err)
+	// Adding it twice is fine
+	err = AddColumnIfNotExists(db, "old_table", "name", "TEXT NOT NULL DEFAULT ''")
+	assert.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO old_table (id, name) VALUES (1, 'x')")
+	assert.NoError(t, err)
+}
+
 func TestSetup(t *testing.T) {
 	db, dbPath := setupTestDB(t)
 	defer db.Close()
+	t.Setenv("DB_PATH", dbPath)
 
 	// Run setup
 	Setup()
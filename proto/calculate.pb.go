@@ -26,6 +26,7 @@ type UserDataRequest struct {
 	UserId        int32                  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
 	CustomId      int32                  `protobuf:"varint,2,opt,name=customId,proto3" json:"customId,omitempty"`      // Optional: Expression ID to fetch one
 	Calculation   *Calculation           `protobuf:"bytes,3,opt,name=calculation,proto3" json:"calculation,omitempty"` // Optional: Send a new expression
+	Format        string                 `protobuf:"bytes,4,opt,name=format,proto3" json:"format,omitempty"`           // Optional: "latex", "mathml", "unicode" or "infix-minimal" when fetching one
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -81,9 +82,24 @@ func (x *UserDataRequest) GetCalculation() *Calculation {
 	return nil
 }
 
+func (x *UserDataRequest) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
 type UserDataResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Formatted     string                 `protobuf:"bytes,2,opt,name=formatted,proto3" json:"formatted,omitempty"` // Set when a format was requested
+	Id            int32                  `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty"`
+	Status        string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`   // "pending", "running", "done" or "failed"
+	Result        float64                `protobuf:"fixed64,5,opt,name=result,proto3" json:"result,omitempty"` // Set when the status is "done"
+	Error         string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`     // Set when the status is "failed"
+	Expression    string                 `protobuf:"bytes,7,opt,name=expression,proto3" json:"expression,omitempty"`
+	CreatedAt     string                 `protobuf:"bytes,8,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	UpdatedAt     string                 `protobuf:"bytes,9,opt,name=updatedAt,proto3" json:"updatedAt,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -125,10 +141,67 @@ func (x *UserDataResponse) GetMessage() string {
 	return ""
 }
 
+func (x *UserDataResponse) GetFormatted() string {
+	if x != nil {
+		return x.Formatted
+	}
+	return ""
+}
+
+func (x *UserDataResponse) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UserDataResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *UserDataResponse) GetResult() float64 {
+	if x != nil {
+		return x.Result
+	}
+	return 0
+}
+
+func (x *UserDataResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *UserDataResponse) GetExpression() string {
+	if x != nil {
+		return x.Expression
+	}
+	return ""
+}
+
+func (x *UserDataResponse) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+func (x *UserDataResponse) GetUpdatedAt() string {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return ""
+}
+
 type GetUserCalculationRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        int32                  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
 	CustomId      int32                  `protobuf:"varint,2,opt,name=customId,proto3" json:"customId,omitempty"`
+	Format        string                 `protobuf:"bytes,3,opt,name=format,proto3" json:"format,omitempty"` // Also typeset the expression: latex, mathml, unicode or infix-minimal
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -177,9 +250,23 @@ func (x *GetUserCalculationRequest) GetCustomId() int32 {
 	return 0
 }
 
+func (x *GetUserCalculationRequest) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
 type UserCalculationResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Expression    string                 `protobuf:"bytes,1,opt,name=expression,proto3" json:"expression,omitempty"`
+	Id            int32                  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Result        float64                `protobuf:"fixed64,4,opt,name=result,proto3" json:"result,omitempty"`
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	CreatedAt     string                 `protobuf:"bytes,6,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	UpdatedAt     string                 `protobuf:"bytes,7,opt,name=updatedAt,proto3" json:"updatedAt,omitempty"`
+	Formatted     string                 `protobuf:"bytes,8,opt,name=formatted,proto3" json:"formatted,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -221,6 +308,55 @@ func (x *UserCalculationResponse) GetExpression() string {
 	return ""
 }
 
+func (x *UserCalculationResponse) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UserCalculationResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *UserCalculationResponse) GetResult() float64 {
+	if x != nil {
+		return x.Result
+	}
+	return 0
+}
+
+func (x *UserCalculationResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *UserCalculationResponse) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+func (x *UserCalculationResponse) GetUpdatedAt() string {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return ""
+}
+
+func (x *UserCalculationResponse) GetFormatted() string {
+	if x != nil {
+		return x.Formatted
+	}
+	return ""
+}
+
 type UserIdRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        int32                  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
@@ -313,6 +449,11 @@ type Calculation struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Expression    string                 `protobuf:"bytes,1,opt,name=expression,proto3" json:"expression,omitempty"`
 	Result        float32                `protobuf:"fixed32,2,opt,name=result,proto3" json:"result,omitempty"`
+	Notation      string                 `protobuf:"bytes,3,opt,name=notation,proto3" json:"notation,omitempty"` // Optional: "infix" (default), "rpn" or "prefix"
+	Locale        string                 `protobuf:"bytes,4,opt,name=locale,proto3" json:"locale,omitempty"`     // Optional: how numbers are written, e.g. "de-DE" (default "en-US")
+	Id            int32                  `protobuf:"varint,5,opt,name=id,proto3" json:"id,omitempty"`
+	Status        string                 `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	CallbackUrl   string                 `protobuf:"bytes,7,opt,name=callbackUrl,proto3" json:"callbackUrl,omitempty"` // Optional: receives a signed POST when the calculation is finished
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -361,37 +502,1502 @@ func (x *Calculation) GetResult() float32 {
 	return 0
 }
 
-var File_proto_calculate_proto protoreflect.FileDescriptor
+func (x *Calculation) GetNotation() string {
+	if x != nil {
+		return x.Notation
+	}
+	return ""
+}
 
-const file_proto_calculate_proto_rawDesc = "" +
-	"\n" +
-	"\x15proto/calculate.proto\x12\x04user\"z\n" +
-	"\x0fUserDataRequest\x12\x16\n" +
-	"\x06userId\x18\x01 \x01(\x05R\x06userId\x12\x1a\n" +
-	"\bcustomId\x18\x02 \x01(\x05R\bcustomId\x123\n" +
-	"\vcalculation\x18\x03 \x01(\v2\x11.user.CalculationR\vcalculation\",\n" +
-	"\x10UserDataResponse\x12\x18\n" +
-	"\amessage\x18\x01 \x01(\tR\amessage\"O\n" +
-	"\x19GetUserCalculationRequest\x12\x16\n" +
-	"\x06userId\x18\x01 \x01(\x05R\x06userId\x12\x1a\n" +
-	"\bcustomId\x18\x02 \x01(\x05R\bcustomId\"9\n" +
-	"\x17UserCalculationResponse\x12\x1e\n" +
-	"\n" +
-	"expression\x18\x01 \x01(\tR\n" +
-	"expression\"'\n" +
-	"\rUserIdRequest\x12\x16\n" +
-	"\x06userId\x18\x01 \x01(\x05R\x06userId\"Q\n" +
-	"\x18UserCalculationsResponse\x125\n" +
-	"\fcalculations\x18\x01 \x03(\v2\x11.user.CalculationR\fcalculations\"E\n" +
-	"\vCalculation\x12\x1e\n" +
-	"\n" +
-	"expression\x18\x01 \x01(\tR\n" +
-	"expression\x12\x16\n" +
-	"\x06result\x18\x02 \x01(\x02R\x06result2\xee\x01\n" +
-	"\vUserService\x12=\n" +
-	"\fSendUserData\x12\x15.user.UserDataRequest\x1a\x16.user.UserDataResponse\x12T\n" +
-	"\x12GetUserCalculation\x12\x1f.user.GetUserCalculationRequest\x1a\x1d.user.UserCalculationResponse\x12J\n" +
-	"\x13GetUserCalculations\x12\x13.user.UserIdRequest\x1a\x1e.user.UserCalculationsResponseB\x0eZ\f./proto;userb\x06proto3"
+func (x *Calculation) GetLocale() string {
+	if x != nil {
+		return x.Locale
+	}
+	return ""
+}
+
+func (x *Calculation) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Calculation) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Calculation) GetCallbackUrl() string {
+	if x != nil {
+		return x.CallbackUrl
+	}
+	return ""
+}
+
+type CancelCalculationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int32                  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	Id            int32                  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelCalculationRequest) Reset() {
+	*x = CancelCalculationRequest{}
+	mi := &file_proto_calculate_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelCalculationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelCalculationRequest) ProtoMessage() {}
+
+func (x *CancelCalculationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelCalculationRequest.ProtoReflect.Descriptor instead.
+func (*CancelCalculationRequest) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CancelCalculationRequest) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *CancelCalculationRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type WatchCalculationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int32                  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	Id            int32                  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchCalculationRequest) Reset() {
+	*x = WatchCalculationRequest{}
+	mi := &file_proto_calculate_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchCalculationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchCalculationRequest) ProtoMessage() {}
+
+func (x *WatchCalculationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchCalculationRequest.ProtoReflect.Descriptor instead.
+func (*WatchCalculationRequest) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *WatchCalculationRequest) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *WatchCalculationRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type CalculationEvent struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type           string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"` // "status" when the status changed, "progress" when an operation was computed
+	Status         string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Result         float64                `protobuf:"fixed64,4,opt,name=result,proto3" json:"result,omitempty"` // Set when the status is done
+	Error          string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	Path           string                 `protobuf:"bytes,6,opt,name=path,proto3" json:"path,omitempty"`     // Position of the computed operation in the tree, e.g. "0.1"
+	Value          float64                `protobuf:"fixed64,7,opt,name=value,proto3" json:"value,omitempty"` // Result of the computed operation
+	CompletedTasks int32                  `protobuf:"varint,8,opt,name=completedTasks,proto3" json:"completedTasks,omitempty"`
+	TotalTasks     int32                  `protobuf:"varint,9,opt,name=totalTasks,proto3" json:"totalTasks,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CalculationEvent) Reset() {
+	*x = CalculationEvent{}
+	mi := &file_proto_calculate_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CalculationEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CalculationEvent) ProtoMessage() {}
+
+func (x *CalculationEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CalculationEvent.ProtoReflect.Descriptor instead.
+func (*CalculationEvent) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CalculationEvent) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *CalculationEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *CalculationEvent) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *CalculationEvent) GetResult() float64 {
+	if x != nil {
+		return x.Result
+	}
+	return 0
+}
+
+func (x *CalculationEvent) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CalculationEvent) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *CalculationEvent) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *CalculationEvent) GetCompletedTasks() int32 {
+	if x != nil {
+		return x.CompletedTasks
+	}
+	return 0
+}
+
+func (x *CalculationEvent) GetTotalTasks() int32 {
+	if x != nil {
+		return x.TotalTasks
+	}
+	return 0
+}
+
+type MetricsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MetricsRequest) Reset() {
+	*x = MetricsRequest{}
+	mi := &file_proto_calculate_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetricsRequest) ProtoMessage() {}
+
+func (x *MetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MetricsRequest.ProtoReflect.Descriptor instead.
+func (*MetricsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{10}
+}
+
+type MetricsResponse struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	Workers               int32                  `protobuf:"varint,1,opt,name=workers,proto3" json:"workers,omitempty"`
+	BusyWorkers           int32                  `protobuf:"varint,2,opt,name=busyWorkers,proto3" json:"busyWorkers,omitempty"`
+	Utilisation           float64                `protobuf:"fixed64,3,opt,name=utilisation,proto3" json:"utilisation,omitempty"` // busyWorkers / workers
+	QueueDepth            int32                  `protobuf:"varint,4,opt,name=queueDepth,proto3" json:"queueDepth,omitempty"`    // Calculations waiting for a worker
+	QueueCapacity         int32                  `protobuf:"varint,5,opt,name=queueCapacity,proto3" json:"queueCapacity,omitempty"`
+	Processed             int64                  `protobuf:"varint,6,opt,name=processed,proto3" json:"processed,omitempty"`
+	Rejected              int64                  `protobuf:"varint,7,opt,name=rejected,proto3" json:"rejected,omitempty"`                            // Calculations turned away because the queue was full
+	TaskQueueDepth        int32                  `protobuf:"varint,8,opt,name=taskQueueDepth,proto3" json:"taskQueueDepth,omitempty"`                // Operations waiting for an agent
+	TasksRunning          int32                  `protobuf:"varint,9,opt,name=tasksRunning,proto3" json:"tasksRunning,omitempty"`                    // Operations being computed by agents
+	InteractiveQueueDepth int32                  `protobuf:"varint,10,opt,name=interactiveQueueDepth,proto3" json:"interactiveQueueDepth,omitempty"` // Single calculations waiting for a worker
+	BatchQueueDepth       int32                  `protobuf:"varint,11,opt,name=batchQueueDepth,proto3" json:"batchQueueDepth,omitempty"`             // Batch and resumed calculations waiting for a worker
+	CacheEnabled          bool                   `protobuf:"varint,12,opt,name=cacheEnabled,proto3" json:"cacheEnabled,omitempty"`
+	CacheHits             int64                  `protobuf:"varint,13,opt,name=cacheHits,proto3" json:"cacheHits,omitempty"` // Expressions whose result was known already
+	CacheMisses           int64                  `protobuf:"varint,14,opt,name=cacheMisses,proto3" json:"cacheMisses,omitempty"`
+	CacheEntries          int32                  `protobuf:"varint,15,opt,name=cacheEntries,proto3" json:"cacheEntries,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *MetricsResponse) Reset() {
+	*x = MetricsResponse{}
+	mi := &file_proto_calculate_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MetricsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetricsResponse) ProtoMessage() {}
+
+func (x *MetricsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MetricsResponse.ProtoReflect.Descriptor instead.
+func (*MetricsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *MetricsResponse) GetWorkers() int32 {
+	if x != nil {
+		return x.Workers
+	}
+	return 0
+}
+
+func (x *MetricsResponse) GetBusyWorkers() int32 {
+	if x != nil {
+		return x.BusyWorkers
+	}
+	return 0
+}
+
+func (x *MetricsResponse) GetUtilisation() float64 {
+	if x != nil {
+		return x.Utilisation
+	}
+	return 0
+}
+
+func (x *MetricsResponse) GetQueueDepth() int32 {
+	if x != nil {
+		return x.QueueDepth
+	}
+	return 0
+}
+
+func (x *MetricsResponse) GetQueueCapacity() int32 {
+	if x != nil {
+		return x.QueueCapacity
+	}
+	return 0
+}
+
+func (x *MetricsResponse) GetProcessed() int64 {
+	if x != nil {
+		return x.Processed
+	}
+	return 0
+}
+
+func (x *MetricsResponse) GetRejected() int64 {
+	if x != nil {
+		return x.Rejected
+	}
+	return 0
+}
+
+func (x *MetricsResponse) GetTaskQueueDepth() int32 {
+	if x != nil {
+		return x.TaskQueueDepth
+	}
+	return 0
+}
+
+func (x *MetricsResponse) GetTasksRunning() int32 {
+	if x != nil {
+		return x.TasksRunning
+	}
+	return 0
+}
+
+func (x *MetricsResponse) GetInteractiveQueueDepth() int32 {
+	if x != nil {
+		return x.InteractiveQueueDepth
+	}
+	return 0
+}
+
+func (x *MetricsResponse) GetBatchQueueDepth() int32 {
+	if x != nil {
+		return x.BatchQueueDepth
+	}
+	return 0
+}
+
+func (x *MetricsResponse) GetCacheEnabled() bool {
+	if x != nil {
+		return x.CacheEnabled
+	}
+	return false
+}
+
+func (x *MetricsResponse) GetCacheHits() int64 {
+	if x != nil {
+		return x.CacheHits
+	}
+	return 0
+}
+
+func (x *MetricsResponse) GetCacheMisses() int64 {
+	if x != nil {
+		return x.CacheMisses
+	}
+	return 0
+}
+
+func (x *MetricsResponse) GetCacheEntries() int32 {
+	if x != nil {
+		return x.CacheEntries
+	}
+	return 0
+}
+
+type BatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int32                  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	Calculations  []*Calculation         `protobuf:"bytes,2,rep,name=calculations,proto3" json:"calculations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchRequest) Reset() {
+	*x = BatchRequest{}
+	mi := &file_proto_calculate_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchRequest) ProtoMessage() {}
+
+func (x *BatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchRequest.ProtoReflect.Descriptor instead.
+func (*BatchRequest) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *BatchRequest) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *BatchRequest) GetCalculations() []*Calculation {
+	if x != nil {
+		return x.Calculations
+	}
+	return nil
+}
+
+type BatchItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`  // Position of the expression in the request
+	Id            int32                  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`        // Set when the expression was saved
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"` // "pending" when saved, "rejected" otherwise
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`   // Why the expression was rejected
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchItem) Reset() {
+	*x = BatchItem{}
+	mi := &file_proto_calculate_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchItem) ProtoMessage() {}
+
+func (x *BatchItem) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchItem.ProtoReflect.Descriptor instead.
+func (*BatchItem) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *BatchItem) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *BatchItem) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *BatchItem) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *BatchItem) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type BatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*BatchItem           `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchResponse) Reset() {
+	*x = BatchResponse{}
+	mi := &file_proto_calculate_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchResponse) ProtoMessage() {}
+
+func (x *BatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchResponse.ProtoReflect.Descriptor instead.
+func (*BatchResponse) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *BatchResponse) GetItems() []*BatchItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type CalculationPlanRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int32                  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	Id            int32                  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CalculationPlanRequest) Reset() {
+	*x = CalculationPlanRequest{}
+	mi := &file_proto_calculate_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CalculationPlanRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CalculationPlanRequest) ProtoMessage() {}
+
+func (x *CalculationPlanRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CalculationPlanRequest.ProtoReflect.Descriptor instead.
+func (*CalculationPlanRequest) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *CalculationPlanRequest) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *CalculationPlanRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type PlanNode struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`                   // Position of the first use in the tree, e.g. "0.1"
+	Op            string                 `protobuf:"bytes,3,opt,name=op,proto3" json:"op,omitempty"`                       // "num" for numbers
+	Value         float64                `protobuf:"fixed64,4,opt,name=value,proto3" json:"value,omitempty"`               // Set when the node is done
+	DependsOn     []int32                `protobuf:"varint,5,rep,packed,name=dependsOn,proto3" json:"dependsOn,omitempty"` // Ids of the operands
+	Uses          int32                  `protobuf:"varint,6,opt,name=uses,proto3" json:"uses,omitempty"`                  // How often the result is used, more than 1 for shared sub-expressions
+	State         string                 `protobuf:"bytes,7,opt,name=state,proto3" json:"state,omitempty"`                 // "waiting", "queued", "running", "done" or "failed"
+	AgentId       string                 `protobuf:"bytes,8,opt,name=agentId,proto3" json:"agentId,omitempty"`
+	ReadyAt       string                 `protobuf:"bytes,9,opt,name=readyAt,proto3" json:"readyAt,omitempty"`
+	StartedAt     string                 `protobuf:"bytes,10,opt,name=startedAt,proto3" json:"startedAt,omitempty"`
+	FinishedAt    string                 `protobuf:"bytes,11,opt,name=finishedAt,proto3" json:"finishedAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlanNode) Reset() {
+	*x = PlanNode{}
+	mi := &file_proto_calculate_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlanNode) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlanNode) ProtoMessage() {}
+
+func (x *PlanNode) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlanNode.ProtoReflect.Descriptor instead.
+func (*PlanNode) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *PlanNode) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *PlanNode) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *PlanNode) GetOp() string {
+	if x != nil {
+		return x.Op
+	}
+	return ""
+}
+
+func (x *PlanNode) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *PlanNode) GetDependsOn() []int32 {
+	if x != nil {
+		return x.DependsOn
+	}
+	return nil
+}
+
+func (x *PlanNode) GetUses() int32 {
+	if x != nil {
+		return x.Uses
+	}
+	return 0
+}
+
+func (x *PlanNode) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *PlanNode) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *PlanNode) GetReadyAt() string {
+	if x != nil {
+		return x.ReadyAt
+	}
+	return ""
+}
+
+func (x *PlanNode) GetStartedAt() string {
+	if x != nil {
+		return x.StartedAt
+	}
+	return ""
+}
+
+func (x *PlanNode) GetFinishedAt() string {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return ""
+}
+
+type CalculationPlan struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status         string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Expression     string                 `protobuf:"bytes,3,opt,name=expression,proto3" json:"expression,omitempty"`
+	Live           bool                   `protobuf:"varint,4,opt,name=live,proto3" json:"live,omitempty"` // False when the plan is built from the expression alone, without states and timing
+	CompletedTasks int32                  `protobuf:"varint,5,opt,name=completedTasks,proto3" json:"completedTasks,omitempty"`
+	TotalTasks     int32                  `protobuf:"varint,6,opt,name=totalTasks,proto3" json:"totalTasks,omitempty"`
+	Nodes          []*PlanNode            `protobuf:"bytes,7,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CalculationPlan) Reset() {
+	*x = CalculationPlan{}
+	mi := &file_proto_calculate_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CalculationPlan) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CalculationPlan) ProtoMessage() {}
+
+func (x *CalculationPlan) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CalculationPlan.ProtoReflect.Descriptor instead.
+func (*CalculationPlan) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *CalculationPlan) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *CalculationPlan) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *CalculationPlan) GetExpression() string {
+	if x != nil {
+		return x.Expression
+	}
+	return ""
+}
+
+func (x *CalculationPlan) GetLive() bool {
+	if x != nil {
+		return x.Live
+	}
+	return false
+}
+
+func (x *CalculationPlan) GetCompletedTasks() int32 {
+	if x != nil {
+		return x.CompletedTasks
+	}
+	return 0
+}
+
+func (x *CalculationPlan) GetTotalTasks() int32 {
+	if x != nil {
+		return x.TotalTasks
+	}
+	return 0
+}
+
+func (x *CalculationPlan) GetNodes() []*PlanNode {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+type AgentsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentsRequest) Reset() {
+	*x = AgentsRequest{}
+	mi := &file_proto_calculate_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentsRequest) ProtoMessage() {}
+
+func (x *AgentsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentsRequest.ProtoReflect.Descriptor instead.
+func (*AgentsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{18}
+}
+
+type AgentStatus struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status         string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"` // "alive" or "dead"
+	Capacity       int32                  `protobuf:"varint,3,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	RunningTasks   int32                  `protobuf:"varint,4,opt,name=runningTasks,proto3" json:"runningTasks,omitempty"`
+	CompletedTasks int64                  `protobuf:"varint,5,opt,name=completedTasks,proto3" json:"completedTasks,omitempty"`
+	RegisteredAt   string                 `protobuf:"bytes,6,opt,name=registeredAt,proto3" json:"registeredAt,omitempty"`
+	LastSeenAt     string                 `protobuf:"bytes,7,opt,name=lastSeenAt,proto3" json:"lastSeenAt,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *AgentStatus) Reset() {
+	*x = AgentStatus{}
+	mi := &file_proto_calculate_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentStatus) ProtoMessage() {}
+
+func (x *AgentStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentStatus.ProtoReflect.Descriptor instead.
+func (*AgentStatus) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *AgentStatus) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *AgentStatus) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *AgentStatus) GetCapacity() int32 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *AgentStatus) GetRunningTasks() int32 {
+	if x != nil {
+		return x.RunningTasks
+	}
+	return 0
+}
+
+func (x *AgentStatus) GetCompletedTasks() int64 {
+	if x != nil {
+		return x.CompletedTasks
+	}
+	return 0
+}
+
+func (x *AgentStatus) GetRegisteredAt() string {
+	if x != nil {
+		return x.RegisteredAt
+	}
+	return ""
+}
+
+func (x *AgentStatus) GetLastSeenAt() string {
+	if x != nil {
+		return x.LastSeenAt
+	}
+	return ""
+}
+
+type AgentsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Agents        []*AgentStatus         `protobuf:"bytes,1,rep,name=agents,proto3" json:"agents,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentsResponse) Reset() {
+	*x = AgentsResponse{}
+	mi := &file_proto_calculate_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentsResponse) ProtoMessage() {}
+
+func (x *AgentsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentsResponse.ProtoReflect.Descriptor instead.
+func (*AgentsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *AgentsResponse) GetAgents() []*AgentStatus {
+	if x != nil {
+		return x.Agents
+	}
+	return nil
+}
+
+type DeadLettersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PendingOnly   bool                   `protobuf:"varint,1,opt,name=pendingOnly,proto3" json:"pendingOnly,omitempty"` // Leave out the dead letters that were requeued
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeadLettersRequest) Reset() {
+	*x = DeadLettersRequest{}
+	mi := &file_proto_calculate_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeadLettersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeadLettersRequest) ProtoMessage() {}
+
+func (x *DeadLettersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeadLettersRequest.ProtoReflect.Descriptor instead.
+func (*DeadLettersRequest) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *DeadLettersRequest) GetPendingOnly() bool {
+	if x != nil {
+		return x.PendingOnly
+	}
+	return false
+}
+
+type DeadLetter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	TaskId        int64                  `protobuf:"varint,2,opt,name=taskId,proto3" json:"taskId,omitempty"`
+	ExpressionId  int32                  `protobuf:"varint,3,opt,name=expressionId,proto3" json:"expressionId,omitempty"`
+	UserId        int32                  `protobuf:"varint,4,opt,name=userId,proto3" json:"userId,omitempty"`
+	Expression    string                 `protobuf:"bytes,5,opt,name=expression,proto3" json:"expression,omitempty"`
+	Path          string                 `protobuf:"bytes,6,opt,name=path,proto3" json:"path,omitempty"`
+	Operation     string                 `protobuf:"bytes,7,opt,name=operation,proto3" json:"operation,omitempty"`
+	Left          float64                `protobuf:"fixed64,8,opt,name=left,proto3" json:"left,omitempty"`
+	Right         float64                `protobuf:"fixed64,9,opt,name=right,proto3" json:"right,omitempty"`
+	Attempts      int32                  `protobuf:"varint,10,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	Error         string                 `protobuf:"bytes,11,opt,name=error,proto3" json:"error,omitempty"`
+	CreatedAt     string                 `protobuf:"bytes,12,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	RequeuedAt    string                 `protobuf:"bytes,13,opt,name=requeuedAt,proto3" json:"requeuedAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeadLetter) Reset() {
+	*x = DeadLetter{}
+	mi := &file_proto_calculate_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeadLetter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeadLetter) ProtoMessage() {}
+
+func (x *DeadLetter) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeadLetter.ProtoReflect.Descriptor instead.
+func (*DeadLetter) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *DeadLetter) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *DeadLetter) GetTaskId() int64 {
+	if x != nil {
+		return x.TaskId
+	}
+	return 0
+}
+
+func (x *DeadLetter) GetExpressionId() int32 {
+	if x != nil {
+		return x.ExpressionId
+	}
+	return 0
+}
+
+func (x *DeadLetter) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *DeadLetter) GetExpression() string {
+	if x != nil {
+		return x.Expression
+	}
+	return ""
+}
+
+func (x *DeadLetter) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *DeadLetter) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *DeadLetter) GetLeft() float64 {
+	if x != nil {
+		return x.Left
+	}
+	return 0
+}
+
+func (x *DeadLetter) GetRight() float64 {
+	if x != nil {
+		return x.Right
+	}
+	return 0
+}
+
+func (x *DeadLetter) GetAttempts() int32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+func (x *DeadLetter) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *DeadLetter) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+func (x *DeadLetter) GetRequeuedAt() string {
+	if x != nil {
+		return x.RequeuedAt
+	}
+	return ""
+}
+
+type DeadLettersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeadLetters   []*DeadLetter          `protobuf:"bytes,1,rep,name=deadLetters,proto3" json:"deadLetters,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeadLettersResponse) Reset() {
+	*x = DeadLettersResponse{}
+	mi := &file_proto_calculate_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeadLettersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeadLettersResponse) ProtoMessage() {}
+
+func (x *DeadLettersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeadLettersResponse.ProtoReflect.Descriptor instead.
+func (*DeadLettersResponse) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *DeadLettersResponse) GetDeadLetters() []*DeadLetter {
+	if x != nil {
+		return x.DeadLetters
+	}
+	return nil
+}
+
+type RequeueDeadLetterRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RequeueDeadLetterRequest) Reset() {
+	*x = RequeueDeadLetterRequest{}
+	mi := &file_proto_calculate_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RequeueDeadLetterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequeueDeadLetterRequest) ProtoMessage() {}
+
+func (x *RequeueDeadLetterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calculate_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequeueDeadLetterRequest.ProtoReflect.Descriptor instead.
+func (*RequeueDeadLetterRequest) Descriptor() ([]byte, []int) {
+	return file_proto_calculate_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *RequeueDeadLetterRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+var File_proto_calculate_proto protoreflect.FileDescriptor
+
+const file_proto_calculate_proto_rawDesc = "" +
+	"\n" +
+	"\x15proto/calculate.proto\x12\x04user\"\x92\x01\n" +
+	"\x0fUserDataRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\x05R\x06userId\x12\x1a\n" +
+	"\bcustomId\x18\x02 \x01(\x05R\bcustomId\x123\n" +
+	"\vcalculation\x18\x03 \x01(\v2\x11.user.CalculationR\vcalculation\x12\x16\n" +
+	"\x06format\x18\x04 \x01(\tR\x06format\"\xfc\x01\n" +
+	"\x10UserDataResponse\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12\x1c\n" +
+	"\tformatted\x18\x02 \x01(\tR\tformatted\x12\x0e\n" +
+	"\x02id\x18\x03 \x01(\x05R\x02id\x12\x16\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x12\x16\n" +
+	"\x06result\x18\x05 \x01(\x01R\x06result\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\x12\x1e\n" +
+	"\n" +
+	"expression\x18\a \x01(\tR\n" +
+	"expression\x12\x1c\n" +
+	"\tcreatedAt\x18\b \x01(\tR\tcreatedAt\x12\x1c\n" +
+	"\tupdatedAt\x18\t \x01(\tR\tupdatedAt\"g\n" +
+	"\x19GetUserCalculationRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\x05R\x06userId\x12\x1a\n" +
+	"\bcustomId\x18\x02 \x01(\x05R\bcustomId\x12\x16\n" +
+	"\x06format\x18\x03 \x01(\tR\x06format\"\xe9\x01\n" +
+	"\x17UserCalculationResponse\x12\x1e\n" +
+	"\n" +
+	"expression\x18\x01 \x01(\tR\n" +
+	"expression\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\x05R\x02id\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x16\n" +
+	"\x06result\x18\x04 \x01(\x01R\x06result\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x12\x1c\n" +
+	"\tcreatedAt\x18\x06 \x01(\tR\tcreatedAt\x12\x1c\n" +
+	"\tupdatedAt\x18\a \x01(\tR\tupdatedAt\x12\x1c\n" +
+	"\tformatted\x18\b \x01(\tR\tformatted\"'\n" +
+	"\rUserIdRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\x05R\x06userId\"Q\n" +
+	"\x18UserCalculationsResponse\x125\n" +
+	"\fcalculations\x18\x01 \x03(\v2\x11.user.CalculationR\fcalculations\"\xc3\x01\n" +
+	"\vCalculation\x12\x1e\n" +
+	"\n" +
+	"expression\x18\x01 \x01(\tR\n" +
+	"expression\x12\x16\n" +
+	"\x06result\x18\x02 \x01(\x02R\x06result\x12\x1a\n" +
+	"\bnotation\x18\x03 \x01(\tR\bnotation\x12\x16\n" +
+	"\x06locale\x18\x04 \x01(\tR\x06locale\x12\x0e\n" +
+	"\x02id\x18\x05 \x01(\x05R\x02id\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\tR\x06status\x12 \n" +
+	"\vcallbackUrl\x18\a \x01(\tR\vcallbackUrl\"B\n" +
+	"\x18CancelCalculationRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\x05R\x06userId\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\x05R\x02id\"A\n" +
+	"\x17WatchCalculationRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\x05R\x06userId\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\x05R\x02id\"\xee\x01\n" +
+	"\x10CalculationEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x16\n" +
+	"\x06result\x18\x04 \x01(\x01R\x06result\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x12\x12\n" +
+	"\x04path\x18\x06 \x01(\tR\x04path\x12\x14\n" +
+	"\x05value\x18\a \x01(\x01R\x05value\x12&\n" +
+	"\x0ecompletedTasks\x18\b \x01(\x05R\x0ecompletedTasks\x12\x1e\n" +
+	"\n" +
+	"totalTasks\x18\t \x01(\x05R\n" +
+	"totalTasks\"\x10\n" +
+	"\x0eMetricsRequest\"\xa3\x04\n" +
+	"\x0fMetricsResponse\x12\x18\n" +
+	"\aworkers\x18\x01 \x01(\x05R\aworkers\x12 \n" +
+	"\vbusyWorkers\x18\x02 \x01(\x05R\vbusyWorkers\x12 \n" +
+	"\vutilisation\x18\x03 \x01(\x01R\vutilisation\x12\x1e\n" +
+	"\n" +
+	"queueDepth\x18\x04 \x01(\x05R\n" +
+	"queueDepth\x12$\n" +
+	"\rqueueCapacity\x18\x05 \x01(\x05R\rqueueCapacity\x12\x1c\n" +
+	"\tprocessed\x18\x06 \x01(\x03R\tprocessed\x12\x1a\n" +
+	"\brejected\x18\a \x01(\x03R\brejected\x12&\n" +
+	"\x0etaskQueueDepth\x18\b \x01(\x05R\x0etaskQueueDepth\x12\"\n" +
+	"\ftasksRunning\x18\t \x01(\x05R\ftasksRunning\x124\n" +
+	"\x15interactiveQueueDepth\x18\n" +
+	" \x01(\x05R\x15interactiveQueueDepth\x12(\n" +
+	"\x0fbatchQueueDepth\x18\v \x01(\x05R\x0fbatchQueueDepth\x12\"\n" +
+	"\fcacheEnabled\x18\f \x01(\bR\fcacheEnabled\x12\x1c\n" +
+	"\tcacheHits\x18\r \x01(\x03R\tcacheHits\x12 \n" +
+	"\vcacheMisses\x18\x0e \x01(\x03R\vcacheMisses\x12\"\n" +
+	"\fcacheEntries\x18\x0f \x01(\x05R\fcacheEntries\"]\n" +
+	"\fBatchRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\x05R\x06userId\x125\n" +
+	"\fcalculations\x18\x02 \x03(\v2\x11.user.CalculationR\fcalculations\"_\n" +
+	"\tBatchItem\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\x05R\x02id\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"6\n" +
+	"\rBatchResponse\x12%\n" +
+	"\x05items\x18\x01 \x03(\v2\x0f.user.BatchItemR\x05items\"@\n" +
+	"\x16CalculationPlanRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\x05R\x06userId\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\x05R\x02id\"\x8e\x02\n" +
+	"\bPlanNode\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x0e\n" +
+	"\x02op\x18\x03 \x01(\tR\x02op\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\x01R\x05value\x12\x1c\n" +
+	"\tdependsOn\x18\x05 \x03(\x05R\tdependsOn\x12\x12\n" +
+	"\x04uses\x18\x06 \x01(\x05R\x04uses\x12\x14\n" +
+	"\x05state\x18\a \x01(\tR\x05state\x12\x18\n" +
+	"\aagentId\x18\b \x01(\tR\aagentId\x12\x18\n" +
+	"\areadyAt\x18\t \x01(\tR\areadyAt\x12\x1c\n" +
+	"\tstartedAt\x18\n" +
+	" \x01(\tR\tstartedAt\x12\x1e\n" +
+	"\n" +
+	"finishedAt\x18\v \x01(\tR\n" +
+	"finishedAt\"\xdb\x01\n" +
+	"\x0fCalculationPlan\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x1e\n" +
+	"\n" +
+	"expression\x18\x03 \x01(\tR\n" +
+	"expression\x12\x12\n" +
+	"\x04live\x18\x04 \x01(\bR\x04live\x12&\n" +
+	"\x0ecompletedTasks\x18\x05 \x01(\x05R\x0ecompletedTasks\x12\x1e\n" +
+	"\n" +
+	"totalTasks\x18\x06 \x01(\x05R\n" +
+	"totalTasks\x12$\n" +
+	"\x05nodes\x18\a \x03(\v2\x0e.user.PlanNodeR\x05nodes\"\x0f\n" +
+	"\rAgentsRequest\"\xe1\x01\n" +
+	"\vAgentStatus\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x1a\n" +
+	"\bcapacity\x18\x03 \x01(\x05R\bcapacity\x12\"\n" +
+	"\frunningTasks\x18\x04 \x01(\x05R\frunningTasks\x12&\n" +
+	"\x0ecompletedTasks\x18\x05 \x01(\x03R\x0ecompletedTasks\x12\"\n" +
+	"\fregisteredAt\x18\x06 \x01(\tR\fregisteredAt\x12\x1e\n" +
+	"\n" +
+	"lastSeenAt\x18\a \x01(\tR\n" +
+	"lastSeenAt\";\n" +
+	"\x0eAgentsResponse\x12)\n" +
+	"\x06agents\x18\x01 \x03(\v2\x11.user.AgentStatusR\x06agents\"6\n" +
+	"\x12DeadLettersRequest\x12 \n" +
+	"\vpendingOnly\x18\x01 \x01(\bR\vpendingOnly\"\xdc\x02\n" +
+	"\n" +
+	"DeadLetter\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x16\n" +
+	"\x06taskId\x18\x02 \x01(\x03R\x06taskId\x12\"\n" +
+	"\fexpressionId\x18\x03 \x01(\x05R\fexpressionId\x12\x16\n" +
+	"\x06userId\x18\x04 \x01(\x05R\x06userId\x12\x1e\n" +
+	"\n" +
+	"expression\x18\x05 \x01(\tR\n" +
+	"expression\x12\x12\n" +
+	"\x04path\x18\x06 \x01(\tR\x04path\x12\x1c\n" +
+	"\toperation\x18\a \x01(\tR\toperation\x12\x12\n" +
+	"\x04left\x18\b \x01(\x01R\x04left\x12\x14\n" +
+	"\x05right\x18\t \x01(\x01R\x05right\x12\x1a\n" +
+	"\battempts\x18\n" +
+	" \x01(\x05R\battempts\x12\x14\n" +
+	"\x05error\x18\v \x01(\tR\x05error\x12\x1c\n" +
+	"\tcreatedAt\x18\f \x01(\tR\tcreatedAt\x12\x1e\n" +
+	"\n" +
+	"requeuedAt\x18\r \x01(\tR\n" +
+	"requeuedAt\"I\n" +
+	"\x13DeadLettersResponse\x122\n" +
+	"\vdeadLetters\x18\x01 \x03(\v2\x10.user.DeadLetterR\vdeadLetters\"*\n" +
+	"\x18RequeueDeadLetterRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id2\x8a\x06\n" +
+	"\vUserService\x12=\n" +
+	"\fSendUserData\x12\x15.user.UserDataRequest\x1a\x16.user.UserDataResponse\x12T\n" +
+	"\x12GetUserCalculation\x12\x1f.user.GetUserCalculationRequest\x1a\x1d.user.UserCalculationResponse\x12J\n" +
+	"\x13GetUserCalculations\x12\x13.user.UserIdRequest\x1a\x1e.user.UserCalculationsResponse\x12K\n" +
+	"\x11CancelCalculation\x12\x1e.user.CancelCalculationRequest\x1a\x16.user.UserDataResponse\x12K\n" +
+	"\x10WatchCalculation\x12\x1d.user.WatchCalculationRequest\x1a\x16.user.CalculationEvent0\x01\x129\n" +
+	"\n" +
+	"GetMetrics\x12\x14.user.MetricsRequest\x1a\x15.user.MetricsResponse\x124\n" +
+	"\tSendBatch\x12\x12.user.BatchRequest\x1a\x13.user.BatchResponse\x12I\n" +
+	"\x12GetCalculationPlan\x12\x1c.user.CalculationPlanRequest\x1a\x15.user.CalculationPlan\x126\n" +
+	"\tGetAgents\x12\x13.user.AgentsRequest\x1a\x14.user.AgentsResponse\x12E\n" +
+	"\x0eGetDeadLetters\x12\x18.user.DeadLettersRequest\x1a\x19.user.DeadLettersResponse\x12E\n" +
+	"\x11RequeueDeadLetter\x12\x1e.user.RequeueDeadLetterRequest\x1a\x10.user.DeadLetterB\x0eZ\f./proto;userb\x06proto3"
 
 var (
 	file_proto_calculate_proto_rawDescOnce sync.Once
@@ -405,7 +2011,7 @@ func file_proto_calculate_proto_rawDescGZIP() []byte {
 	return file_proto_calculate_proto_rawDescData
 }
 
-var file_proto_calculate_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_proto_calculate_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
 var file_proto_calculate_proto_goTypes = []any{
 	(*UserDataRequest)(nil),           // 0: user.UserDataRequest
 	(*UserDataResponse)(nil),          // 1: user.UserDataResponse
@@ -414,21 +2020,60 @@ var file_proto_calculate_proto_goTypes = []any{
 	(*UserIdRequest)(nil),             // 4: user.UserIdRequest
 	(*UserCalculationsResponse)(nil),  // 5: user.UserCalculationsResponse
 	(*Calculation)(nil),               // 6: user.Calculation
+	(*CancelCalculationRequest)(nil),  // 7: user.CancelCalculationRequest
+	(*WatchCalculationRequest)(nil),   // 8: user.WatchCalculationRequest
+	(*CalculationEvent)(nil),          // 9: user.CalculationEvent
+	(*MetricsRequest)(nil),            // 10: user.MetricsRequest
+	(*MetricsResponse)(nil),           // 11: user.MetricsResponse
+	(*BatchRequest)(nil),              // 12: user.BatchRequest
+	(*BatchItem)(nil),                 // 13: user.BatchItem
+	(*BatchResponse)(nil),             // 14: user.BatchResponse
+	(*CalculationPlanRequest)(nil),    // 15: user.CalculationPlanRequest
+	(*PlanNode)(nil),                  // 16: user.PlanNode
+	(*CalculationPlan)(nil),           // 17: user.CalculationPlan
+	(*AgentsRequest)(nil),             // 18: user.AgentsRequest
+	(*AgentStatus)(nil),               // 19: user.AgentStatus
+	(*AgentsResponse)(nil),            // 20: user.AgentsResponse
+	(*DeadLettersRequest)(nil),        // 21: user.DeadLettersRequest
+	(*DeadLetter)(nil),                // 22: user.DeadLetter
+	(*DeadLettersResponse)(nil),       // 23: user.DeadLettersResponse
+	(*RequeueDeadLetterRequest)(nil),  // 24: user.RequeueDeadLetterRequest
 }
 var file_proto_calculate_proto_depIdxs = []int32{
-	6, // 0: user.UserDataRequest.calculation:type_name -> user.Calculation
-	6, // 1: user.UserCalculationsResponse.calculations:type_name -> user.Calculation
-	0, // 2: user.UserService.SendUserData:input_type -> user.UserDataRequest
-	2, // 3: user.UserService.GetUserCalculation:input_type -> user.GetUserCalculationRequest
-	4, // 4: user.UserService.GetUserCalculations:input_type -> user.UserIdRequest
-	1, // 5: user.UserService.SendUserData:output_type -> user.UserDataResponse
-	3, // 6: user.UserService.GetUserCalculation:output_type -> user.UserCalculationResponse
-	5, // 7: user.UserService.GetUserCalculations:output_type -> user.UserCalculationsResponse
-	5, // [5:8] is the sub-list for method output_type
-	2, // [2:5] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	6,  // 0: user.UserDataRequest.calculation:type_name -> user.Calculation
+	6,  // 1: user.UserCalculationsResponse.calculations:type_name -> user.Calculation
+	6,  // 2: user.BatchRequest.calculations:type_name -> user.Calculation
+	13, // 3: user.BatchResponse.items:type_name -> user.BatchItem
+	16, // 4: user.CalculationPlan.nodes:type_name -> user.PlanNode
+	19, // 5: user.AgentsResponse.agents:type_name -> user.AgentStatus
+	22, // 6: user.DeadLettersResponse.deadLetters:type_name -> user.DeadLetter
+	0,  // 7: user.UserService.SendUserData:input_type -> user.UserDataRequest
+	2,  // 8: user.UserService.GetUserCalculation:input_type -> user.GetUserCalculationRequest
+	4,  // 9: user.UserService.GetUserCalculations:input_type -> user.UserIdRequest
+	7,  // 10: user.UserService.CancelCalculation:input_type -> user.CancelCalculationRequest
+	8,  // 11: user.UserService.WatchCalculation:input_type -> user.WatchCalculationRequest
+	10, // 12: user.UserService.GetMetrics:input_type -> user.MetricsRequest
+	12, // 13: user.UserService.SendBatch:input_type -> user.BatchRequest
+	15, // 14: user.UserService.GetCalculationPlan:input_type -> user.CalculationPlanRequest
+	18, // 15: user.UserService.GetAgents:input_type -> user.AgentsRequest
+	21, // 16: user.UserService.GetDeadLetters:input_type -> user.DeadLettersRequest
+	24, // 17: user.UserService.RequeueDeadLetter:input_type -> user.RequeueDeadLetterRequest
+	1,  // 18: user.UserService.SendUserData:output_type -> user.UserDataResponse
+	3,  // 19: user.UserService.GetUserCalculation:output_type -> user.UserCalculationResponse
+	5,  // 20: user.UserService.GetUserCalculations:output_type -> user.UserCalculationsResponse
+	1,  // 21: user.UserService.CancelCalculation:output_type -> user.UserDataResponse
+	9,  // 22: user.UserService.WatchCalculation:output_type -> user.CalculationEvent
+	11, // 23: user.UserService.GetMetrics:output_type -> user.MetricsResponse
+	14, // 24: user.UserService.SendBatch:output_type -> user.BatchResponse
+	17, // 25: user.UserService.GetCalculationPlan:output_type -> user.CalculationPlan
+	20, // 26: user.UserService.GetAgents:output_type -> user.AgentsResponse
+	23, // 27: user.UserService.GetDeadLetters:output_type -> user.DeadLettersResponse
+	22, // 28: user.UserService.RequeueDeadLetter:output_type -> user.DeadLetter
+	18, // [18:29] is the sub-list for method output_type
+	7,  // [7:18] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_proto_calculate_proto_init() }
@@ -442,7 +2087,7 @@ func file_proto_calculate_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_calculate_proto_rawDesc), len(file_proto_calculate_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   25,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -22,6 +22,14 @@ const (
 	UserService_SendUserData_FullMethodName        = "/user.UserService/SendUserData"
 	UserService_GetUserCalculation_FullMethodName  = "/user.UserService/GetUserCalculation"
 	UserService_GetUserCalculations_FullMethodName = "/user.UserService/GetUserCalculations"
+	UserService_CancelCalculation_FullMethodName   = "/user.UserService/CancelCalculation"
+	UserService_WatchCalculation_FullMethodName    = "/user.UserService/WatchCalculation"
+	UserService_GetMetrics_FullMethodName          = "/user.UserService/GetMetrics"
+	UserService_SendBatch_FullMethodName           = "/user.UserService/SendBatch"
+	UserService_GetCalculationPlan_FullMethodName  = "/user.UserService/GetCalculationPlan"
+	UserService_GetAgents_FullMethodName           = "/user.UserService/GetAgents"
+	UserService_GetDeadLetters_FullMethodName      = "/user.UserService/GetDeadLetters"
+	UserService_RequeueDeadLetter_FullMethodName   = "/user.UserService/RequeueDeadLetter"
 )
 
 // UserServiceClient is the client API for UserService service.
@@ -34,6 +42,22 @@ type UserServiceClient interface {
 	GetUserCalculation(ctx context.Context, in *GetUserCalculationRequest, opts ...grpc.CallOption) (*UserCalculationResponse, error)
 	// Optional: if you want a separate endpoint just for fetching ALL calculations
 	GetUserCalculations(ctx context.Context, in *UserIdRequest, opts ...grpc.CallOption) (*UserCalculationsResponse, error)
+	// Stops a pending or running calculation
+	CancelCalculation(ctx context.Context, in *CancelCalculationRequest, opts ...grpc.CallOption) (*UserDataResponse, error)
+	// Streams the status changes and partial results of a calculation until it is finished
+	WatchCalculation(ctx context.Context, in *WatchCalculationRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CalculationEvent], error)
+	// Load of the worker pool and the task queue
+	GetMetrics(ctx context.Context, in *MetricsRequest, opts ...grpc.CallOption) (*MetricsResponse, error)
+	// Saves several expressions at once, every item succeeds or fails on its own
+	SendBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error)
+	// Shows how a calculation is split into operations, for debugging
+	GetCalculationPlan(ctx context.Context, in *CalculationPlanRequest, opts ...grpc.CallOption) (*CalculationPlan, error)
+	// Agents known to the orchestrator and whether they are alive
+	GetAgents(ctx context.Context, in *AgentsRequest, opts ...grpc.CallOption) (*AgentsResponse, error)
+	// Operations that failed for good, newest first
+	GetDeadLetters(ctx context.Context, in *DeadLettersRequest, opts ...grpc.CallOption) (*DeadLettersResponse, error)
+	// Starts the failed calculation of a dead letter again
+	RequeueDeadLetter(ctx context.Context, in *RequeueDeadLetterRequest, opts ...grpc.CallOption) (*DeadLetter, error)
 }
 
 type userServiceClient struct {
@@ -74,6 +98,95 @@ func (c *userServiceClient) GetUserCalculations(ctx context.Context, in *UserIdR
 	return out, nil
 }
 
+func (c *userServiceClient) CancelCalculation(ctx context.Context, in *CancelCalculationRequest, opts ...grpc.CallOption) (*UserDataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UserDataResponse)
+	err := c.cc.Invoke(ctx, UserService_CancelCalculation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) WatchCalculation(ctx context.Context, in *WatchCalculationRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CalculationEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &UserService_ServiceDesc.Streams[0], UserService_WatchCalculation_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchCalculationRequest, CalculationEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type UserService_WatchCalculationClient = grpc.ServerStreamingClient[CalculationEvent]
+
+func (c *userServiceClient) GetMetrics(ctx context.Context, in *MetricsRequest, opts ...grpc.CallOption) (*MetricsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MetricsResponse)
+	err := c.cc.Invoke(ctx, UserService_GetMetrics_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) SendBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchResponse)
+	err := c.cc.Invoke(ctx, UserService_SendBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetCalculationPlan(ctx context.Context, in *CalculationPlanRequest, opts ...grpc.CallOption) (*CalculationPlan, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CalculationPlan)
+	err := c.cc.Invoke(ctx, UserService_GetCalculationPlan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetAgents(ctx context.Context, in *AgentsRequest, opts ...grpc.CallOption) (*AgentsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AgentsResponse)
+	err := c.cc.Invoke(ctx, UserService_GetAgents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetDeadLetters(ctx context.Context, in *DeadLettersRequest, opts ...grpc.CallOption) (*DeadLettersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeadLettersResponse)
+	err := c.cc.Invoke(ctx, UserService_GetDeadLetters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) RequeueDeadLetter(ctx context.Context, in *RequeueDeadLetterRequest, opts ...grpc.CallOption) (*DeadLetter, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeadLetter)
+	err := c.cc.Invoke(ctx, UserService_RequeueDeadLetter_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // UserServiceServer is the server API for UserService service.
 // All implementations must embed UnimplementedUserServiceServer
 // for forward compatibility.
@@ -84,6 +197,22 @@ type UserServiceServer interface {
 	GetUserCalculation(context.Context, *GetUserCalculationRequest) (*UserCalculationResponse, error)
 	// Optional: if you want a separate endpoint just for fetching ALL calculations
 	GetUserCalculations(context.Context, *UserIdRequest) (*UserCalculationsResponse, error)
+	// Stops a pending or running calculation
+	CancelCalculation(context.Context, *CancelCalculationRequest) (*UserDataResponse, error)
+	// Streams the status changes and partial results of a calculation until it is finished
+	WatchCalculation(*WatchCalculationRequest, grpc.ServerStreamingServer[CalculationEvent]) error
+	// Load of the worker pool and the task queue
+	GetMetrics(context.Context, *MetricsRequest) (*MetricsResponse, error)
+	// Saves several expressions at once, every item succeeds or fails on its own
+	SendBatch(context.Context, *BatchRequest) (*BatchResponse, error)
+	// Shows how a calculation is split into operations, for debugging
+	GetCalculationPlan(context.Context, *CalculationPlanRequest) (*CalculationPlan, error)
+	// Agents known to the orchestrator and whether they are alive
+	GetAgents(context.Context, *AgentsRequest) (*AgentsResponse, error)
+	// Operations that failed for good, newest first
+	GetDeadLetters(context.Context, *DeadLettersRequest) (*DeadLettersResponse, error)
+	// Starts the failed calculation of a dead letter again
+	RequeueDeadLetter(context.Context, *RequeueDeadLetterRequest) (*DeadLetter, error)
 	mustEmbedUnimplementedUserServiceServer()
 }
 
@@ -103,6 +232,30 @@ func (UnimplementedUserServiceServer) GetUserCalculation(context.Context, *GetUs
 func (UnimplementedUserServiceServer) GetUserCalculations(context.Context, *UserIdRequest) (*UserCalculationsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetUserCalculations not implemented")
 }
+func (UnimplementedUserServiceServer) CancelCalculation(context.Context, *CancelCalculationRequest) (*UserDataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelCalculation not implemented")
+}
+func (UnimplementedUserServiceServer) WatchCalculation(*WatchCalculationRequest, grpc.ServerStreamingServer[CalculationEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchCalculation not implemented")
+}
+func (UnimplementedUserServiceServer) GetMetrics(context.Context, *MetricsRequest) (*MetricsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetrics not implemented")
+}
+func (UnimplementedUserServiceServer) SendBatch(context.Context, *BatchRequest) (*BatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendBatch not implemented")
+}
+func (UnimplementedUserServiceServer) GetCalculationPlan(context.Context, *CalculationPlanRequest) (*CalculationPlan, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCalculationPlan not implemented")
+}
+func (UnimplementedUserServiceServer) GetAgents(context.Context, *AgentsRequest) (*AgentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAgents not implemented")
+}
+func (UnimplementedUserServiceServer) GetDeadLetters(context.Context, *DeadLettersRequest) (*DeadLettersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeadLetters not implemented")
+}
+func (UnimplementedUserServiceServer) RequeueDeadLetter(context.Context, *RequeueDeadLetterRequest) (*DeadLetter, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequeueDeadLetter not implemented")
+}
 func (UnimplementedUserServiceServer) mustEmbedUnimplementedUserServiceServer() {}
 func (UnimplementedUserServiceServer) testEmbeddedByValue()                     {}
 
@@ -178,6 +331,143 @@ func _UserService_GetUserCalculations_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserService_CancelCalculation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelCalculationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).CancelCalculation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_CancelCalculation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).CancelCalculation(ctx, req.(*CancelCalculationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_WatchCalculation_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchCalculationRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UserServiceServer).WatchCalculation(m, &grpc.GenericServerStream[WatchCalculationRequest, CalculationEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type UserService_WatchCalculationServer = grpc.ServerStreamingServer[CalculationEvent]
+
+func _UserService_GetMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetMetrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetMetrics(ctx, req.(*MetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_SendBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).SendBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_SendBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).SendBatch(ctx, req.(*BatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetCalculationPlan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CalculationPlanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetCalculationPlan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetCalculationPlan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetCalculationPlan(ctx, req.(*CalculationPlanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetAgents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AgentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetAgents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetAgents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetAgents(ctx, req.(*AgentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeadLettersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetDeadLetters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetDeadLetters(ctx, req.(*DeadLettersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_RequeueDeadLetter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequeueDeadLetterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).RequeueDeadLetter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_RequeueDeadLetter_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).RequeueDeadLetter(ctx, req.(*RequeueDeadLetterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // UserService_ServiceDesc is the grpc.ServiceDesc for UserService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -197,7 +487,41 @@ var UserService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetUserCalculations",
 			Handler:    _UserService_GetUserCalculations_Handler,
 		},
+		{
+			MethodName: "CancelCalculation",
+			Handler:    _UserService_CancelCalculation_Handler,
+		},
+		{
+			MethodName: "GetMetrics",
+			Handler:    _UserService_GetMetrics_Handler,
+		},
+		{
+			MethodName: "SendBatch",
+			Handler:    _UserService_SendBatch_Handler,
+		},
+		{
+			MethodName: "GetCalculationPlan",
+			Handler:    _UserService_GetCalculationPlan_Handler,
+		},
+		{
+			MethodName: "GetAgents",
+			Handler:    _UserService_GetAgents_Handler,
+		},
+		{
+			MethodName: "GetDeadLetters",
+			Handler:    _UserService_GetDeadLetters_Handler,
+		},
+		{
+			MethodName: "RequeueDeadLetter",
+			Handler:    _UserService_RequeueDeadLetter_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchCalculation",
+			Handler:       _UserService_WatchCalculation_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/calculate.proto",
 }
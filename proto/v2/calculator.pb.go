@@ -0,0 +1,641 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v6.30.2
+// source: proto/v2/calculator.proto
+
+package calculatorv2
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ExpressionStatus int32
+
+const (
+	ExpressionStatus_EXPRESSION_STATUS_UNSPECIFIED ExpressionStatus = 0
+	ExpressionStatus_EXPRESSION_STATUS_PENDING     ExpressionStatus = 1
+	ExpressionStatus_EXPRESSION_STATUS_RUNNING     ExpressionStatus = 2
+	ExpressionStatus_EXPRESSION_STATUS_DONE        ExpressionStatus = 3
+	ExpressionStatus_EXPRESSION_STATUS_FAILED      ExpressionStatus = 4
+	ExpressionStatus_EXPRESSION_STATUS_CANCELLED   ExpressionStatus = 5
+)
+
+// Enum value maps for ExpressionStatus.
+var (
+	ExpressionStatus_name = map[int32]string{
+		0: "EXPRESSION_STATUS_UNSPECIFIED",
+		1: "EXPRESSION_STATUS_PENDING",
+		2: "EXPRESSION_STATUS_RUNNING",
+		3: "EXPRESSION_STATUS_DONE",
+		4: "EXPRESSION_STATUS_FAILED",
+		5: "EXPRESSION_STATUS_CANCELLED",
+	}
+	ExpressionStatus_value = map[string]int32{
+		"EXPRESSION_STATUS_UNSPECIFIED": 0,
+		"EXPRESSION_STATUS_PENDING":     1,
+		"EXPRESSION_STATUS_RUNNING":     2,
+		"EXPRESSION_STATUS_DONE":        3,
+		"EXPRESSION_STATUS_FAILED":      4,
+		"EXPRESSION_STATUS_CANCELLED":   5,
+	}
+)
+
+func (x ExpressionStatus) Enum() *ExpressionStatus {
+	p := new(ExpressionStatus)
+	*p = x
+	return p
+}
+
+func (x ExpressionStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ExpressionStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_v2_calculator_proto_enumTypes[0].Descriptor()
+}
+
+func (ExpressionStatus) Type() protoreflect.EnumType {
+	return &file_proto_v2_calculator_proto_enumTypes[0]
+}
+
+func (x ExpressionStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ExpressionStatus.Descriptor instead.
+func (ExpressionStatus) EnumDescriptor() ([]byte, []int) {
+	return file_proto_v2_calculator_proto_rawDescGZIP(), []int{0}
+}
+
+type Expression struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Expression    string                 `protobuf:"bytes,2,opt,name=expression,proto3" json:"expression,omitempty"` // As saved, fully bracketed infix
+	Status        ExpressionStatus       `protobuf:"varint,3,opt,name=status,proto3,enum=calculator.v2.ExpressionStatus" json:"status,omitempty"`
+	Result        float64                `protobuf:"fixed64,4,opt,name=result,proto3" json:"result,omitempty"` // Only meaningful when the status is DONE
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`     // Why the calculation failed or was cancelled
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=updatedAt,proto3" json:"updatedAt,omitempty"`
+	CallbackUrl   string                 `protobuf:"bytes,8,opt,name=callbackUrl,proto3" json:"callbackUrl,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Expression) Reset() {
+	*x = Expression{}
+	mi := &file_proto_v2_calculator_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Expression) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Expression) ProtoMessage() {}
+
+func (x *Expression) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v2_calculator_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Expression.ProtoReflect.Descriptor instead.
+func (*Expression) Descriptor() ([]byte, []int) {
+	return file_proto_v2_calculator_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Expression) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Expression) GetExpression() string {
+	if x != nil {
+		return x.Expression
+	}
+	return ""
+}
+
+func (x *Expression) GetStatus() ExpressionStatus {
+	if x != nil {
+		return x.Status
+	}
+	return ExpressionStatus_EXPRESSION_STATUS_UNSPECIFIED
+}
+
+func (x *Expression) GetResult() float64 {
+	if x != nil {
+		return x.Result
+	}
+	return 0
+}
+
+func (x *Expression) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Expression) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Expression) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Expression) GetCallbackUrl() string {
+	if x != nil {
+		return x.CallbackUrl
+	}
+	return ""
+}
+
+type SubmitExpressionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int32                  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	Expression    string                 `protobuf:"bytes,2,opt,name=expression,proto3" json:"expression,omitempty"`
+	Notation      string                 `protobuf:"bytes,3,opt,name=notation,proto3" json:"notation,omitempty"`       // "infix" (default), "rpn" or "prefix"
+	Locale        string                 `protobuf:"bytes,4,opt,name=locale,proto3" json:"locale,omitempty"`           // Number format of the expression, e.g. "de-DE"
+	CallbackUrl   string                 `protobuf:"bytes,5,opt,name=callbackUrl,proto3" json:"callbackUrl,omitempty"` // Notified when the calculation is finished
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitExpressionRequest) Reset() {
+	*x = SubmitExpressionRequest{}
+	mi := &file_proto_v2_calculator_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitExpressionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitExpressionRequest) ProtoMessage() {}
+
+func (x *SubmitExpressionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v2_calculator_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitExpressionRequest.ProtoReflect.Descriptor instead.
+func (*SubmitExpressionRequest) Descriptor() ([]byte, []int) {
+	return file_proto_v2_calculator_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SubmitExpressionRequest) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *SubmitExpressionRequest) GetExpression() string {
+	if x != nil {
+		return x.Expression
+	}
+	return ""
+}
+
+func (x *SubmitExpressionRequest) GetNotation() string {
+	if x != nil {
+		return x.Notation
+	}
+	return ""
+}
+
+func (x *SubmitExpressionRequest) GetLocale() string {
+	if x != nil {
+		return x.Locale
+	}
+	return ""
+}
+
+func (x *SubmitExpressionRequest) GetCallbackUrl() string {
+	if x != nil {
+		return x.CallbackUrl
+	}
+	return ""
+}
+
+type GetExpressionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int32                  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	Id            int32                  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetExpressionRequest) Reset() {
+	*x = GetExpressionRequest{}
+	mi := &file_proto_v2_calculator_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetExpressionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetExpressionRequest) ProtoMessage() {}
+
+func (x *GetExpressionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v2_calculator_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetExpressionRequest.ProtoReflect.Descriptor instead.
+func (*GetExpressionRequest) Descriptor() ([]byte, []int) {
+	return file_proto_v2_calculator_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetExpressionRequest) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *GetExpressionRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListExpressionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int32                  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	PageSize      int32                  `protobuf:"varint,2,opt,name=pageSize,proto3" json:"pageSize,omitempty"`                                 // 100 if not set, at most 1000
+	PageToken     string                 `protobuf:"bytes,3,opt,name=pageToken,proto3" json:"pageToken,omitempty"`                                // nextPageToken of the previous page
+	Status        ExpressionStatus       `protobuf:"varint,4,opt,name=status,proto3,enum=calculator.v2.ExpressionStatus" json:"status,omitempty"` // Only expressions with this status, all if not set
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListExpressionsRequest) Reset() {
+	*x = ListExpressionsRequest{}
+	mi := &file_proto_v2_calculator_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListExpressionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListExpressionsRequest) ProtoMessage() {}
+
+func (x *ListExpressionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v2_calculator_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListExpressionsRequest.ProtoReflect.Descriptor instead.
+func (*ListExpressionsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_v2_calculator_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListExpressionsRequest) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *ListExpressionsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListExpressionsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListExpressionsRequest) GetStatus() ExpressionStatus {
+	if x != nil {
+		return x.Status
+	}
+	return ExpressionStatus_EXPRESSION_STATUS_UNSPECIFIED
+}
+
+type ListExpressionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Expressions   []*Expression          `protobuf:"bytes,1,rep,name=expressions,proto3" json:"expressions,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=nextPageToken,proto3" json:"nextPageToken,omitempty"` // Empty on the last page
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListExpressionsResponse) Reset() {
+	*x = ListExpressionsResponse{}
+	mi := &file_proto_v2_calculator_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListExpressionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListExpressionsResponse) ProtoMessage() {}
+
+func (x *ListExpressionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v2_calculator_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListExpressionsResponse.ProtoReflect.Descriptor instead.
+func (*ListExpressionsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_v2_calculator_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListExpressionsResponse) GetExpressions() []*Expression {
+	if x != nil {
+		return x.Expressions
+	}
+	return nil
+}
+
+func (x *ListExpressionsResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type DeleteExpressionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int32                  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	Id            int32                  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteExpressionRequest) Reset() {
+	*x = DeleteExpressionRequest{}
+	mi := &file_proto_v2_calculator_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteExpressionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteExpressionRequest) ProtoMessage() {}
+
+func (x *DeleteExpressionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v2_calculator_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteExpressionRequest.ProtoReflect.Descriptor instead.
+func (*DeleteExpressionRequest) Descriptor() ([]byte, []int) {
+	return file_proto_v2_calculator_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DeleteExpressionRequest) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *DeleteExpressionRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type DeleteExpressionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteExpressionResponse) Reset() {
+	*x = DeleteExpressionResponse{}
+	mi := &file_proto_v2_calculator_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteExpressionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteExpressionResponse) ProtoMessage() {}
+
+func (x *DeleteExpressionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v2_calculator_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteExpressionResponse.ProtoReflect.Descriptor instead.
+func (*DeleteExpressionResponse) Descriptor() ([]byte, []int) {
+	return file_proto_v2_calculator_proto_rawDescGZIP(), []int{6}
+}
+
+var File_proto_v2_calculator_proto protoreflect.FileDescriptor
+
+const file_proto_v2_calculator_proto_rawDesc = "" +
+	"\n" +
+	"\x19proto/v2/calculator.proto\x12\rcalculator.v2\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb9\x02\n" +
+	"\n" +
+	"Expression\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x1e\n" +
+	"\n" +
+	"expression\x18\x02 \x01(\tR\n" +
+	"expression\x127\n" +
+	"\x06status\x18\x03 \x01(\x0e2\x1f.calculator.v2.ExpressionStatusR\x06status\x12\x16\n" +
+	"\x06result\x18\x04 \x01(\x01R\x06result\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x128\n" +
+	"\tcreatedAt\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x128\n" +
+	"\tupdatedAt\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12 \n" +
+	"\vcallbackUrl\x18\b \x01(\tR\vcallbackUrl\"\xa7\x01\n" +
+	"\x17SubmitExpressionRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\x05R\x06userId\x12\x1e\n" +
+	"\n" +
+	"expression\x18\x02 \x01(\tR\n" +
+	"expression\x12\x1a\n" +
+	"\bnotation\x18\x03 \x01(\tR\bnotation\x12\x16\n" +
+	"\x06locale\x18\x04 \x01(\tR\x06locale\x12 \n" +
+	"\vcallbackUrl\x18\x05 \x01(\tR\vcallbackUrl\">\n" +
+	"\x14GetExpressionRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\x05R\x06userId\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\x05R\x02id\"\xa3\x01\n" +
+	"\x16ListExpressionsRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\x05R\x06userId\x12\x1a\n" +
+	"\bpageSize\x18\x02 \x01(\x05R\bpageSize\x12\x1c\n" +
+	"\tpageToken\x18\x03 \x01(\tR\tpageToken\x127\n" +
+	"\x06status\x18\x04 \x01(\x0e2\x1f.calculator.v2.ExpressionStatusR\x06status\"|\n" +
+	"\x17ListExpressionsResponse\x12;\n" +
+	"\vexpressions\x18\x01 \x03(\v2\x19.calculator.v2.ExpressionR\vexpressions\x12$\n" +
+	"\rnextPageToken\x18\x02 \x01(\tR\rnextPageToken\"A\n" +
+	"\x17DeleteExpressionRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\x05R\x06userId\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\x05R\x02id\"\x1a\n" +
+	"\x18DeleteExpressionResponse*\xce\x01\n" +
+	"\x10ExpressionStatus\x12!\n" +
+	"\x1dEXPRESSION_STATUS_UNSPECIFIED\x10\x00\x12\x1d\n" +
+	"\x19EXPRESSION_STATUS_PENDING\x10\x01\x12\x1d\n" +
+	"\x19EXPRESSION_STATUS_RUNNING\x10\x02\x12\x1a\n" +
+	"\x16EXPRESSION_STATUS_DONE\x10\x03\x12\x1c\n" +
+	"\x18EXPRESSION_STATUS_FAILED\x10\x04\x12\x1f\n" +
+	"\x1bEXPRESSION_STATUS_CANCELLED\x10\x052\x82\x03\n" +
+	"\x11CalculatorService\x12U\n" +
+	"\x10SubmitExpression\x12&.calculator.v2.SubmitExpressionRequest\x1a\x19.calculator.v2.Expression\x12O\n" +
+	"\rGetExpression\x12#.calculator.v2.GetExpressionRequest\x1a\x19.calculator.v2.Expression\x12`\n" +
+	"\x0fListExpressions\x12%.calculator.v2.ListExpressionsRequest\x1a&.calculator.v2.ListExpressionsResponse\x12c\n" +
+	"\x10DeleteExpression\x12&.calculator.v2.DeleteExpressionRequest\x1a'.calculator.v2.DeleteExpressionResponseB\x19Z\x17./proto/v2;calculatorv2b\x06proto3"
+
+var (
+	file_proto_v2_calculator_proto_rawDescOnce sync.Once
+	file_proto_v2_calculator_proto_rawDescData []byte
+)
+
+func file_proto_v2_calculator_proto_rawDescGZIP() []byte {
+	file_proto_v2_calculator_proto_rawDescOnce.Do(func() {
+		file_proto_v2_calculator_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_v2_calculator_proto_rawDesc), len(file_proto_v2_calculator_proto_rawDesc)))
+	})
+	return file_proto_v2_calculator_proto_rawDescData
+}
+
+var file_proto_v2_calculator_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_v2_calculator_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_proto_v2_calculator_proto_goTypes = []any{
+	(ExpressionStatus)(0),            // 0: calculator.v2.ExpressionStatus
+	(*Expression)(nil),               // 1: calculator.v2.Expression
+	(*SubmitExpressionRequest)(nil),  // 2: calculator.v2.SubmitExpressionRequest
+	(*GetExpressionRequest)(nil),     // 3: calculator.v2.GetExpressionRequest
+	(*ListExpressionsRequest)(nil),   // 4: calculator.v2.ListExpressionsRequest
+	(*ListExpressionsResponse)(nil),  // 5: calculator.v2.ListExpressionsResponse
+	(*DeleteExpressionRequest)(nil),  // 6: calculator.v2.DeleteExpressionRequest
+	(*DeleteExpressionResponse)(nil), // 7: calculator.v2.DeleteExpressionResponse
+	(*timestamppb.Timestamp)(nil),    // 8: google.protobuf.Timestamp
+}
+var file_proto_v2_calculator_proto_depIdxs = []int32{
+	0, // 0: calculator.v2.Expression.status:type_name -> calculator.v2.ExpressionStatus
+	8, // 1: calculator.v2.Expression.createdAt:type_name -> google.protobuf.Timestamp
+	8, // 2: calculator.v2.Expression.updatedAt:type_name -> google.protobuf.Timestamp
+	0, // 3: calculator.v2.ListExpressionsRequest.status:type_name -> calculator.v2.ExpressionStatus
+	1, // 4: calculator.v2.ListExpressionsResponse.expressions:type_name -> calculator.v2.Expression
+	2, // 5: calculator.v2.CalculatorService.SubmitExpression:input_type -> calculator.v2.SubmitExpressionRequest
+	3, // 6: calculator.v2.CalculatorService.GetExpression:input_type -> calculator.v2.GetExpressionRequest
+	4, // 7: calculator.v2.CalculatorService.ListExpressions:input_type -> calculator.v2.ListExpressionsRequest
+	6, // 8: calculator.v2.CalculatorService.DeleteExpression:input_type -> calculator.v2.DeleteExpressionRequest
+	1, // 9: calculator.v2.CalculatorService.SubmitExpression:output_type -> calculator.v2.Expression
+	1, // 10: calculator.v2.CalculatorService.GetExpression:output_type -> calculator.v2.Expression
+	5, // 11: calculator.v2.CalculatorService.ListExpressions:output_type -> calculator.v2.ListExpressionsResponse
+	7, // 12: calculator.v2.CalculatorService.DeleteExpression:output_type -> calculator.v2.DeleteExpressionResponse
+	9, // [9:13] is the sub-list for method output_type
+	5, // [5:9] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_proto_v2_calculator_proto_init() }
+func file_proto_v2_calculator_proto_init() {
+	if File_proto_v2_calculator_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_v2_calculator_proto_rawDesc), len(file_proto_v2_calculator_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_v2_calculator_proto_goTypes,
+		DependencyIndexes: file_proto_v2_calculator_proto_depIdxs,
+		EnumInfos:         file_proto_v2_calculator_proto_enumTypes,
+		MessageInfos:      file_proto_v2_calculator_proto_msgTypes,
+	}.Build()
+	File_proto_v2_calculator_proto = out.File
+	file_proto_v2_calculator_proto_goTypes = nil
+	file_proto_v2_calculator_proto_depIdxs = nil
+}
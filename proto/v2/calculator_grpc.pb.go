@@ -0,0 +1,249 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v6.30.2
+// source: proto/v2/calculator.proto
+
+package calculatorv2
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CalculatorService_SubmitExpression_FullMethodName = "/calculator.v2.CalculatorService/SubmitExpression"
+	CalculatorService_GetExpression_FullMethodName    = "/calculator.v2.CalculatorService/GetExpression"
+	CalculatorService_ListExpressions_FullMethodName  = "/calculator.v2.CalculatorService/ListExpressions"
+	CalculatorService_DeleteExpression_FullMethodName = "/calculator.v2.CalculatorService/DeleteExpression"
+)
+
+// CalculatorServiceClient is the client API for CalculatorService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CalculatorService replaces the v1 UserService.SendUserData, which submits or
+// fetches depending on the fields that are set. Both run side by side
+type CalculatorServiceClient interface {
+	// Saves the expression and starts calculating it, the result may be known right away
+	SubmitExpression(ctx context.Context, in *SubmitExpressionRequest, opts ...grpc.CallOption) (*Expression, error)
+	// One expression of the user, NOT_FOUND if there is none with this id
+	GetExpression(ctx context.Context, in *GetExpressionRequest, opts ...grpc.CallOption) (*Expression, error)
+	// Expressions of the user ordered by id, a page at a time
+	ListExpressions(ctx context.Context, in *ListExpressionsRequest, opts ...grpc.CallOption) (*ListExpressionsResponse, error)
+	// Removes an expression of the user, one still being calculated is cancelled first
+	DeleteExpression(ctx context.Context, in *DeleteExpressionRequest, opts ...grpc.CallOption) (*DeleteExpressionResponse, error)
+}
+
+type calculatorServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCalculatorServiceClient(cc grpc.ClientConnInterface) CalculatorServiceClient {
+	return &calculatorServiceClient{cc}
+}
+
+func (c *calculatorServiceClient) SubmitExpression(ctx context.Context, in *SubmitExpressionRequest, opts ...grpc.CallOption) (*Expression, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Expression)
+	err := c.cc.Invoke(ctx, CalculatorService_SubmitExpression_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) GetExpression(ctx context.Context, in *GetExpressionRequest, opts ...grpc.CallOption) (*Expression, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Expression)
+	err := c.cc.Invoke(ctx, CalculatorService_GetExpression_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) ListExpressions(ctx context.Context, in *ListExpressionsRequest, opts ...grpc.CallOption) (*ListExpressionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListExpressionsResponse)
+	err := c.cc.Invoke(ctx, CalculatorService_ListExpressions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) DeleteExpression(ctx context.Context, in *DeleteExpressionRequest, opts ...grpc.CallOption) (*DeleteExpressionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteExpressionResponse)
+	err := c.cc.Invoke(ctx, CalculatorService_DeleteExpression_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CalculatorServiceServer is the server API for CalculatorService service.
+// All implementations must embed UnimplementedCalculatorServiceServer
+// for forward compatibility.
+//
+// CalculatorService replaces the v1 UserService.SendUserData, which submits or
+// fetches depending on the fields that are set. Both run side by side
+type CalculatorServiceServer interface {
+	// Saves the expression and starts calculating it, the result may be known right away
+	SubmitExpression(context.Context, *SubmitExpressionRequest) (*Expression, error)
+	// One expression of the user, NOT_FOUND if there is none with this id
+	GetExpression(context.Context, *GetExpressionRequest) (*Expression, error)
+	// Expressions of the user ordered by id, a page at a time
+	ListExpressions(context.Context, *ListExpressionsRequest) (*ListExpressionsResponse, error)
+	// Removes an expression of the user, one still being calculated is cancelled first
+	DeleteExpression(context.Context, *DeleteExpressionRequest) (*DeleteExpressionResponse, error)
+	mustEmbedUnimplementedCalculatorServiceServer()
+}
+
+// UnimplementedCalculatorServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCalculatorServiceServer struct{}
+
+func (UnimplementedCalculatorServiceServer) SubmitExpression(context.Context, *SubmitExpressionRequest) (*Expression, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitExpression not implemented")
+}
+func (UnimplementedCalculatorServiceServer) GetExpression(context.Context, *GetExpressionRequest) (*Expression, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetExpression not implemented")
+}
+func (UnimplementedCalculatorServiceServer) ListExpressions(context.Context, *ListExpressionsRequest) (*ListExpressionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListExpressions not implemented")
+}
+func (UnimplementedCalculatorServiceServer) DeleteExpression(context.Context, *DeleteExpressionRequest) (*DeleteExpressionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteExpression not implemented")
+}
+func (UnimplementedCalculatorServiceServer) mustEmbedUnimplementedCalculatorServiceServer() {}
+func (UnimplementedCalculatorServiceServer) testEmbeddedByValue()                           {}
+
+// UnsafeCalculatorServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CalculatorServiceServer will
+// result in compilation errors.
+type UnsafeCalculatorServiceServer interface {
+	mustEmbedUnimplementedCalculatorServiceServer()
+}
+
+func RegisterCalculatorServiceServer(s grpc.ServiceRegistrar, srv CalculatorServiceServer) {
+	// If the following call pancis, it indicates UnimplementedCalculatorServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CalculatorService_ServiceDesc, srv)
+}
+
+func _CalculatorService_SubmitExpression_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitExpressionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).SubmitExpression(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorService_SubmitExpression_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).SubmitExpression(ctx, req.(*SubmitExpressionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_GetExpression_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetExpressionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).GetExpression(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorService_GetExpression_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).GetExpression(ctx, req.(*GetExpressionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_ListExpressions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListExpressionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).ListExpressions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorService_ListExpressions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).ListExpressions(ctx, req.(*ListExpressionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_DeleteExpression_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteExpressionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).DeleteExpression(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorService_DeleteExpression_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).DeleteExpression(ctx, req.(*DeleteExpressionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CalculatorService_ServiceDesc is the grpc.ServiceDesc for CalculatorService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CalculatorService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "calculator.v2.CalculatorService",
+	HandlerType: (*CalculatorServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitExpression",
+			Handler:    _CalculatorService_SubmitExpression_Handler,
+		},
+		{
+			MethodName: "GetExpression",
+			Handler:    _CalculatorService_GetExpression_Handler,
+		},
+		{
+			MethodName: "ListExpressions",
+			Handler:    _CalculatorService_ListExpressions_Handler,
+		},
+		{
+			MethodName: "DeleteExpression",
+			Handler:    _CalculatorService_DeleteExpression_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/v2/calculator.proto",
+}